@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"fmt"
+	"math/big"
 	"strings"
 
 	"github.com/NishanthSpShetty/monkey/token"
@@ -10,6 +11,10 @@ import (
 
 type Node interface {
 	TokenLiteral() string
+	// Pos is the position of the first character of the node,
+	// End is the position just past its last character.
+	Pos() token.Position
+	End() token.Position
 }
 
 type Statement interface {
@@ -26,6 +31,9 @@ type Expression interface {
 
 type Program struct {
 	Statements []Statement
+	// Comments holds every comment in source order, including those attached
+	// to statements. It is empty unless the lexer keeps comments.
+	Comments []*CommentGroup
 }
 
 // TokenLiteral implements Node.
@@ -36,6 +44,20 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
+func (p *Program) End() token.Position {
+	if n := len(p.Statements); n > 0 {
+		return p.Statements[n-1].End()
+	}
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	out := bytes.Buffer{}
 	for _, st := range p.Statements {
@@ -48,10 +70,16 @@ type LetStatement struct {
 	Token token.Token
 	Name  *Identifier
 	Value Expression
+	// Doc is the comment group on the lines right above the statement,
+	// Comment the one following it on the same line.
+	Doc     *CommentGroup
+	Comment *CommentGroup
 }
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position  { return ls.Token.Pos }
+func (ls *LetStatement) End() token.Position  { return endOf(ls.Value, ls.Token) }
 
 func (ls *LetStatement) String() string {
 	return fmt.Sprintf("let %s = %s", ls.Name, ls.Value)
@@ -64,6 +92,8 @@ type Identifier struct {
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() token.Position  { return i.Token.Pos }
+func (i *Identifier) End() token.Position  { return i.Token.End }
 func (i *Identifier) String() string {
 	return fmt.Sprintf("%s", i.Value)
 }
@@ -71,10 +101,15 @@ func (i *Identifier) String() string {
 type ReturnStatement struct {
 	Token       token.Token
 	ReturnValue Expression
+	// Doc and Comment are the comments around the statement, as for LetStatement.
+	Doc     *CommentGroup
+	Comment *CommentGroup
 }
 
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() token.Position  { return rs.Token.Pos }
+func (rs *ReturnStatement) End() token.Position  { return endOf(rs.ReturnValue, rs.Token) }
 
 func (rs *ReturnStatement) String() string {
 	return fmt.Sprintf("ReturnStatement<Token: %v, ReturnValue: %s > ", rs.Token, rs.ReturnValue)
@@ -83,10 +118,15 @@ func (rs *ReturnStatement) String() string {
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
+	// Doc and Comment are the comments around the statement, as for LetStatement.
+	Doc     *CommentGroup
+	Comment *CommentGroup
 }
 
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() token.Position  { return es.Token.Pos }
+func (es *ExpressionStatement) End() token.Position  { return endOf(es.Expression, es.Token) }
 func (es *ExpressionStatement) String() string {
 	return fmt.Sprintf("%s", es.Expression)
 }
@@ -94,14 +134,34 @@ func (es *ExpressionStatement) String() string {
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
+	// Big holds literals too large for Value, it is nil otherwise.
+	Big *big.Int
 }
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position  { return il.Token.Pos }
+func (il *IntegerLiteral) End() token.Position  { return il.Token.End }
 func (il *IntegerLiteral) String() string {
+	if il.Big != nil {
+		return il.Big.String()
+	}
 	return fmt.Sprintf("%d", il.Value)
 }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FloatLiteral) End() token.Position  { return fl.Token.End }
+func (fl *FloatLiteral) String() string {
+	return fl.Token.Literal
+}
+
 type PrefixExpression struct {
 	Token    token.Token // The prefix token, e.g. !
 	Operator string
@@ -110,6 +170,8 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Pos }
+func (pe *PrefixExpression) End() token.Position  { return endOf(pe.Right, pe.Token) }
 func (pe *PrefixExpression) String() string {
 	return fmt.Sprintf("(%s%s)", pe.Operator, pe.Right)
 }
@@ -123,10 +185,29 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() token.Position  { return posOf(ie.Left, ie.Token) }
+func (ie *InfixExpression) End() token.Position  { return endOf(ie.Right, ie.Token) }
 func (ie *InfixExpression) String() string {
 	return fmt.Sprintf("(%s %s %s)", ie.Left, ie.Operator, ie.Right)
 }
 
+// AssignExpression stores Value into Target, a variable or an index
+// expression. Operator is "=" or a compound assignment like "+=".
+type AssignExpression struct {
+	Token    token.Token // the assignment operator
+	Operator string
+	Target   Expression
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Pos() token.Position  { return posOf(ae.Target, ae.Token) }
+func (ae *AssignExpression) End() token.Position  { return endOf(ae.Value, ae.Token) }
+func (ae *AssignExpression) String() string {
+	return fmt.Sprintf("(%s %s %s)", ae.Target, ae.Operator, ae.Value)
+}
+
 type Boolean struct {
 	Token token.Token
 	Value bool
@@ -134,6 +215,8 @@ type Boolean struct {
 
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Pos() token.Position  { return b.Token.Pos }
+func (b *Boolean) End() token.Position  { return b.Token.End }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
 type IfExpression struct {
@@ -145,20 +228,33 @@ type IfExpression struct {
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position  { return ie.Token.Pos }
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	if ie.Consequence != nil {
+		return ie.Consequence.End()
+	}
+	return ie.Token.End
+}
 
 func (ie *IfExpression) String() string {
 	return fmt.Sprintf("if (%s) is True { %s } if False { %s } ", ie.Condition, ie.Consequence, ie.Alternative)
 }
 
 type BlockStatement struct {
-	Token      token.Token
+	Token      token.Token // {
 	Statements []Statement
+	Rbrace     token.Token
 }
 
 func (bs *BlockStatement) statementNode() {}
 func (bs *BlockStatement) TokenLiteral() string {
 	return bs.Token.Literal
 }
+func (bs *BlockStatement) Pos() token.Position { return bs.Token.Pos }
+func (bs *BlockStatement) End() token.Position { return closeOf(bs.Rbrace, bs.Token) }
 
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
@@ -171,20 +267,43 @@ func (bs *BlockStatement) String() string {
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
-	Body       *BlockStatement
+	// Defaults holds the default value of each parameter, nil for the ones
+	// without. It is nil when no parameter has a default.
+	Defaults []Expression
+	// Rest collects the arguments following the parameters, fn(a, ...rest)
+	Rest *Identifier
+	Body *BlockStatement
+	// File is the source the function was parsed from
+	File *token.File
 }
 
 func (fe *FunctionLiteral) expressionNode()      {}
 func (fe *FunctionLiteral) TokenLiteral() string { return fe.Token.Literal }
+func (fe *FunctionLiteral) Pos() token.Position  { return fe.Token.Pos }
+func (fe *FunctionLiteral) End() token.Position {
+	if fe.Body != nil {
+		return fe.Body.End()
+	}
+	return fe.Token.End
+}
 func (fe *FunctionLiteral) String() string {
 	var out bytes.Buffer
 	out.WriteString("Function ")
 	out.WriteString(fe.TokenLiteral())
 	out.WriteString("(")
 
-	for _, p := range fe.Parameters {
-		out.WriteString(p.String())
+	params := []string{}
+	for i, p := range fe.Parameters {
+		if def := fe.Default(i); def != nil {
+			params = append(params, p.String()+" = "+def.String())
+		} else {
+			params = append(params, p.String())
+		}
 	}
+	if fe.Rest != nil {
+		params = append(params, "..."+fe.Rest.String())
+	}
+	out.WriteString(strings.Join(params, ", "))
 
 	out.WriteString(") {")
 	out.WriteString(fe.Body.String())
@@ -192,14 +311,34 @@ func (fe *FunctionLiteral) String() string {
 	return out.String()
 }
 
+// Default returns the default value of the i-th parameter, nil when it has none.
+func (fe *FunctionLiteral) Default(i int) Expression {
+	if fe.Defaults == nil {
+		return nil
+	}
+	return fe.Defaults[i]
+}
+
+// Required returns the number of parameters without a default, which come first.
+func (fe *FunctionLiteral) Required() int {
+	n := 0
+	for n < len(fe.Parameters) && fe.Default(n) == nil {
+		n++
+	}
+	return n
+}
+
 type CallExpression struct {
-	Token     token.Token
+	Token     token.Token // (
 	Function  Expression
 	Arguments []Expression
+	Rparen    token.Token
 }
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position  { return posOf(ce.Function, ce.Token) }
+func (ce *CallExpression) End() token.Position  { return closeOf(ce.Rparen, ce.Token) }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString(ce.Function.String())
@@ -213,6 +352,20 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// SpreadExpression passes the elements of Value as arguments of a call, f(...xs).
+type SpreadExpression struct {
+	Token token.Token // ...
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode()      {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) Pos() token.Position  { return se.Token.Pos }
+func (se *SpreadExpression) End() token.Position  { return endOf(se.Value, se.Token) }
+func (se *SpreadExpression) String() string {
+	return "..." + se.Value.String()
+}
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -220,15 +373,20 @@ type StringLiteral struct {
 
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos }
+func (sl *StringLiteral) End() token.Position  { return sl.Token.End }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
 type ArrayLiteral struct {
-	Token    token.Token
+	Token    token.Token // [
 	Elements []Expression
+	Rbracket token.Token
 }
 
 func (a *ArrayLiteral) expressionNode()      {}
 func (a *ArrayLiteral) TokenLiteral() string { return a.Token.Literal }
+func (a *ArrayLiteral) Pos() token.Position  { return a.Token.Pos }
+func (a *ArrayLiteral) End() token.Position  { return closeOf(a.Rbracket, a.Token) }
 func (a *ArrayLiteral) String() string {
 	var out bytes.Buffer
 	el := []string{}
@@ -243,13 +401,16 @@ func (a *ArrayLiteral) String() string {
 }
 
 type IndexExpression struct {
-	Token token.Token
-	Left  Expression
-	Index Expression
+	Token    token.Token // [
+	Left     Expression
+	Index    Expression
+	Rbracket token.Token
 }
 
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position  { return posOf(ie.Left, ie.Token) }
+func (ie *IndexExpression) End() token.Position  { return closeOf(ie.Rbracket, ie.Token) }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -260,19 +421,29 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// HashPair is a key: value entry of a hash literal.
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
 type HashLiteral struct {
-	Token token.Token
-	Pairs map[Expression]Expression
+	Token token.Token // {
+	// Pairs are in source order
+	Pairs  []HashPair
+	Rbrace token.Token
 }
 
 func (hl *HashLiteral) expressionNode()      {}
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos }
+func (hl *HashLiteral) End() token.Position  { return closeOf(hl.Rbrace, hl.Token) }
 
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
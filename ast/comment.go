@@ -0,0 +1,67 @@
+package ast
+
+import (
+	"strings"
+
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+// Comment is a // or /* */ comment. Comments are only kept when the lexer
+// runs in ScanComments mode.
+type Comment struct {
+	Token token.Token
+}
+
+func (c *Comment) TokenLiteral() string { return c.Token.Literal }
+func (c *Comment) Pos() token.Position  { return c.Token.Pos }
+func (c *Comment) End() token.Position  { return c.Token.End }
+func (c *Comment) String() string       { return c.Token.Literal }
+
+// CommentGroup is a run of comments with no tokens or blank lines between them.
+type CommentGroup struct {
+	List []*Comment
+}
+
+func (g *CommentGroup) TokenLiteral() string { return g.List[0].TokenLiteral() }
+func (g *CommentGroup) Pos() token.Position  { return g.List[0].Pos() }
+func (g *CommentGroup) End() token.Position  { return g.List[len(g.List)-1].End() }
+
+func (g *CommentGroup) String() string {
+	out := strings.Builder{}
+	for i, c := range g.List {
+		if i > 0 {
+			if c.Pos().Line == g.List[i-1].End().Line {
+				out.WriteString(" ")
+			} else {
+				out.WriteString("\n")
+			}
+		}
+		out.WriteString(c.String())
+	}
+	return out.String()
+}
+
+// Text returns the text of the comments without the comment markers and
+// surrounding blank lines, as a doc generator would show it.
+func (g *CommentGroup) Text() string {
+	lines := []string{}
+	for _, c := range g.List {
+		text := c.Token.Literal
+		if strings.HasPrefix(text, "//") {
+			lines = append(lines, strings.TrimPrefix(strings.TrimPrefix(text, "//"), " "))
+			continue
+		}
+		text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,70 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+// WhileStatement runs Body as long as Condition is truthy.
+//
+//	while (x < 10) { ... }
+type WhileStatement struct {
+	Token     token.Token // while
+	Condition Expression
+	Body      *BlockStatement
+	// Doc and Comment are the comments around the statement, as for LetStatement.
+	Doc     *CommentGroup
+	Comment *CommentGroup
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() token.Position  { return ws.Token.Pos }
+func (ws *WhileStatement) End() token.Position  { return endOf(ws.Body, ws.Token) }
+func (ws *WhileStatement) String() string {
+	return fmt.Sprintf("while (%s) { %s }", ws.Condition, ws.Body)
+}
+
+// ForStatement runs Body for each element of Iterable. With a single variable
+// it is bound to the element, or the key for hashes, with two variables to
+// the index or key and the element.
+//
+//	for (x in [1, 2]) { ... }
+//	for (k, v in {"a": 1}) { ... }
+type ForStatement struct {
+	Token    token.Token // for
+	Key      *Identifier // nil when there is a single variable
+	Value    *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+	// Doc and Comment are the comments around the statement, as for LetStatement.
+	Doc     *CommentGroup
+	Comment *CommentGroup
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() token.Position  { return fs.Token.Pos }
+func (fs *ForStatement) End() token.Position  { return endOf(fs.Body, fs.Token) }
+func (fs *ForStatement) String() string {
+	vars := fs.Value.String()
+	if fs.Key != nil {
+		vars = fs.Key.String() + ", " + vars
+	}
+	return fmt.Sprintf("for (%s in %s) { %s }", vars, fs.Iterable, fs.Body)
+}
+
+// BranchStatement is a break or a continue, told apart by the token type.
+type BranchStatement struct {
+	Token token.Token
+	// Doc and Comment are the comments around the statement, as for LetStatement.
+	Doc     *CommentGroup
+	Comment *CommentGroup
+}
+
+func (bs *BranchStatement) statementNode()       {}
+func (bs *BranchStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BranchStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BranchStatement) End() token.Position  { return bs.Token.End }
+func (bs *BranchStatement) String() string       { return bs.Token.Literal }
@@ -0,0 +1,28 @@
+package ast
+
+import "github.com/NishanthSpShetty/monkey/token"
+
+// posOf returns the start of n, or of tok when n failed to parse.
+func posOf(n Node, tok token.Token) token.Position {
+	if n == nil {
+		return tok.Pos
+	}
+	return n.Pos()
+}
+
+// endOf returns the end of n, or of tok when n failed to parse.
+func endOf(n Node, tok token.Token) token.Position {
+	if n == nil {
+		return tok.End
+	}
+	return n.End()
+}
+
+// closeOf returns the end of the closing delimiter, or of the opening one
+// when the closing delimiter is missing.
+func closeOf(close, open token.Token) token.Position {
+	if close.End.IsValid() {
+		return close.End
+	}
+	return open.End
+}
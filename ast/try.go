@@ -0,0 +1,67 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+// TryStatement runs Body, and Catch when Body raises an error, with the
+// error bound to Param. Like a let in Catch, Param is bound in the scope
+// around the statement and replaces a binding of the same name there.
+// Finally runs last whichever way the others end. Catch or Finally may be
+// missing, not both.
+//
+//	try { ... } catch (e) { ... } finally { ... }
+type TryStatement struct {
+	Token   token.Token // try
+	Body    *BlockStatement
+	Param   *Identifier // nil when the catch does not name the error
+	Catch   *BlockStatement
+	Finally *BlockStatement
+	// Doc and Comment are the comments around the statement, as for LetStatement.
+	Doc     *CommentGroup
+	Comment *CommentGroup
+}
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TryStatement) Pos() token.Position  { return ts.Token.Pos }
+func (ts *TryStatement) End() token.Position {
+	switch {
+	case ts.Finally != nil:
+		return endOf(ts.Finally, ts.Token)
+	case ts.Catch != nil:
+		return endOf(ts.Catch, ts.Token)
+	}
+	return endOf(ts.Body, ts.Token)
+}
+func (ts *TryStatement) String() string {
+	out := fmt.Sprintf("try { %s }", ts.Body)
+	if ts.Catch != nil {
+		if ts.Param != nil {
+			out += fmt.Sprintf(" catch (%s) { %s }", ts.Param, ts.Catch)
+		} else {
+			out += fmt.Sprintf(" catch { %s }", ts.Catch)
+		}
+	}
+	if ts.Finally != nil {
+		out += fmt.Sprintf(" finally { %s }", ts.Finally)
+	}
+	return out
+}
+
+// ThrowStatement raises Value as an error.
+type ThrowStatement struct {
+	Token token.Token // throw
+	Value Expression
+	// Doc and Comment are the comments around the statement, as for LetStatement.
+	Doc     *CommentGroup
+	Comment *CommentGroup
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) Pos() token.Position  { return ts.Token.Pos }
+func (ts *ThrowStatement) End() token.Position  { return endOf(ts.Value, ts.Token) }
+func (ts *ThrowStatement) String() string       { return fmt.Sprintf("throw %s", ts.Value) }
@@ -0,0 +1,86 @@
+package ast
+
+// Inspect walks the tree rooted at node in source order, calling f for each
+// node. When f returns true the children of the node are walked, followed
+// by a call f(nil). Comments are not walked.
+func Inspect(node Node, f func(Node) bool) {
+	if isNil(node) || !f(node) {
+		return
+	}
+
+	walk := func(children ...Node) {
+		for _, c := range children {
+			Inspect(c, f)
+		}
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, s := range n.Statements {
+			walk(s)
+		}
+	case *LetStatement:
+		walk(n.Name, n.Value)
+	case *ReturnStatement:
+		walk(n.ReturnValue)
+	case *ExpressionStatement:
+		walk(n.Expression)
+	case *ThrowStatement:
+		walk(n.Value)
+	case *BlockStatement:
+		for _, s := range n.Statements {
+			walk(s)
+		}
+	case *WhileStatement:
+		walk(n.Condition, n.Body)
+	case *ForStatement:
+		walk(n.Key, n.Value, n.Iterable, n.Body)
+	case *TryStatement:
+		walk(n.Body, n.Param, n.Catch, n.Finally)
+	case *PrefixExpression:
+		walk(n.Right)
+	case *InfixExpression:
+		walk(n.Left, n.Right)
+	case *AssignExpression:
+		walk(n.Target, n.Value)
+	case *IfExpression:
+		walk(n.Condition, n.Consequence, n.Alternative)
+	case *FunctionLiteral:
+		for i, p := range n.Parameters {
+			walk(p, n.Default(i))
+		}
+		walk(n.Rest, n.Body)
+	case *CallExpression:
+		walk(n.Function)
+		for _, arg := range n.Arguments {
+			walk(arg)
+		}
+	case *SpreadExpression:
+		walk(n.Value)
+	case *ArrayLiteral:
+		for _, e := range n.Elements {
+			walk(e)
+		}
+	case *IndexExpression:
+		walk(n.Left, n.Index)
+	case *HashLiteral:
+		for _, pair := range n.Pairs {
+			walk(pair.Key, pair.Value)
+		}
+	}
+	f(nil)
+}
+
+// isNil reports whether n is nil or a nil pointer held by the interface,
+// like a missing Key of a ForStatement.
+func isNil(n Node) bool {
+	switch n := n.(type) {
+	case nil:
+		return true
+	case *Identifier:
+		return n == nil
+	case *BlockStatement:
+		return n == nil
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+// Package checker finds mistakes in a program without running it: names
+// which are not defined, bindings never used, builtins hidden by a binding,
+// code which cannot run and calls with the wrong number of arguments.
+package checker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+type Kind string
+
+const (
+	// UndefinedName is reported for a name no scope declares, which fails when it runs
+	UndefinedName Kind = "UndefinedName"
+	// WrongArity is reported for a call to a known function with too few or too many arguments
+	WrongArity Kind = "WrongArity"
+	// Unused is reported for a let or a parameter which is never read
+	Unused Kind = "Unused"
+	// ShadowedBuiltin is reported for a binding hiding the builtin of the same name
+	ShadowedBuiltin Kind = "ShadowedBuiltin"
+	// Unreachable is reported for statements following a return, throw, break or continue
+	Unreachable Kind = "Unreachable"
+)
+
+type Severity int
+
+const (
+	// Error is a mistake the program fails on when it gets there
+	Error Severity = iota
+	// Warning is likely a mistake, the program still runs
+	Warning
+)
+
+// Diagnostic is a problem found in the source from Pos up to End.
+type Diagnostic struct {
+	Kind     Kind
+	Severity Severity
+	Message  string
+	Pos      token.Position
+	End      token.Position
+}
+
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s", d.Pos, d.message())
+}
+
+// Render formats the diagnostic with its location in f and the offending source line.
+func (d *Diagnostic) Render(f *token.File) string {
+	return f.Format(d.Pos, d.message())
+}
+
+func (d *Diagnostic) message() string {
+	if d.Severity == Warning {
+		return "warning: " + d.Message
+	}
+	return d.Message
+}
+
+// Check returns the problems found in program, in source order. The names
+// in globals are defined when it runs, like the args of a script.
+func Check(program *ast.Program, globals ...string) []*Diagnostic {
+	c := &checker{info: Analyze(program), globals: map[string]bool{}}
+	for _, name := range globals {
+		c.globals[name] = true
+	}
+
+	c.names()
+	c.bindings(c.info.Scope)
+	ast.Inspect(program, c.inspect)
+
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		return c.diags[i].Pos.Offset < c.diags[j].Pos.Offset
+	})
+	return c.diags
+}
+
+type checker struct {
+	info    *Info
+	globals map[string]bool
+	diags   []*Diagnostic
+}
+
+func (c *checker) report(kind Kind, severity Severity, n ast.Node, format string, args ...any) {
+	c.diags = append(c.diags, &Diagnostic{
+		Kind:     kind,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+		Pos:      n.Pos(),
+		End:      n.End(),
+	})
+}
+
+// names reports the identifiers which resolve to nothing.
+func (c *checker) names() {
+	for _, r := range c.info.Refs {
+		if r.Binding != nil || c.globals[r.Ident.Value] {
+			continue
+		}
+		if _, ok := runtime.GetBuiltin(r.Ident.Value); ok {
+			continue
+		}
+		// the message the evaluator fails with
+		c.report(UndefinedName, Error, r.Ident, "identifier not found: %s", r.Ident.Value)
+	}
+}
+
+// bindings reports the unused bindings and those hiding builtins in s and
+// the scopes nested in it. Names starting with _ are meant to be unused.
+func (c *checker) bindings(s *Scope) {
+	for _, b := range s.Bindings {
+		name := b.Name.Value
+		if _, ok := runtime.GetBuiltin(name); ok {
+			c.report(ShadowedBuiltin, Warning, b.Name, "%s %s shadows the builtin %s", b.Kind, name, name)
+		}
+		if len(b.Uses) > 0 || strings.HasPrefix(name, "_") {
+			continue
+		}
+		switch b.Kind {
+		case Let:
+			c.report(Unused, Warning, b.Name, "%s is declared but never used", name)
+		case Parameter:
+			c.report(Unused, Warning, b.Name, "parameter %s is never used", name)
+		}
+	}
+	for _, child := range s.Children {
+		c.bindings(child)
+	}
+}
+
+func (c *checker) inspect(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.Program:
+		c.unreachable(n.Statements)
+	case *ast.BlockStatement:
+		c.unreachable(n.Statements)
+	case *ast.CallExpression:
+		c.arity(n)
+	}
+	return true
+}
+
+// unreachable reports the statements of a block following one which always leaves it.
+func (c *checker) unreachable(list []ast.Statement) {
+	for i, st := range list[:max(len(list)-1, 0)] {
+		switch st.(type) {
+		case *ast.ReturnStatement, *ast.ThrowStatement, *ast.BranchStatement:
+			rest := &span{pos: list[i+1].Pos(), end: list[len(list)-1].End()}
+			c.report(Unreachable, Warning, rest, "unreachable code after %s", st.TokenLiteral())
+			return
+		}
+	}
+}
+
+// arity reports calls to function literals, directly or through a let
+// binding, with an argument count the function does not take.
+func (c *checker) arity(call *ast.CallExpression) {
+	var fn *ast.FunctionLiteral
+	name := "fn"
+	switch f := call.Function.(type) {
+	case *ast.FunctionLiteral:
+		fn = f
+	case *ast.Identifier:
+		if r := c.ref(f); r != nil && r.Binding != nil {
+			fn = r.Binding.Function()
+			name = f.Value
+		}
+	}
+	if fn == nil {
+		return
+	}
+	for _, arg := range call.Arguments {
+		if _, ok := arg.(*ast.SpreadExpression); ok {
+			// the count is only known when it runs
+			return
+		}
+	}
+
+	most := len(fn.Parameters)
+	if fn.Rest != nil {
+		most = -1
+	}
+	if err := runtime.CheckArity(fn.Required(), most, len(call.Arguments)); err != nil {
+		c.report(WrongArity, Error, call, "calling %s: %s", name, err.Message)
+	}
+}
+
+// ref returns the ref of ident.
+func (c *checker) ref(ident *ast.Identifier) *Ref {
+	i := sort.Search(len(c.info.Refs), func(i int) bool {
+		return c.info.Refs[i].Ident.Pos().Offset >= ident.Pos().Offset
+	})
+	if i < len(c.info.Refs) && c.info.Refs[i].Ident == ident {
+		return c.info.Refs[i]
+	}
+	return nil
+}
+
+// span is a range of source to report a diagnostic on.
+type span struct {
+	pos, end token.Position
+}
+
+func (s *span) TokenLiteral() string { return "" }
+func (s *span) Pos() token.Position  { return s.pos }
+func (s *span) End() token.Position  { return s.end }
@@ -0,0 +1,134 @@
+package checker
+
+import (
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/NishanthSpShetty/monkey/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+// check returns the diagnostics of input as "line:col: message".
+func check(t *testing.T, input string, globals ...string) []string {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if !assert.Empty(t, p.Errors(), input) {
+		return nil
+	}
+	out := []string{}
+	for _, d := range Check(program, globals...) {
+		out = append(out, d.Error())
+	}
+	return out
+}
+
+func TestUndefinedNames(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let a = 1; puts(a + b)", []string{"1:21: identifier not found: b"}},
+		{"puts(args)", []string{"1:6: identifier not found: args"}},
+		{"x = 1", []string{"1:1: identifier not found: x"}},
+		// a name is only bound once its let has run
+		{"puts(a); let a = 1; puts(a)", []string{"1:6: identifier not found: a"}},
+		{"let a = a + 1", []string{"1:5: warning: a is declared but never used", "1:9: identifier not found: a"}},
+		// functions run after the names they use are declared
+		{"let f = fn() { g() }; let g = fn() { f() }; f()", []string{}},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(5)", []string{}},
+		// the bindings of blocks belong to the function, like at run time
+		{"if (true) { let a = 1 } puts(a)", []string{}},
+		{"for (i, x in [1]) { puts(i, x) } puts(x)", []string{}},
+		{"try { 1 } catch (e) { puts(e) }", []string{}},
+		{"let f = fn(a) { let b = a; b }; f(1); puts(b)", []string{"1:44: identifier not found: b"}},
+		{"let f = fn(n = m, ...r) { puts(n, r) }; f()", []string{"1:16: identifier not found: m"}},
+		// a let further down a loop is bound on the iterations after the first
+		{"let total = 0; for (i in range(3)) { if (i > 0) { total += prev; } let prev = i; }", []string{}},
+		{"let i = 0; while (i < 3) { i += 1; puts(seen); let seen = i; }", []string{}},
+		{"while (n < 3) { let n = 1 }", []string{}},
+		{"for (i in range(3)) { for (j in range(3)) { puts(prev) } let prev = i }", []string{}},
+		{"for (x in xs) { let xs = [] }", []string{"1:11: identifier not found: xs", "1:21: warning: xs is declared but never used"}},
+		{"for (i in range(3)) { let f = fn() { puts(g) }; f() } let g = 1", []string{}},
+		{"let f = fn() { for (i in range(3)) { puts(prev) } let prev = 1; prev }; f()", []string{"1:43: identifier not found: prev"}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, check(t, tt.input), tt.input)
+	}
+
+	assert.Empty(t, check(t, "puts(args)", "args"))
+}
+
+func TestUnused(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let a = 1", []string{"1:5: warning: a is declared but never used"}},
+		{"let f = fn(a, b) { a }; f(1, 2)", []string{"1:15: warning: parameter b is never used"}},
+		{"let f = fn(...more) { 1 }; f()", []string{"1:15: warning: parameter more is never used"}},
+		// assigning is not using
+		{"let a = 1; a = 2", []string{"1:5: warning: a is declared but never used"}},
+		{"let a = 1; a += 2", []string{}},
+		{"let _ = 1; let f = fn(_x) { 1 }; f(1)", []string{}},
+		// loop variables and caught errors are not reported
+		{"for (i, x in [1]) { puts(x) }", []string{}},
+		{"try { 1 } catch (e) { 2 }", []string{}},
+		// a let declared again is a new binding
+		{"let a = 1; let a = 2; puts(a)", []string{"1:5: warning: a is declared but never used"}},
+		// the use reads the let of the previous iteration, or the one before the loop
+		{"let prev = 0; for (i in range(3)) { puts(prev); let prev = i }", []string{}},
+		{"for (i in range(3)) { let a = 1; puts(a); let a = 2 }", []string{"1:47: warning: a is declared but never used"}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, check(t, tt.input), tt.input)
+	}
+}
+
+func TestShadowedBuiltins(t *testing.T) {
+	expected := []string{
+		"1:5: warning: let len shadows the builtin len",
+		"1:29: warning: parameter map shadows the builtin map",
+		"1:48: warning: loop variable first shadows the builtin first",
+	}
+	assert.Equal(t, expected, check(t, "let len = fn(_xs) { 0 }; fn(map) { map }; for (first in []) { first } len([])"))
+}
+
+func TestUnreachable(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let f = fn() { return 1; puts(2); puts(3) }; f()", []string{"1:26: warning: unreachable code after return"}},
+		{"while (true) { break; puts(1) }", []string{"1:23: warning: unreachable code after break"}},
+		{"for (x in [1]) { if (x) { continue } puts(x) }", []string{}},
+		{"throw 1; puts(2)", []string{"1:10: warning: unreachable code after throw"}},
+		{"let f = fn() { return 1 }; f()", []string{}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, check(t, tt.input), tt.input)
+	}
+}
+
+func TestArity(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let add = fn(a, b) { a + b }; add(1)", []string{"1:31: calling add: wrong number of arguments: want=2, got=1"}},
+		{"let add = fn(a, b = 1) { a + b }; add(1); add(1, 2); add()", []string{"1:54: calling add: wrong number of arguments: want=1 to 2, got=0"}},
+		{"let f = fn(a, ...r) { puts(a, r) }; f(1, 2, 3); f()", []string{"1:49: calling f: wrong number of arguments: want=at least 1, got=0"}},
+		{"fn(x) { x }(1, 2)", []string{"1:1: calling fn: wrong number of arguments: want=1, got=2"}},
+		// spread arguments and reassigned functions are only known at run time
+		{"let f = fn(a) { a }; f(...[1, 2])", []string{}},
+		{"let f = fn(a) { a }; f = fn() { 1 }; f()", []string{}},
+		// the inner f is not the function
+		{"let f = fn(a) { a }; let g = fn(f) { f() }; g(f)", []string{}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, check(t, tt.input), tt.input)
+	}
+}
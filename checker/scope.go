@@ -0,0 +1,266 @@
+package checker
+
+import (
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+// BindingKind tells how a name was declared.
+type BindingKind string
+
+const (
+	Let       BindingKind = "let"
+	Parameter BindingKind = "parameter"
+	LoopVar   BindingKind = "loop variable"
+	CatchVar  BindingKind = "caught error"
+)
+
+// Binding is a name declared by a let, a parameter, a loop variable or a catch.
+type Binding struct {
+	Name *ast.Identifier
+	Kind BindingKind
+	// From is where the name can be used from, the end of a let statement
+	// or the start of the function, loop or catch declaring it
+	From token.Position
+	// Let is the statement declaring a let, Func the function declaring a parameter
+	Let  *ast.LetStatement
+	Func *ast.FunctionLiteral
+	// Uses are the identifiers reading the binding, Assigned is set when
+	// something else is assigned to it
+	Uses     []*ast.Identifier
+	Assigned bool
+}
+
+// Function returns the function literal bound by a let, nil when the value
+// is something else or may be replaced.
+func (b *Binding) Function() *ast.FunctionLiteral {
+	if b.Let == nil || b.Assigned {
+		return nil
+	}
+	fn, _ := b.Let.Value.(*ast.FunctionLiteral)
+	return fn
+}
+
+// Scope is the program or a function literal, the only constructs which
+// open a scope: like runtime.NewScope on a call, the bindings of the blocks
+// in a function belong to the function.
+type Scope struct {
+	Node     ast.Node
+	Outer    *Scope
+	Bindings []*Binding
+	Children []*Scope
+}
+
+// Ref is an identifier of the program. Binding is the binding it declares
+// or refers to, nil for builtins and undefined names.
+type Ref struct {
+	Ident   *ast.Identifier
+	Scope   *Scope
+	Binding *Binding
+	Decl    bool
+}
+
+// Info is what Analyze learns about a program.
+type Info struct {
+	// Scope is the scope of the program, those of its functions are nested in it
+	Scope *Scope
+	// Refs are the identifiers in source order
+	Refs []*Ref
+}
+
+// Analyze builds the scope tree of program and resolves its identifiers.
+func Analyze(program *ast.Program) *Info {
+	info := &Info{Scope: &Scope{Node: program}}
+	current := info.Scope
+	decls := map[*ast.Identifier]*Binding{}
+	// targets are the identifiers assigned with a plain =
+	targets := map[*ast.Identifier]bool{}
+	// loops are the loops running the refs again, see outerLoop
+	loops := map[*Ref]ast.Node{}
+
+	declare := func(name *ast.Identifier, b *Binding) {
+		if name == nil {
+			return
+		}
+		b.Name = name
+		current.Bindings = append(current.Bindings, b)
+		decls[name] = b
+	}
+
+	var stack []ast.Node
+	ast.Inspect(program, func(n ast.Node) bool {
+		if n == nil {
+			if _, ok := stack[len(stack)-1].(*ast.FunctionLiteral); ok {
+				current = current.Outer
+			}
+			stack = stack[:len(stack)-1]
+			return false
+		}
+		stack = append(stack, n)
+
+		switch n := n.(type) {
+		case *ast.LetStatement:
+			declare(n.Name, &Binding{Kind: Let, From: n.End(), Let: n})
+		case *ast.FunctionLiteral:
+			inner := &Scope{Node: n, Outer: current}
+			current.Children = append(current.Children, inner)
+			current = inner
+			for _, param := range n.Parameters {
+				declare(param, &Binding{Kind: Parameter, From: n.Pos(), Func: n})
+			}
+			declare(n.Rest, &Binding{Kind: Parameter, From: n.Pos(), Func: n})
+		case *ast.ForStatement:
+			declare(n.Key, &Binding{Kind: LoopVar, From: n.Pos()})
+			declare(n.Value, &Binding{Kind: LoopVar, From: n.Pos()})
+		case *ast.TryStatement:
+			if n.Catch != nil {
+				declare(n.Param, &Binding{Kind: CatchVar, From: n.Catch.Pos()})
+			}
+		case *ast.AssignExpression:
+			if target, ok := n.Target.(*ast.Identifier); ok && n.Operator == "=" {
+				targets[target] = true
+			}
+		case *ast.Identifier:
+			r := &Ref{Ident: n, Scope: current, Binding: decls[n], Decl: decls[n] != nil}
+			info.Refs = append(info.Refs, r)
+			if loop := outerLoop(stack); loop != nil {
+				loops[r] = loop
+			}
+		}
+		return true
+	})
+
+	// every binding is known now, names may be used before they are declared
+	for _, r := range info.Refs {
+		if r.Decl {
+			continue
+		}
+		r.Binding = r.Scope.Lookup(r.Ident.Value, r.Ident.Pos())
+		bindings := []*Binding{r.Binding}
+		// a let further down a loop is bound from the next iteration on,
+		// unless the loop binds the name again before the use
+		if loop := loops[r]; loop != nil && (r.Binding == nil || !inside(r.Binding.From, loop)) {
+			if b := r.Scope.declaredIn(loop, r.Ident.Value, r.Ident.Pos()); b != nil {
+				if r.Binding == nil {
+					r.Binding = b
+				}
+				bindings = append(bindings, b)
+			}
+		}
+		for _, b := range bindings {
+			switch {
+			case b == nil:
+			case targets[r.Ident]:
+				b.Assigned = true
+			default:
+				b.Uses = append(b.Uses, r.Ident)
+			}
+		}
+	}
+	return info
+}
+
+// outerLoop returns the outermost loop of the function at the top of stack
+// which runs the last node of stack again on every iteration: one in the body
+// or the condition of a while, or in the body of a for.
+func outerLoop(stack []ast.Node) ast.Node {
+	var loop ast.Node
+	for i := len(stack) - 2; i >= 0; i-- {
+		switch n := stack[i].(type) {
+		case *ast.FunctionLiteral:
+			return loop
+		case *ast.WhileStatement:
+			loop = n
+		case *ast.ForStatement:
+			if stack[i+1] == ast.Node(n.Body) {
+				loop = n
+			}
+		}
+	}
+	return loop
+}
+
+// declaredIn returns the first let of name in s following pos within loop.
+func (s *Scope) declaredIn(loop ast.Node, name string, pos token.Position) *Binding {
+	for _, b := range s.Bindings {
+		if b.Kind == Let && b.Name.Value == name && b.From.Offset > pos.Offset && inside(b.From, loop) {
+			return b
+		}
+	}
+	return nil
+}
+
+func inside(pos token.Position, n ast.Node) bool {
+	return n.Pos().Offset <= pos.Offset && pos.Offset <= n.End().Offset
+}
+
+// Lookup finds the binding of name used at pos in s. A name declared later
+// in an enclosing scope is found too, as a function runs after it is created.
+func (s *Scope) Lookup(name string, pos token.Position) *Binding {
+	for sc := s; sc != nil; sc = sc.Outer {
+		var before, after *Binding
+		for _, b := range sc.Bindings {
+			if b.Name.Value != name {
+				continue
+			}
+			if b.From.Offset <= pos.Offset {
+				before = b
+			} else if after == nil {
+				after = b
+			}
+		}
+		if before != nil {
+			return before
+		}
+		if after != nil && sc != s {
+			return after
+		}
+	}
+	return nil
+}
+
+// Visible returns the bindings which can be used at pos in s, innermost first.
+func (s *Scope) Visible(pos token.Position) []*Binding {
+	seen := map[string]bool{}
+	var out []*Binding
+	for sc := s; sc != nil; sc = sc.Outer {
+		for i := len(sc.Bindings) - 1; i >= 0; i-- {
+			b := sc.Bindings[i]
+			if seen[b.Name.Value] || (sc == s && b.From.Offset > pos.Offset) {
+				continue
+			}
+			seen[b.Name.Value] = true
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// ScopeAt returns the innermost scope holding pos.
+func (info *Info) ScopeAt(pos token.Position) *Scope {
+	s := info.Scope
+	for {
+		inner := s
+		for _, c := range s.Children {
+			if c.Node.Pos().Offset <= pos.Offset && pos.Offset < c.Node.End().Offset {
+				inner = c
+				break
+			}
+		}
+		if inner == s {
+			return s
+		}
+		s = inner
+	}
+}
+
+// RefAt returns the identifier at pos, a position just past its end is on it too.
+func (info *Info) RefAt(pos token.Position) *Ref {
+	for _, r := range info.Refs {
+		start, end := r.Ident.Pos(), r.Ident.End()
+		if start.Line == pos.Line && start.Column <= pos.Column && pos.Column <= end.Column {
+			return r
+		}
+	}
+	return nil
+}
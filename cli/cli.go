@@ -0,0 +1,357 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/checker"
+	"github.com/NishanthSpShetty/monkey/formatter"
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/NishanthSpShetty/monkey/lsp"
+	"github.com/NishanthSpShetty/monkey/parser"
+	"github.com/NishanthSpShetty/monkey/repl"
+	"github.com/NishanthSpShetty/monkey/runtime/compiler"
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator"
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/NishanthSpShetty/monkey/runtime/vm"
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+// exit statuses of the monkey command
+const (
+	ExitOK = iota
+	// ExitError is returned when the program failed to parse or raised an error
+	ExitError
+	// ExitUsage is returned when the command line itself is wrong
+	ExitUsage
+)
+
+const usage = `usage: monkey <command> [arguments]
+
+commands:
+  run [flags] <file> [args...]             run a script, - reads it from stdin
+  eval [flags] <source> [args...]          evaluate source and print the result
+  repl                                      start the interactive repl
+  check <file>...                           report mistakes without running
+  fmt [-w] [-l] <file>...                   format scripts, printing the result
+  lsp                                       run the language server on stdin and stdout
+  help                                      show this message
+
+monkey <file> [args...] is short for monkey run, so scripts can start with
+#!/usr/bin/env monkey. -e <source> is short for eval. Script arguments are
+bound to the array args.
+
+run and eval flags:
+  -engine eval|vm     eval walks the tree, vm runs bytecode
+  -timeout duration   stop the script after this long, like 2s
+  -max-steps n        stop the script after n evaluation steps, the vm counts instructions
+  -max-depth n        maximum depth of nested function calls
+  -max-alloc bytes    stop the script after allocating about this many bytes
+
+fmt flags:
+  -w    write the result back to the file instead of printing it
+  -l    list the files whose formatting differs instead of printing them
+`
+
+type command struct {
+	in             io.Reader
+	stdout, stderr io.Writer
+}
+
+// Run executes the monkey command line, args excludes the program name.
+// It returns the exit status of the command.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &command{in: stdin, stdout: stdout, stderr: stderr}
+
+	if len(args) == 0 {
+		return c.repl(nil)
+	}
+
+	switch args[0] {
+	case "run":
+		return c.run(args[1:])
+	case "eval", "-e":
+		return c.eval(args[1:])
+	case "repl":
+		return c.repl(args[1:])
+	case "check":
+		return c.check(args[1:])
+	case "fmt":
+		return c.format(args[1:])
+	case "lsp":
+		return c.lsp(args[1:])
+	case "help", "-h", "-help", "--help":
+		io.WriteString(stdout, usage)
+		return ExitOK
+	}
+
+	if _, err := os.Stat(args[0]); err == nil {
+		return c.run(args)
+	}
+	fmt.Fprintf(stderr, "monkey: unknown command %q\n\n%s", args[0], usage)
+	return ExitUsage
+}
+
+// options are the flags shared by run and eval.
+type options struct {
+	engine  string
+	timeout time.Duration
+	limits  runtime.Limits
+}
+
+func (c *command) flags(name string) (*flag.FlagSet, *options) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	opts := &options{limits: runtime.DefaultLimits}
+	fs.StringVar(&opts.engine, "engine", "eval", "execution engine, eval walks the tree and vm runs bytecode")
+	fs.DurationVar(&opts.timeout, "timeout", 0, "stop the script after this long, 0 for no timeout")
+	fs.Int64Var(&opts.limits.MaxSteps, "max-steps", 0, "maximum number of evaluation steps, 0 for no limit")
+	fs.IntVar(&opts.limits.MaxDepth, "max-depth", opts.limits.MaxDepth, "maximum depth of nested function calls")
+	fs.Int64Var(&opts.limits.MaxAlloc, "max-alloc", 0, "approximate maximum number of bytes allocated, 0 for no limit")
+	return fs, opts
+}
+
+func (c *command) run(args []string) int {
+	fs, opts := c.flags("run")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() < 1 {
+		fmt.Fprintf(c.stderr, "monkey run: missing script\n\n%s", usage)
+		return ExitUsage
+	}
+
+	name := fs.Arg(0)
+	src, err := c.readSource(name)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "monkey run: %s\n", err)
+		return ExitError
+	}
+
+	_, status := c.execute(opts, name, src, fs.Args()[1:])
+	return status
+}
+
+func (c *command) eval(args []string) int {
+	fs, opts := c.flags("eval")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() < 1 {
+		fmt.Fprintf(c.stderr, "monkey eval: missing source\n\n%s", usage)
+		return ExitUsage
+	}
+
+	result, status := c.execute(opts, "eval", fs.Arg(0), fs.Args()[1:])
+	if status != ExitOK {
+		return status
+	}
+	if result != nil && result != runtime.Nil {
+		fmt.Fprintln(c.stdout, result.Inspect())
+	}
+	return ExitOK
+}
+
+func (c *command) repl(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintf(c.stderr, "monkey repl: unexpected arguments %s\n", strings.Join(args, " "))
+		return ExitUsage
+	}
+
+	name := "there"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	fmt.Fprintf(c.stdout, "Hello %s! This is monkey lang\n", name)
+	repl.Start(c.in, c.stdout)
+	return ExitOK
+}
+
+func (c *command) check(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(c.stderr, "monkey check: missing file\n\n%s", usage)
+		return ExitUsage
+	}
+
+	status := ExitOK
+	for _, name := range args {
+		src, err := c.readSource(name)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "monkey check: %s\n", err)
+			status = ExitError
+			continue
+		}
+		program, file, ok := c.parse(name, src)
+		if !ok {
+			status = ExitError
+			continue
+		}
+		// warnings are printed, only errors fail the check
+		for _, d := range checker.Check(program, "args") {
+			fmt.Fprintln(c.stderr, d.Render(file))
+			if d.Severity == checker.Error {
+				status = ExitError
+			}
+		}
+	}
+	return status
+}
+
+func (c *command) format(args []string) int {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	write := fs.Bool("w", false, "write the result back to the file instead of printing it")
+	list := fs.Bool("l", false, "list the files whose formatting differs instead of printing them")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintf(c.stderr, "monkey fmt: missing file\n\n%s", usage)
+		return ExitUsage
+	}
+
+	status := ExitOK
+	for _, name := range fs.Args() {
+		src, err := c.readSource(name)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "monkey fmt: %s\n", err)
+			status = ExitError
+			continue
+		}
+		out, err := formatter.Source(name, src)
+		if err != nil {
+			fmt.Fprintln(c.stderr, err)
+			status = ExitError
+			continue
+		}
+
+		if *list && out != src {
+			fmt.Fprintln(c.stdout, name)
+		}
+		if *write && name != "-" {
+			if out == src {
+				continue
+			}
+			if err := writeFile(name, out); err != nil {
+				fmt.Fprintf(c.stderr, "monkey fmt: %s\n", err)
+				status = ExitError
+			}
+			continue
+		}
+		if !*list {
+			io.WriteString(c.stdout, out)
+		}
+	}
+	return status
+}
+
+func (c *command) lsp(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintf(c.stderr, "monkey lsp: unexpected arguments %s\n", strings.Join(args, " "))
+		return ExitUsage
+	}
+	if err := lsp.Serve(c.in, c.stdout); err != nil {
+		fmt.Fprintf(c.stderr, "monkey lsp: %s\n", err)
+		return ExitError
+	}
+	return ExitOK
+}
+
+// writeFile replaces the content of the existing file name, keeping its permissions.
+func writeFile(name, content string) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, []byte(content), info.Mode().Perm())
+}
+
+// readSource reads the script called name, - is the standard input.
+func (c *command) readSource(name string) (string, error) {
+	if name == "-" {
+		b, err := io.ReadAll(c.in)
+		return string(b), err
+	}
+	b, err := os.ReadFile(name)
+	return string(b), err
+}
+
+// parse parses src, printing the syntax errors found.
+func (c *command) parse(name, src string) (*ast.Program, *token.File, bool) {
+	l := lexer.NewNamed(name, src)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		for _, err := range errs {
+			fmt.Fprintln(c.stderr, err.Render(l.File()))
+		}
+		return nil, l.File(), false
+	}
+	return program, l.File(), true
+}
+
+// execute parses and runs src as opts say, printing any error to stderr.
+func (c *command) execute(opts *options, name, src string, args []string) (runtime.Object, int) {
+	if opts.engine != "eval" && opts.engine != "vm" {
+		fmt.Fprintf(c.stderr, "monkey: unknown engine %q, want eval or vm\n", opts.engine)
+		return nil, ExitUsage
+	}
+
+	program, file, ok := c.parse(name, src)
+	if !ok {
+		return nil, ExitError
+	}
+
+	argv := &runtime.Array{}
+	for _, a := range args {
+		argv.Elements = append(argv.Elements, &runtime.String{Value: a})
+	}
+
+	ctx := context.Background()
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	var result runtime.Object
+	if opts.engine == "vm" {
+		result = runVM(ctx, opts.limits, program, argv)
+	} else {
+		r := runtime.New()
+		r.SetLimits(opts.limits)
+		r.Put("args", argv)
+		result = evaluator.Eval(ctx, r, program)
+	}
+
+	if err, ok := result.(*runtime.Error); ok {
+		fmt.Fprintln(c.stderr, err.Render(file))
+		return result, ExitError
+	}
+	return result, ExitOK
+}
+
+func runVM(ctx context.Context, limits runtime.Limits, program *ast.Program, argv *runtime.Array) runtime.Object {
+	symbols := compiler.NewSymbolTable()
+	globals := make([]runtime.Object, vm.GlobalsSize)
+	globals[symbols.Define("args").Index] = argv
+
+	c := compiler.NewWithState(symbols, []runtime.Object{})
+	if err := c.Compile(program); err != nil {
+		rerr := runtime.NewError("%s", err)
+		if cerr, ok := err.(*compiler.Error); ok {
+			rerr.Pos = cerr.Pos
+		}
+		return rerr
+	}
+	machine := vm.NewWithGlobals(c.Bytecode(), globals)
+	machine.SetLimits(limits)
+	return machine.RunContext(ctx)
+}
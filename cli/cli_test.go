@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runCLI(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := Run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+func writeScript(t *testing.T, src string) string {
+	path := filepath.Join(t.TempDir(), "script.mk")
+	assert.NoError(t, os.WriteFile(path, []byte(src), 0o755))
+	return path
+}
+
+func TestEval(t *testing.T) {
+	for _, engine := range []string{"eval", "vm"} {
+		status, stdout, stderr := runCLI("", "eval", "-engine", engine, "let a = 20; a * 2 + 2")
+		assert.Equal(t, ExitOK, status, stderr)
+		assert.Equal(t, "42\n", stdout)
+
+		status, stdout, _ = runCLI("", "-e", "-engine", engine, "args", "a", "b")
+		assert.Equal(t, ExitOK, status)
+		assert.Equal(t, "[a, b]\n", stdout)
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	path := writeScript(t, "#!/usr/bin/env monkey\nlet n = len(args);\nif (n != 2) { n + true }\n")
+
+	for _, engine := range []string{"eval", "vm"} {
+		status, _, stderr := runCLI("", "run", "-engine", engine, path, "one", "two")
+		assert.Equal(t, ExitOK, status, stderr)
+
+		// the script fails when it does not get exactly two arguments
+		status, _, stderr = runCLI("", "run", "-engine", engine, path)
+		assert.Equal(t, ExitError, status)
+		assert.Equal(t, path+":3:15: Error: type mismatch: Integer + Boolean\n"+
+			"    if (n != 2) { n + true }\n"+
+			"                  ^\n", stderr)
+	}
+
+	// a path on its own is short for run, the way a shebang invokes it
+	status, _, stderr := runCLI("", path, "one", "two")
+	assert.Equal(t, ExitOK, status, stderr)
+
+	status, _, stderr = runCLI("len(args) + 1", "run", "-")
+	assert.Equal(t, ExitOK, status, stderr)
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	path := writeScript(t, "let a = 1;\nlet b = (a + 1;\n")
+
+	status, _, stderr := runCLI("", "run", path)
+	assert.Equal(t, ExitError, status)
+	assert.Contains(t, stderr, path+":2:15: expected next token to be ), got ; instead")
+
+	status, _, stderr = runCLI("", "check", path)
+	assert.Equal(t, ExitError, status)
+	assert.Contains(t, stderr, path+":2:15:")
+
+	status, _, stderr = runCLI("", "check", writeScript(t, "let a = 1;"))
+	assert.Equal(t, ExitOK, status, stderr)
+}
+
+func TestCheck(t *testing.T) {
+	path := writeScript(t, "let a = 1;\nlet f = fn(x, y) { x };\nf(b);\n")
+
+	status, _, stderr := runCLI("", "check", path)
+	assert.Equal(t, ExitError, status)
+	assert.Contains(t, stderr, path+":1:5: warning: a is declared but never used")
+	assert.Contains(t, stderr, path+":2:15: warning: parameter y is never used")
+	assert.Contains(t, stderr, path+":3:1: calling f: wrong number of arguments: want=2, got=1")
+	assert.Contains(t, stderr, path+":3:3: identifier not found: b")
+
+	// warnings alone pass, args is bound for scripts
+	status, _, stderr = runCLI("", "check", writeScript(t, "let len = fn(_) { 0 };\nputs(len(args));\n"))
+	assert.Equal(t, ExitOK, status, stderr)
+	assert.Contains(t, stderr, "warning: let len shadows the builtin len")
+}
+
+func TestFormat(t *testing.T) {
+	path := writeScript(t, "let a=1\nputs( a )")
+	formatted := "let a = 1;\nputs(a);\n"
+
+	status, stdout, stderr := runCLI("", "fmt", path)
+	assert.Equal(t, ExitOK, status, stderr)
+	assert.Equal(t, formatted, stdout)
+
+	status, stdout, _ = runCLI("", "fmt", "-l", path)
+	assert.Equal(t, ExitOK, status)
+	assert.Equal(t, path+"\n", stdout)
+
+	status, stdout, _ = runCLI("", "fmt", "-w", path)
+	assert.Equal(t, ExitOK, status)
+	assert.Empty(t, stdout)
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, formatted, string(content))
+
+	// formatted files are not listed
+	status, stdout, _ = runCLI("", "fmt", "-l", path)
+	assert.Equal(t, ExitOK, status)
+	assert.Empty(t, stdout)
+
+	status, stdout, _ = runCLI("x=[1,2]", "fmt", "-")
+	assert.Equal(t, ExitOK, status)
+	assert.Equal(t, "x = [1, 2];\n", stdout)
+
+	status, _, stderr = runCLI("", "fmt", writeScript(t, "let = 1"))
+	assert.Equal(t, ExitError, status)
+	assert.Contains(t, stderr, ":1:5: expected next token to be IDENT")
+}
+
+func TestLSP(t *testing.T) {
+	frame := func(body string) string {
+		return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	input := frame(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`) +
+		frame(`{"jsonrpc":"2.0","id":2,"method":"shutdown"}`) +
+		frame(`{"jsonrpc":"2.0","method":"exit"}`)
+
+	status, stdout, stderr := runCLI(input, "lsp")
+	assert.Equal(t, ExitOK, status, stderr)
+	assert.Contains(t, stdout, `"hoverProvider":true`)
+	assert.Contains(t, stdout, `{"jsonrpc":"2.0","id":2,"result":null}`)
+}
+
+func TestStackTrace(t *testing.T) {
+	path := writeScript(t, "let check = fn(x) { x + true };\nlet run = fn() { check(1) };\nrun();\n")
+
+	for _, engine := range []string{"eval", "vm"} {
+		status, _, stderr := runCLI("", "run", "-engine", engine, path)
+		assert.Equal(t, ExitError, status)
+		assert.Equal(t, path+":1:21: Error: type mismatch: Integer + Boolean\n"+
+			"    let check = fn(x) { x + true };\n"+
+			"                        ^\n"+
+			"stack trace (most recent call first):\n"+
+			"    check(1) at "+path+":2:18\n"+
+			"    run() at "+path+":3:1\n", stderr)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	for _, engine := range []string{"eval", "vm"} {
+		status, _, stderr := runCLI("", "eval", "-engine", engine, "-max-steps", "1000", "while (true) {}")
+		assert.Equal(t, ExitError, status)
+		assert.Contains(t, stderr, "Error: step limit exceeded: 1000 steps")
+
+		status, _, stderr = runCLI("", "eval", "-engine", engine, "-timeout", "20ms", "while (true) {}")
+		assert.Equal(t, ExitError, status)
+		assert.Contains(t, stderr, "Error: evaluation canceled: context deadline exceeded")
+
+		status, _, stderr = runCLI("", "eval", "-engine", engine, "-max-alloc", "65536", "let a = []; while (true) { push(a, 1) }")
+		assert.Equal(t, ExitError, status)
+		assert.Contains(t, stderr, "Error: allocation limit exceeded: 65536 bytes")
+
+		status, _, stderr = runCLI("", "eval", "-engine", engine, "let f = fn() { f() }; f()")
+		assert.Equal(t, ExitError, status)
+		assert.Contains(t, stderr, "Error: maximum call depth exceeded: 10000")
+
+		status, _, stderr = runCLI("", "eval", "-engine", engine, "-max-depth", "3", "let f = fn(n) { if (n > 0) { f(n - 1) } }; f(5)")
+		assert.Equal(t, ExitError, status)
+		assert.Contains(t, stderr, "Error: maximum call depth exceeded: 3")
+	}
+
+	// nesting deep enough to overflow the stack of the parser is an error
+	status, _, stderr := runCLI("", "eval", strings.Repeat("[", 3000000))
+	assert.Equal(t, ExitError, status)
+	assert.Contains(t, stderr, "nesting is too deep, more than 1000 levels")
+}
+
+func TestUsage(t *testing.T) {
+	tests := [][]string{
+		{"nosuchcommand"},
+		{"run"},
+		{"eval", "-engine", "jit", "1"},
+		{"eval", "-nosuchflag", "1"},
+		{"check"},
+		{"fmt"},
+		{"fmt", "-x", "a.mk"},
+		{"lsp", "extra"},
+	}
+	for _, args := range tests {
+		status, _, _ := runCLI("", args...)
+		assert.Equalf(t, ExitUsage, status, "args %v", args)
+	}
+
+	status, stdout, _ := runCLI("", "help")
+	assert.Equal(t, ExitOK, status)
+	assert.Contains(t, stdout, "usage: monkey")
+}
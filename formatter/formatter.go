@@ -0,0 +1,256 @@
+// Package formatter prints monkey programs in a canonical style: four space
+// indentation, single spaces around binary operators, a semicolon after
+// every simple statement and arrays and hashes on one line as long as they
+// fit in Width. Comments are kept where they were, relative to the
+// statements and elements around them, and formatting formatted source
+// leaves it as it is.
+package formatter
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/NishanthSpShetty/monkey/parser"
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+// Width is the line width arrays and hashes are wrapped at.
+const Width = 80
+
+// indent is the text one level of indentation adds.
+const indent = "    "
+
+// Error is returned for source which does not parse.
+type Error struct {
+	File   *token.File
+	Errors []*parser.ParseError
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Render(e.File)
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Source formats src, read from the file called name. Source with syntax
+// errors is not formatted, they are returned as an *Error.
+func Source(name, src string) (string, error) {
+	l := lexer.NewNamed(name, src)
+	l.SetMode(lexer.ScanComments)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return "", &Error{File: l.File(), Errors: p.Errors()}
+	}
+
+	pr := newPrinter(program.Comments)
+	if strings.HasPrefix(src, "#!") {
+		// the lexer skips the shebang line, keep it as it is
+		shebang, _, _ := strings.Cut(src, "\n")
+		pr.write(strings.TrimSuffix(shebang, "\r"))
+		pr.line = 1
+	}
+	pr.program(program)
+	return pr.out.String(), nil
+}
+
+// Program formats program, its comments are printed when it was parsed with
+// the lexer keeping them.
+func Program(program *ast.Program) string {
+	p := newPrinter(program.Comments)
+	p.program(program)
+	return p.out.String()
+}
+
+// Expression formats e on its own, without comments.
+func Expression(e ast.Expression) string {
+	p := &printer{}
+	p.expression(e, parser.LOWEST)
+	return p.out.String()
+}
+
+type printer struct {
+	out strings.Builder
+	// depth is the indentation level and col the column the next character goes to
+	depth int
+	col   int
+
+	comments []*ast.Comment
+	// next is the first comment not printed yet
+	next int
+	// line is the source line of the last statement or comment printed
+	line int
+	// open is set right after an opening brace, where blank lines are dropped
+	open bool
+	// flat keeps arrays and hashes on one line, it is used to measure them
+	flat bool
+}
+
+func newPrinter(groups []*ast.CommentGroup) *printer {
+	p := &printer{}
+	for _, g := range groups {
+		p.comments = append(p.comments, g.List...)
+	}
+	return p
+}
+
+// measure returns a printer which prints on one line as far as it can and
+// leaves the comments alone.
+func (p *printer) measure() *printer {
+	return &printer{depth: p.depth, flat: true}
+}
+
+func (p *printer) write(s string) {
+	p.out.WriteString(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		p.col = utf8.RuneCountInString(s[i+1:])
+		return
+	}
+	p.col += utf8.RuneCountInString(s)
+}
+
+// newline starts an indented line.
+func (p *printer) newline() {
+	p.write("\n" + strings.Repeat(indent, p.depth))
+}
+
+// linebreak starts the line for what is at line in the source, keeping a
+// blank line before it when the source has one or more.
+func (p *printer) linebreak(line int) {
+	if p.out.Len() > 0 {
+		if !p.open && line-p.line > 1 {
+			p.write("\n")
+		}
+		p.newline()
+	}
+	p.open = false
+}
+
+func (p *printer) program(program *ast.Program) {
+	p.statements(program.Statements, token.Position{Offset: math.MaxInt})
+	if p.out.Len() > 0 {
+		p.write("\n")
+	}
+}
+
+// statements prints list a statement per line, followed by the comments
+// before end.
+func (p *printer) statements(list []ast.Statement, end token.Position) {
+	for i, s := range list {
+		next := end.Offset
+		if i+1 < len(list) {
+			next = list[i+1].Pos().Offset
+		}
+		p.leading(s.Pos())
+		p.linebreak(s.Pos().Line)
+		p.statement(s)
+		p.line = s.End().Line
+		p.trailing(s.End(), next)
+	}
+	p.leading(end)
+}
+
+// leading prints the comments before pos, each on its own line.
+func (p *printer) leading(pos token.Position) {
+	for ; p.next < len(p.comments); p.next++ {
+		c := p.comments[p.next]
+		if c.Pos().Offset >= pos.Offset {
+			return
+		}
+		p.linebreak(c.Pos().Line)
+		p.write(c.Token.Literal)
+		p.line = c.End().Line
+	}
+}
+
+// trailing prints the comments on the line of end and before the offset
+// next after what ended at end. The comments left inside it, which have no
+// line of their own in the output, are moved there too.
+func (p *printer) trailing(end token.Position, next int) {
+	lineComment := false
+	for ; p.next < len(p.comments); p.next++ {
+		c := p.comments[p.next]
+		inside := c.Pos().Offset < end.Offset
+		if !inside && (c.Pos().Line != end.Line || c.Pos().Offset >= next) {
+			return
+		}
+		if lineComment {
+			// nothing can follow a // comment on its line
+			p.newline()
+		} else {
+			p.write(" ")
+		}
+		p.write(c.Token.Literal)
+		p.line = max(p.line, c.End().Line)
+		lineComment = strings.HasPrefix(c.Token.Literal, "//")
+	}
+}
+
+// hasComments reports whether comments not printed yet start between from and to.
+func (p *printer) hasComments(from, to token.Position) bool {
+	for _, c := range p.comments[p.next:] {
+		if c.Pos().Offset >= to.Offset {
+			return false
+		}
+		if c.Pos().Offset >= from.Offset {
+			return true
+		}
+	}
+	return false
+}
+
+// item is an element of an array or a pair of a hash.
+type item struct {
+	pos, end token.Position
+	print    func(p *printer)
+}
+
+// items prints the items between the delimiters open and close, the closing
+// one at closing in the source. They go on one line when that fits in Width
+// and holds no comments, otherwise on a line each.
+func (p *printer) items(open, close string, list []item, node ast.Node, closing token.Position) {
+	m := p.measure()
+	m.write(open)
+	for i, it := range list {
+		if i > 0 {
+			m.write(", ")
+		}
+		it.print(m)
+	}
+	m.write(close)
+	line := m.out.String()
+
+	if p.flat || (!strings.Contains(line, "\n") && p.col+utf8.RuneCountInString(line) <= Width && !p.hasComments(node.Pos(), closing)) {
+		p.write(line)
+		return
+	}
+
+	p.write(open)
+	p.depth++
+	for i, it := range list {
+		next := closing.Offset
+		if i+1 < len(list) {
+			next = list[i+1].pos.Offset
+		}
+		p.open = true
+		p.leading(it.pos)
+		p.open = true
+		p.linebreak(it.pos.Line)
+		it.print(p)
+		if i+1 < len(list) {
+			p.write(",")
+		}
+		p.line = it.end.Line
+		p.trailing(it.end, next)
+	}
+	p.open = true
+	p.leading(closing)
+	p.depth--
+	p.newline()
+	p.write(close)
+}
@@ -0,0 +1,160 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/NishanthSpShetty/monkey/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let  a=1", "let a = 1;\n"},
+		{"a+b*c;(a+b)*c", "a + b * c;\n(a + b) * c;\n"},
+		{"a-(b-c);(a-b)-c; -(-x); !(a==b)", "a - (b - c);\na - b - c;\n--x;\n!(a == b);\n"},
+		{"(-a)[0]; -a[0]; (a = 1) + 2; x = y = 3", "(-a)[0];\n-a[0];\n(a = 1) + 2;\nx = y = 3;\n"},
+		{"let f=fn(a,b=1,...c){return a}", "let f = fn(a, b = 1, ...c) {\n    return a;\n};\n"},
+		{"fn(){}; f(...xs, 1)", "fn() {};\nf(...xs, 1);\n"},
+		{"if(x){1}else{2}", "if (x) {\n    1;\n} else {\n    2;\n}\n"},
+		{"while(x>0){x-=1;continue}", "while (x > 0) {\n    x -= 1;\n    continue;\n}\n"},
+		{"for(k,v in h){puts(k)} for (x in xs) {}", "for (k, v in h) {\n    puts(k);\n}\nfor (x in xs) {}\n"},
+		{"try{throw 1}catch(e){e}finally{}", "try {\n    throw 1;\n} catch (e) {\n    e;\n} finally {}\n"},
+		{"try{1}catch{2}", "try {\n    1;\n} catch {\n    2;\n}\n"},
+		{`"a\"b\\c\n\u{1}é"`, `"a\"b\\c\n\u{1}é";` + "\n"},
+		{"[1,2];{1:true,\"b\":2.5}", "[1, 2];\n{1: true, \"b\": 2.5};\n"},
+		{"#!/usr/bin/env monkey\n\nputs(1)", "#!/usr/bin/env monkey\n\nputs(1);\n"},
+		{"", ""},
+
+		// one blank line is kept between statements, none at the start of a block
+		{"a\n\n\n\nb\nc", "a;\n\nb;\nc;\n"},
+		{"fn() {\n\n  a\n\n  b\n\n}", "fn() {\n    a;\n\n    b;\n};\n"},
+	}
+
+	for _, tt := range tests {
+		out, err := Source("test.mk", tt.input)
+		if assert.NoError(t, err, tt.input) {
+			assert.Equal(t, tt.expected, out, tt.input)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// doc of the file
+
+// add adds
+let add = fn(a, b) { // after the brace
+	a + b // sum
+	// last in the block
+};
+let z = 40 /* plus */ + 2; // trailing
+let xs = [
+  1, // one
+  /* two */ 2
+]
+let e = fn() {
+	// todo
+}
+/* free */`
+
+	expected := `// doc of the file
+
+// add adds
+let add = fn(a, b) {
+    // after the brace
+    a + b; // sum
+    // last in the block
+};
+let z = 40 + 2; /* plus */ // trailing
+let xs = [
+    1, // one
+    /* two */
+    2
+];
+let e = fn() {
+    // todo
+};
+/* free */
+`
+	out, err := Source("test.mk", input)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, out)
+}
+
+func TestWrapping(t *testing.T) {
+	input := `let short = {"a": [1, 2], "b": 3}
+let long = {"name": "a long value here", "other": [1, 2, 3, 4, 5, 6], "third": true}
+let nested = [["a long string in here", "and another one"], ["a third one", "and a fourth"]]
+let fns = [fn(x) { x }]`
+
+	expected := `let short = {"a": [1, 2], "b": 3};
+let long = {
+    "name": "a long value here",
+    "other": [1, 2, 3, 4, 5, 6],
+    "third": true
+};
+let nested = [
+    ["a long string in here", "and another one"],
+    ["a third one", "and a fourth"]
+];
+let fns = [
+    fn(x) {
+        x;
+    }
+];
+`
+	out, err := Source("test.mk", input)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, out)
+	for _, line := range strings.Split(out, "\n") {
+		assert.LessOrEqual(t, len(line), Width, line)
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	inputs := []string{
+		`let fibonacci = fn(x) { if (x == 0) { 0 } else { if (x == 1) { 1 } else { fibonacci(x - 1) + fibonacci(x - 2); } } };`,
+		`let counter = fn() { let n = 0; fn() { n += 1 } } /* a */ // b
+		for (n in range(10, 0, -2)) { if (n < 5) { break } puts(n) }`,
+		`let h = {"k": fn(a, ...b) { a }, /* c */ "j": [1,
+		// d
+		2]} // e`,
+		`let x = 1 +
+		// inside an expression
+		2 // after
+		x`,
+		"a(1, /* arg */ 2)\n\n\n// end\n",
+	}
+
+	for _, input := range inputs {
+		once, err := Source("test.mk", input)
+		if !assert.NoError(t, err, input) {
+			continue
+		}
+		twice, err := Source("test.mk", once)
+		assert.NoError(t, err)
+		assert.Equal(t, once, twice, input)
+
+		// formatting changes the layout, not the program
+		assert.Equal(t, parse(t, input), parse(t, once))
+		assert.Equal(t, strings.Count(input, "//")+strings.Count(input, "/*"), strings.Count(once, "//")+strings.Count(once, "/*"))
+	}
+}
+
+func parse(t *testing.T, src string) string {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	assert.Empty(t, p.Errors())
+	return program.String()
+}
+
+func TestSyntaxError(t *testing.T) {
+	_, err := Source("test.mk", "let = 1")
+	if assert.Error(t, err) {
+		assert.Equal(t, "test.mk:1:5: expected next token to be IDENT, got = instead\n    let = 1\n        ^", err.Error())
+	}
+}
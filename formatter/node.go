@@ -0,0 +1,230 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/parser"
+)
+
+func (p *printer) statement(s ast.Statement) {
+	switch s := s.(type) {
+	case *ast.LetStatement:
+		p.write("let " + s.Name.Value + " = ")
+		p.expression(s.Value, parser.LOWEST)
+		p.write(";")
+	case *ast.ReturnStatement:
+		p.write("return ")
+		p.expression(s.ReturnValue, parser.LOWEST)
+		p.write(";")
+	case *ast.ThrowStatement:
+		p.write("throw ")
+		p.expression(s.Value, parser.LOWEST)
+		p.write(";")
+	case *ast.BranchStatement:
+		p.write(s.Token.Literal + ";")
+	case *ast.ExpressionStatement:
+		p.expression(s.Expression, parser.LOWEST)
+		if _, ok := s.Expression.(*ast.IfExpression); !ok {
+			p.write(";")
+		}
+	case *ast.WhileStatement:
+		p.write("while (")
+		p.expression(s.Condition, parser.LOWEST)
+		p.write(") ")
+		p.block(s.Body)
+	case *ast.ForStatement:
+		p.write("for (")
+		if s.Key != nil {
+			p.write(s.Key.Value + ", ")
+		}
+		p.write(s.Value.Value + " in ")
+		p.expression(s.Iterable, parser.LOWEST)
+		p.write(") ")
+		p.block(s.Body)
+	case *ast.TryStatement:
+		p.write("try ")
+		p.block(s.Body)
+		if s.Catch != nil {
+			p.write(" catch ")
+			if s.Param != nil {
+				p.write("(" + s.Param.Value + ") ")
+			}
+			p.block(s.Catch)
+		}
+		if s.Finally != nil {
+			p.write(" finally ")
+			p.block(s.Finally)
+		}
+	case *ast.BlockStatement:
+		p.block(s)
+	default:
+		panic(fmt.Sprintf("formatter: unexpected statement %T", s))
+	}
+}
+
+// block prints the statements of b indented between braces, an empty block is {}.
+func (p *printer) block(b *ast.BlockStatement) {
+	p.write("{")
+	if len(b.Statements) == 0 && !p.hasComments(b.Pos(), b.Rbrace.Pos) {
+		p.write("}")
+		return
+	}
+	p.depth++
+	p.open = true
+	p.statements(b.Statements, b.Rbrace.Pos)
+	p.depth--
+	p.newline()
+	p.write("}")
+	p.line = b.Rbrace.Pos.Line
+}
+
+// precedence returns how tightly e binds, the way the parser sees it.
+func precedence(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.AssignExpression:
+		return parser.ASSIGN
+	case *ast.InfixExpression:
+		return parser.Precedence(e.Token.Type)
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression:
+		return parser.INDEX
+	}
+	// literals and identifiers
+	return parser.INDEX + 1
+}
+
+// expression prints e, in parentheses when it binds less tightly than least.
+func (p *printer) expression(e ast.Expression, least int) {
+	if precedence(e) < least {
+		p.write("(")
+		defer p.write(")")
+	}
+
+	switch e := e.(type) {
+	case *ast.Identifier:
+		p.write(e.Value)
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.Boolean:
+		p.write(e.TokenLiteral())
+	case *ast.StringLiteral:
+		p.write(quote(e.Value))
+	case *ast.PrefixExpression:
+		p.write(e.Operator)
+		p.expression(e.Right, parser.PREFIX)
+	case *ast.InfixExpression:
+		// operators are left associative, a - (b - c) keeps its parentheses
+		pr := parser.Precedence(e.Token.Type)
+		p.expression(e.Left, pr)
+		p.write(" " + e.Operator + " ")
+		p.expression(e.Right, pr+1)
+	case *ast.AssignExpression:
+		p.expression(e.Target, parser.CALL)
+		p.write(" " + e.Operator + " ")
+		p.expression(e.Value, parser.LOWEST)
+	case *ast.IfExpression:
+		p.write("if (")
+		p.expression(e.Condition, parser.LOWEST)
+		p.write(") ")
+		p.block(e.Consequence)
+		if e.Alternative != nil {
+			p.write(" else ")
+			p.block(e.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		p.function(e)
+	case *ast.CallExpression:
+		p.expression(e.Function, parser.CALL)
+		p.write("(")
+		for i, arg := range e.Arguments {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.expression(arg, parser.LOWEST)
+		}
+		p.write(")")
+	case *ast.SpreadExpression:
+		p.write("...")
+		p.expression(e.Value, parser.LOWEST)
+	case *ast.IndexExpression:
+		p.expression(e.Left, parser.CALL)
+		p.write("[")
+		p.expression(e.Index, parser.LOWEST)
+		p.write("]")
+	case *ast.ArrayLiteral:
+		items := make([]item, len(e.Elements))
+		for i, el := range e.Elements {
+			items[i] = item{pos: el.Pos(), end: el.End(), print: func(p *printer) {
+				p.expression(el, parser.LOWEST)
+			}}
+		}
+		p.items("[", "]", items, e, e.Rbracket.Pos)
+	case *ast.HashLiteral:
+		items := make([]item, len(e.Pairs))
+		for i, pair := range e.Pairs {
+			items[i] = item{pos: pair.Key.Pos(), end: pair.Value.End(), print: func(p *printer) {
+				p.expression(pair.Key, parser.LOWEST)
+				p.write(": ")
+				p.expression(pair.Value, parser.LOWEST)
+			}}
+		}
+		p.items("{", "}", items, e, e.Rbrace.Pos)
+	default:
+		panic(fmt.Sprintf("formatter: unexpected expression %T", e))
+	}
+}
+
+// function prints fn(a, b = 1, ...rest) { ... }
+func (p *printer) function(fn *ast.FunctionLiteral) {
+	p.write("fn(")
+	for i, param := range fn.Parameters {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.write(param.Value)
+		if def := fn.Default(i); def != nil {
+			p.write(" = ")
+			p.expression(def, parser.LOWEST)
+		}
+	}
+	if fn.Rest != nil {
+		if len(fn.Parameters) > 0 {
+			p.write(", ")
+		}
+		p.write("..." + fn.Rest.Value)
+	}
+	p.write(") ")
+	p.block(fn.Body)
+}
+
+// quote returns s as a string literal, escaping what the lexer would not
+// read back as it is.
+func quote(s string) string {
+	var out strings.Builder
+	out.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\r':
+			out.WriteString(`\r`)
+		default:
+			if r < ' ' || r == 0x7f {
+				fmt.Fprintf(&out, `\u{%X}`, r)
+				continue
+			}
+			out.WriteRune(r)
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
@@ -0,0 +1,174 @@
+package interpreter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/NishanthSpShetty/monkey/parser"
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator"
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+// Interpreter evaluates Monkey source on behalf of a Go program. Bindings made
+// by the host or by evaluated source are kept across evaluations.
+//
+// An Interpreter is not safe for concurrent use: evaluations share the global
+// scope and the budget of the limits, which each evaluation resets. Use one
+// Interpreter per goroutine, or serialize the calls.
+type Interpreter struct {
+	runtime *runtime.Runtime
+}
+
+func New() *Interpreter {
+	return &Interpreter{
+		runtime: runtime.New(),
+	}
+}
+
+// Runtime returns the global scope the source is evaluated in.
+func (i *Interpreter) Runtime() *runtime.Runtime {
+	return i.runtime
+}
+
+// Set binds name to value in the global scope.
+func (i *Interpreter) Set(name string, value runtime.Object) {
+	i.runtime.Put(name, value)
+}
+
+// Get returns the value bound to name in the global scope.
+func (i *Interpreter) Get(name string) (runtime.Object, bool) {
+	return i.runtime.Get(name)
+}
+
+// Register exposes fn to scripts as a function called name. Calls are checked
+// against params before reaching fn: the number of arguments must match and each
+// argument must have the listed type, runtime.ObjAny accepts any type. A nil
+// params skips the checks, for functions taking a variable number of arguments.
+func (i *Interpreter) Register(name string, params []runtime.ObjectType, fn runtime.BuiltinFunction) {
+	if params == nil {
+		i.Set(name, &runtime.Builtin{Fn: fn})
+		return
+	}
+	i.Set(name, runtime.NewBuiltin(name, params, fn))
+}
+
+// SetValue converts value with runtime.ToObject and binds it to name in the global scope.
+func (i *Interpreter) SetValue(name string, value interface{}) error {
+	obj, err := runtime.ToObject(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	i.Set(name, obj)
+	return nil
+}
+
+// RegisterFunc exposes the Go function fn to scripts as a function called name.
+// Arguments are converted into the parameter types of fn and the number of
+// arguments must match unless fn is variadic. A trailing error result is
+// reported to the script as a runtime error.
+func (i *Interpreter) RegisterFunc(name string, fn interface{}) error {
+	b, err := runtime.FuncBuiltin(name, fn)
+	if err != nil {
+		return err
+	}
+	i.Set(name, b)
+	return nil
+}
+
+// SetLimits bounds the resources each evaluation may use, an evaluation
+// exceeding them fails with a *RuntimeError whose Err.Limit tells which one.
+// runtime.DefaultLimits only bound the call depth.
+func (i *Interpreter) SetLimits(limits runtime.Limits) {
+	i.runtime.SetLimits(limits)
+}
+
+// SetOutput sends the output of puts to w.
+func (i *Interpreter) SetOutput(w io.Writer) {
+	i.Set("puts", runtime.PutsTo(w))
+}
+
+// Eval parses and evaluates src. A syntax error is returned as a *SyntaxError
+// and a runtime error as a *RuntimeError.
+func (i *Interpreter) Eval(src string) (runtime.Object, error) {
+	return i.EvalNamedContext(context.Background(), "", src)
+}
+
+// EvalContext is Eval stopping with a runtime.Canceled error when ctx is done.
+func (i *Interpreter) EvalContext(ctx context.Context, src string) (runtime.Object, error) {
+	return i.EvalNamedContext(ctx, "", src)
+}
+
+// EvalNamed is Eval for source read from the file called name, which is used in error messages.
+func (i *Interpreter) EvalNamed(name, src string) (runtime.Object, error) {
+	return i.EvalNamedContext(context.Background(), name, src)
+}
+
+// EvalNamedContext is EvalNamed stopping with a runtime.Canceled error when ctx is done.
+func (i *Interpreter) EvalNamedContext(ctx context.Context, name, src string) (runtime.Object, error) {
+	l := lexer.NewNamed(name, src)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &SyntaxError{File: l.File(), Errors: p.Errors()}
+	}
+
+	result := evaluator.Eval(ctx, i.runtime, program)
+	if err, ok := result.(*runtime.Error); ok {
+		return nil, &RuntimeError{File: l.File(), Err: err}
+	}
+	if result == nil {
+		result = runtime.Nil
+	}
+	return result, nil
+}
+
+// EvalValue evaluates src like Eval and converts the result like Value, a
+// result holding itself is an error.
+func (i *Interpreter) EvalValue(src string) (interface{}, error) {
+	obj, err := i.Eval(src)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := runtime.FromObject(obj, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// EvalInto evaluates src like Eval and stores the result into target with runtime.FromObject.
+func (i *Interpreter) EvalInto(src string, target interface{}) error {
+	obj, err := i.Eval(src)
+	if err != nil {
+		return err
+	}
+	return runtime.FromObject(obj, target)
+}
+
+// SyntaxError holds the syntax errors which stopped the source from being evaluated.
+type SyntaxError struct {
+	File   *token.File
+	Errors []*parser.ParseError
+}
+
+func (e *SyntaxError) Error() string {
+	msgs := []string{}
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Render(e.File))
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// RuntimeError is the runtime error the evaluation stopped on.
+type RuntimeError struct {
+	File *token.File
+	Err  *runtime.Error
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Err.Render(e.File)
+}
@@ -0,0 +1,153 @@
+package interpreter
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	i := New()
+	i.Register("repeat", []runtime.ObjectType{runtime.ObjString, runtime.ObjInteger}, func(args ...runtime.Object) runtime.Object {
+		s := args[0].(*runtime.String).Value
+		n := args[1].(*runtime.Integer).Value
+		return &runtime.String{Value: strings.Repeat(s, int(n))}
+	})
+
+	v, err := i.EvalValue(`repeat("ab", 3)`)
+	assert.NoError(t, err)
+	assert.Equal(t, "ababab", v)
+
+	_, err = i.Eval(`repeat("ab")`)
+	assert.EqualError(t, err, "1:1: Error: wrong number of arguments to `repeat`. got=1, want=2\n"+
+		"    repeat(\"ab\")\n"+
+		"    ^")
+
+	_, err = i.Eval(`repeat(3, "ab")`)
+	rerr, ok := err.(*RuntimeError)
+	assert.True(t, ok, "must be a runtime error")
+	assert.Equal(t, "argument 1 to `repeat` must be String, got Integer", rerr.Err.Message)
+}
+
+func TestGlobalsAcrossEvaluations(t *testing.T) {
+	i := New()
+	i.Set("limit", &runtime.Integer{Value: 10})
+
+	_, err := i.Eval("let double = fn(x) { x * 2 };")
+	assert.NoError(t, err)
+
+	v, err := i.EvalValue("double(limit) + 1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(21), v)
+
+	double, ok := i.Get("double")
+	assert.True(t, ok, "let bindings must be visible to the host")
+	assert.Equal(t, runtime.ObjFunction, double.Type())
+}
+
+func TestEvalErrors(t *testing.T) {
+	i := New()
+
+	_, err := i.EvalNamed("rules.mk", "let a = ;\nlet b = (1;")
+	serr, ok := err.(*SyntaxError)
+	assert.True(t, ok, "must be a syntax error")
+	assert.Equal(t, 2, len(serr.Errors), "must report every syntax error")
+	assert.Contains(t, err.Error(), "rules.mk:2:11: expected next token to be ), got ; instead")
+
+	_, err = i.EvalNamed("rules.mk", "1 + true")
+	assert.EqualError(t, err, "rules.mk:1:1: Error: type mismatch: Integer + Boolean\n"+
+		"    1 + true\n"+
+		"    ^")
+
+	// the error is in a function from an earlier evaluation
+	_, err = i.EvalNamed("lib.mk", "let f = fn(x) { x + true };")
+	assert.NoError(t, err)
+	_, err = i.EvalNamed("main.mk", "let y = 1;\nf(y)")
+	assert.EqualError(t, err, "lib.mk:1:17: Error: type mismatch: Integer + Boolean\n"+
+		"    let f = fn(x) { x + true };\n"+
+		"                    ^\n"+
+		"stack trace (most recent call first):\n"+
+		"    f(1) at main.mk:2:1")
+}
+
+func TestLimits(t *testing.T) {
+	i := New()
+	i.SetLimits(runtime.Limits{MaxSteps: 10000})
+
+	_, err := i.Eval("while (true) {}")
+	rerr, ok := err.(*RuntimeError)
+	if assert.True(t, ok, "must be a runtime error") {
+		assert.Equal(t, runtime.StepLimit, rerr.Err.Limit)
+	}
+
+	// the budget is renewed for every evaluation
+	v, err := i.EvalValue("let n = 0; while (n < 100) { n += 1 }; n")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), v)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = i.EvalContext(ctx, "while (true) {}")
+	rerr, ok = err.(*RuntimeError)
+	if assert.True(t, ok, "must be a runtime error") {
+		assert.Equal(t, runtime.Canceled, rerr.Err.Limit)
+		assert.Equal(t, "evaluation canceled: context canceled", rerr.Err.Message)
+	}
+}
+
+func TestValue(t *testing.T) {
+	i := New()
+
+	v, err := i.EvalValue(`[1, "two", true, if (false) { 1 }, {"k": [3]}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		int64(1), "two", true, nil,
+		map[interface{}]interface{}{"k": []interface{}{int64(3)}},
+	}, v)
+
+	v, err = i.EvalValue("let a = 1;")
+	assert.NoError(t, err)
+	assert.Nil(t, v, "a program ending in let has no value")
+}
+
+func TestSetOutput(t *testing.T) {
+	var out bytes.Buffer
+	i := New()
+	i.SetOutput(&out)
+
+	_, err := i.Eval(`puts("hello", 42)`)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n42\n", out.String())
+}
+
+type rule struct {
+	Name  string `monkey:"name"`
+	Limit int    `monkey:"limit"`
+}
+
+func TestGoValues(t *testing.T) {
+	i := New()
+	assert.NoError(t, i.SetValue("rule", rule{Name: "max", Limit: 3}))
+	assert.NoError(t, i.RegisterFunc("shout", strings.ToUpper))
+
+	var r rule
+	assert.NoError(t, i.EvalInto(`{"name": shout(rule["name"]), "limit": rule["limit"] * 2}`, &r))
+	assert.Equal(t, rule{Name: "MAX", Limit: 6}, r)
+
+	_, err := i.Eval(`shout(1)`)
+	rerr, ok := err.(*RuntimeError)
+	assert.True(t, ok, "must be a runtime error")
+	assert.Equal(t, "argument 1 to `shout`: cannot convert Integer to string", rerr.Err.Message)
+
+	_, err = i.EvalValue("let a = [1]; push(a, a); a")
+	assert.EqualError(t, err, "cannot convert Array, it holds itself")
+	self, _ := i.Get("a")
+	assert.Equal(t, self, Value(self), "a value holding itself is returned as it is")
+
+	assert.EqualError(t, i.SetValue("ch", make(chan int)), "ch: cannot convert chan int to a monkey object")
+	assert.EqualError(t, i.RegisterFunc("nope", 1), "cannot wrap int as a builtin, want a function")
+}
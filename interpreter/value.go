@@ -0,0 +1,15 @@
+package interpreter
+
+import "github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+
+// Value converts obj into the Go value closest to it: int64, *big.Int, float64, string, bool,
+// nil, []interface{} for arrays and map[interface{}]interface{} for hashes.
+// Other objects, like functions, and values holding themselves are returned as they are.
+func Value(obj runtime.Object) interface{} {
+	var v interface{}
+	if err := runtime.FromObject(obj, &v); err != nil {
+		// converting into an empty interface only fails on a value holding itself
+		return obj
+	}
+	return v
+}
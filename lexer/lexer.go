@@ -1,46 +1,148 @@
 package lexer
 
-import "github.com/NishanthSpShetty/monkey/token"
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/NishanthSpShetty/monkey/token"
+)
 
 type Lexer struct {
+	file         *token.File
 	input        string
 	position     int
 	readPosition int
-	ch           byte
+	ch           rune
+	// line and column of l.ch, both 1-based, the column counts characters not bytes
+	line   int
+	column int
+	mode   Mode
 }
 
+// Mode controls optional behavior of the lexer.
+type Mode uint
+
+const (
+	// ScanComments returns comments as COMMENT tokens instead of skipping them.
+	ScanComments Mode = 1 << iota
+)
+
+// Literals of the ILLEGAL tokens for input ending inside a string or a block
+// comment, so callers reading more input can tell them from other errors.
+const (
+	UnterminatedString  = "unterminated string literal"
+	UnterminatedComment = "unterminated comment"
+)
+
 func New(input string) *Lexer {
+	return NewNamed("", input)
+}
+
+// NewNamed creates a lexer for input read from the file called name,
+// the name is used when rendering positions.
+func NewNamed(name, input string) *Lexer {
 	l := &Lexer{
+		file:  token.NewFile(name, input),
 		input: input,
+		line:  1,
 	}
 	l.readChar()
+	l.skipShebang()
 
 	return l
 }
 
-// peekChar read a char from buffer into l.ch
-func (l *Lexer) peekChar() byte {
+// skipShebang skips a leading #! line, so scripts can be made executable.
+func (l *Lexer) skipShebang() {
+	if l.ch != '#' || l.peekChar() != '!' {
+		return
+	}
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+}
+
+// SetMode changes the behavior of the lexer for the tokens following.
+func (l *Lexer) SetMode(m Mode) {
+	l.mode = m
+}
+
+// File returns the source being lexed.
+func (l *Lexer) File() *token.File {
+	return l.file
+}
+
+// pos returns the position of the current char l.ch
+func (l *Lexer) pos() token.Position {
+	return token.Position{
+		Offset: l.position,
+		Line:   l.line,
+		Column: l.column,
+	}
+}
+
+// peekChar returns the char after l.ch without moving ahead
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	}
 
-	return l.input[l.readPosition]
+	r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return r
 }
 
-// readChar read a char from buffer into l.ch
+// peekByteAt returns the byte n bytes after the start of l.ch, only meant for ASCII lookahead.
+func (l *Lexer) peekByteAt(n int) byte {
+	if l.position+n >= len(l.input) {
+		return 0
+	}
+
+	return l.input[l.position+n]
+}
+
+// readChar read a char from buffer into l.ch, decoding UTF-8
 func (l *Lexer) readChar() {
+	if l.readPosition > len(l.input) {
+		// already past the end, stay on EOF
+		return
+	}
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+	l.column += 1
+	l.position = l.readPosition
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
-	} else {
-		l.ch = l.input[l.readPosition]
+		l.readPosition += 1
+		return
 	}
-	l.position = l.readPosition
-	l.readPosition += 1
+	r, width := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	l.ch = r
+	l.readPosition += width
 }
 
 func (l *Lexer) NextToken() token.Token {
+	for {
+		l.skipWhiteSpaces()
+		start := l.pos()
+		tok := l.nextToken()
+		if !tok.Pos.IsValid() {
+			// errors may point into the token
+			tok.Pos = start
+		}
+		tok.End = l.pos()
+		if tok.Type == token.COMMENT && l.mode&ScanComments == 0 {
+			continue
+		}
+		return tok
+	}
+}
+
+func (l *Lexer) nextToken() token.Token {
 	var tok token.Token
-	l.skipWhiteSpaces()
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -48,26 +150,32 @@ func (l *Lexer) NextToken() token.Token {
 			l.readChar()
 			tok = token.CreateForStr(token.EQ, "==")
 		} else {
-			tok = token.CreateForByte(token.ASSIGN, l.ch)
+			tok = token.CreateForRune(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = token.CreateForByte(token.PLUS, l.ch)
+		tok = l.operator(token.PLUS, token.PLUS_ASSIGN)
 	case ';':
-		tok = token.CreateForByte(token.SEMICOLON, l.ch)
+		tok = token.CreateForRune(token.SEMICOLON, l.ch)
 	case '(':
-		tok = token.CreateForByte(token.LPAREN, l.ch)
+		tok = token.CreateForRune(token.LPAREN, l.ch)
 	case ')':
-		tok = token.CreateForByte(token.RPAREN, l.ch)
+		tok = token.CreateForRune(token.RPAREN, l.ch)
 	case '{':
-		tok = token.CreateForByte(token.LBRACE, l.ch)
+		tok = token.CreateForRune(token.LBRACE, l.ch)
 	case '}':
-		tok = token.CreateForByte(token.RBRACE, l.ch)
+		tok = token.CreateForRune(token.RBRACE, l.ch)
 	case ',':
-		tok = token.CreateForByte(token.COMMA, l.ch)
+		tok = token.CreateForRune(token.COMMA, l.ch)
 	case '-':
-		tok = token.CreateForByte(token.MINUS, l.ch)
+		tok = l.operator(token.MINUS, token.MINUS_ASSIGN)
 	case '/':
-		tok = token.CreateForByte(token.SLASH, l.ch)
+		switch l.peekChar() {
+		case '/':
+			return l.readLineComment()
+		case '*':
+			return l.readBlockComment()
+		}
+		tok = l.operator(token.SLASH, token.SLASH_ASSIGN)
 	case '!':
 
 		if l.peekChar() == '=' {
@@ -75,30 +183,37 @@ func (l *Lexer) NextToken() token.Token {
 			l.readChar()
 			tok = token.CreateForStr(token.NOT_EQ, "!=")
 		} else {
-			tok = token.CreateForByte(token.BANG, l.ch)
+			tok = token.CreateForRune(token.BANG, l.ch)
 		}
 
 	case '>':
-		tok = token.CreateForByte(token.GT, l.ch)
+		tok = token.CreateForRune(token.GT, l.ch)
 
 	case '<':
-		tok = token.CreateForByte(token.LT, l.ch)
+		tok = token.CreateForRune(token.LT, l.ch)
 	case '*':
-		tok = token.CreateForByte(token.ASTERISK, l.ch)
+		tok = l.operator(token.ASTERISK, token.ASTERISK_ASSIGN)
 
 	case '"':
 		// start of string literal
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		return l.readString()
 	case 0:
 		tok = token.Eof()
 
 	case '[':
-		tok = token.CreateForByte(token.LBRACKET, l.ch)
+		tok = token.CreateForRune(token.LBRACKET, l.ch)
 	case ']':
-		tok = token.CreateForByte(token.RBRACKET, l.ch)
+		tok = token.CreateForRune(token.RBRACKET, l.ch)
 	case ':':
-		tok = token.CreateForByte(token.COLON, l.ch)
+		tok = token.CreateForRune(token.COLON, l.ch)
+	case '.':
+		if l.peekChar() != '.' || l.peekByteAt(2) != '.' {
+			tok = token.CreateForStr(token.ILLEGAL, fmt.Sprintf("unexpected character %q", l.ch))
+			break
+		}
+		l.readChar()
+		l.readChar()
+		tok = token.CreateForStr(token.ELLIPSIS, "...")
 
 	default:
 		if isLetter(l.ch) {
@@ -106,11 +221,10 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
+			tok.Literal, tok.Type = l.readNumber()
 			return tok
 		} else {
-			tok = token.Ill()
+			tok = token.CreateForStr(token.ILLEGAL, fmt.Sprintf("unexpected character %q", l.ch))
 		}
 	}
 
@@ -119,27 +233,170 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
-func (l *Lexer) readString() string {
-	position := l.position + 1 // skip "
-	prev := l.ch
+// operator returns the token for the operator at ch, or its compound
+// assignment when it is followed by `=`.
+func (l *Lexer) operator(plain, assign token.TokenType) token.Token {
+	if l.peekChar() != '=' {
+		return token.CreateForRune(plain, l.ch)
+	}
+	l.readChar()
+	return token.CreateForStr(assign, string(assign))
+}
+
+// readString reads a string literal into a STRING token holding its decoded
+// value. A malformed escape or a missing closing quote gives an ILLEGAL token
+// describing the problem, positioned at the escape or at the opening quote.
+func (l *Lexer) readString() token.Token {
+	start := l.pos()
+	value := strings.Builder{}
+	var illegal *token.Token
+
 	for {
 		l.readChar()
+		switch l.ch {
+		case '"':
+			l.readChar()
+			if illegal != nil {
+				return *illegal
+			}
+			return token.CreateForStr(token.STRING, value.String())
 
-		if (l.ch == '"' && prev != '\\') || l.ch == 0 {
-			break
+		case 0:
+			tok := token.CreateForStr(token.ILLEGAL, UnterminatedString)
+			tok.Pos = start
+			return tok
+
+		case '\\':
+			pos := l.pos()
+			r, msg := l.readEscape()
+			if msg != "" && illegal == nil {
+				// keep reading up to the closing quote, so lexing resumes after the string
+				tok := token.CreateForStr(token.ILLEGAL, msg)
+				tok.Pos = pos
+				illegal = &tok
+			}
+			value.WriteRune(r)
+
+		default:
+			value.WriteRune(l.ch)
 		}
-		prev = l.ch
 	}
-	return l.input[position:l.position]
 }
 
-func (l *Lexer) readNumber() string {
+// readEscape decodes the escape sequence starting at the backslash in l.ch and
+// leaves l.ch on its last char. An invalid sequence is described by msg.
+func (l *Lexer) readEscape() (r rune, msg string) {
+	switch l.peekChar() {
+	case 'n':
+		r = '\n'
+	case 't':
+		r = '\t'
+	case 'r':
+		r = '\r'
+	case '\\':
+		r = '\\'
+	case '"':
+		r = '"'
+	case 'u':
+		l.readChar()
+		return l.readUnicodeEscape()
+	case 0:
+		// the string is unterminated, which is reported instead
+		return utf8.RuneError, ""
+	default:
+		return utf8.RuneError, fmt.Sprintf("unknown escape sequence \\%c", l.peekChar())
+	}
+	l.readChar()
+	return r, ""
+}
+
+// readUnicodeEscape decodes the {XXXX} part of \u{XXXX}, 1 to 6 hex digits naming a code point.
+func (l *Lexer) readUnicodeEscape() (rune, string) {
+	const malformed = "invalid unicode escape, want \\u{XXXX} with 1 to 6 hex digits"
+	if l.peekChar() != '{' {
+		return utf8.RuneError, malformed
+	}
+	l.readChar()
+
+	var r rune
+	digits := 0
+	for isHexDigit(l.peekChar()) {
+		l.readChar()
+		digits++
+		if digits <= 6 {
+			r = r*16 + hexValue(l.ch)
+		}
+	}
+	if l.peekChar() != '}' || digits == 0 || digits > 6 {
+		return utf8.RuneError, malformed
+	}
+	l.readChar()
+
+	if !utf8.ValidRune(r) {
+		return utf8.RuneError, fmt.Sprintf("invalid unicode code point U+%X", r)
+	}
+	return r, ""
+}
+
+// readLineComment reads a // comment up to the end of the line, the newline is not part of it.
+func (l *Lexer) readLineComment() token.Token {
 	position := l.position
-	// read until we see letter
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	return token.CreateForStr(token.COMMENT, l.input[position:l.position])
+}
+
+// readBlockComment reads a /* */ comment, which may span lines but does not nest.
+// A comment left open at the end of the input gives an ILLEGAL token.
+func (l *Lexer) readBlockComment() token.Token {
+	position := l.position
+	l.readChar()
+	l.readChar()
+	for !(l.ch == '*' && l.peekChar() == '/') {
+		if l.ch == 0 {
+			return token.CreateForStr(token.ILLEGAL, UnterminatedComment)
+		}
+		l.readChar()
+	}
+	l.readChar()
+	l.readChar()
+	return token.CreateForStr(token.COMMENT, l.input[position:l.position])
+}
+
+// readNumber reads an integer or a float with an optional fraction and exponent, like 1.5e-3.
+// A fraction needs a digit after the dot and an exponent at least one digit.
+func (l *Lexer) readNumber() (string, token.TokenType) {
+	position := l.position
+	typ := token.TokenType(token.INT)
+	l.readDigits()
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		typ = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+
+	if l.ch == 'e' || l.ch == 'E' {
+		next := 1
+		if c := l.peekByteAt(next); c == '+' || c == '-' {
+			next++
+		}
+		if isDigit(rune(l.peekByteAt(next))) {
+			typ = token.FLOAT
+			for i := 0; i < next; i++ {
+				l.readChar()
+			}
+			l.readDigits()
+		}
+	}
+	return l.input[position:l.position], typ
+}
+
+func (l *Lexer) readDigits() {
 	for isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
 }
 
 func (l *Lexer) readIdentifier() string {
@@ -158,10 +415,26 @@ func (l *Lexer) skipWhiteSpaces() {
 	}
 }
 
-func isLetter(ch byte) bool {
-	return ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ch == '_'
+func isLetter(ch rune) bool {
+	return ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ch == '_' ||
+		(ch >= utf8.RuneSelf && unicode.IsLetter(ch))
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return ('0' <= ch && ch <= '9')
 }
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || ('a' <= ch && ch <= 'f') || ('A' <= ch && ch <= 'F')
+}
+
+func hexValue(ch rune) rune {
+	switch {
+	case isDigit(ch):
+		return ch - '0'
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10
+	default:
+		return ch - 'A' + 10
+	}
+}
@@ -32,6 +32,76 @@ func TestNextTokenWithSpecialChar(t *testing.T) {
 	}
 }
 
+func TestAssignOperators(t *testing.T) {
+	input := `x += 1; x-=y *= 2 /= 3 = 4; a / = b`
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "x"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.IDENT, "y"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "2"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "3"},
+		{token.ASSIGN, "="},
+		{token.INT, "4"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.SLASH, "/"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "b"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for _, tt := range tests {
+		tok := l.NextToken()
+		assert.Equal(t, tt.expectedLiteral, tok.Literal, "invalid token literal")
+		assert.Equal(t, tt.expectedType, tok.Type, "invalid token type")
+	}
+}
+
+func TestEllipsis(t *testing.T) {
+	input := `fn(a, ...rest) { f(...rest) } ..`
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.RPAREN, ")"},
+		{token.RBRACE, "}"},
+		{token.ILLEGAL, "unexpected character '.'"},
+		{token.ILLEGAL, "unexpected character '.'"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for _, tt := range tests {
+		tok := l.NextToken()
+		assert.Equal(t, tt.expectedLiteral, tok.Literal, "invalid token literal")
+		assert.Equal(t, tt.expectedType, tok.Type, "invalid token type")
+	}
+}
+
 func TestNextTokenWithProgram(t *testing.T) {
 	input := `let five= 5;
 	let ten = 10;
@@ -41,7 +111,7 @@ func TestNextTokenWithProgram(t *testing.T) {
 	};
 
 	let result = add(five, ten);
-	!-/*5;
+	!-/ *5;
 	5< 10> 5;
 
 	if (5<10){
@@ -141,7 +211,7 @@ func TestNextTokenWithProgram(t *testing.T) {
 		{token.SEMICOLON, ";"},
 		{token.STRING, "foobar"},
 		{token.STRING, "hello world"},
-		{token.STRING, "hello \\\"nishanth\\\""},
+		{token.STRING, "hello \"nishanth\""},
 		{token.LBRACKET, "["},
 		{token.INT, "1"},
 		{token.COMMA, ","},
@@ -164,3 +234,212 @@ func TestNextTokenWithProgram(t *testing.T) {
 		assert.Equalf(t, tt.expectedLiteral, tok.Literal, "[%d] invalid token literal.", i)
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n\tx + \"ab\""
+
+	tests := []struct {
+		expectedType token.TokenType
+		pos          token.Position
+		end          token.Position
+	}{
+		{token.LET, token.Position{Offset: 0, Line: 1, Column: 1}, token.Position{Offset: 3, Line: 1, Column: 4}},
+		{token.IDENT, token.Position{Offset: 4, Line: 1, Column: 5}, token.Position{Offset: 5, Line: 1, Column: 6}},
+		{token.ASSIGN, token.Position{Offset: 6, Line: 1, Column: 7}, token.Position{Offset: 7, Line: 1, Column: 8}},
+		{token.INT, token.Position{Offset: 8, Line: 1, Column: 9}, token.Position{Offset: 9, Line: 1, Column: 10}},
+		{token.SEMICOLON, token.Position{Offset: 9, Line: 1, Column: 10}, token.Position{Offset: 10, Line: 1, Column: 11}},
+		{token.IDENT, token.Position{Offset: 12, Line: 2, Column: 2}, token.Position{Offset: 13, Line: 2, Column: 3}},
+		{token.PLUS, token.Position{Offset: 14, Line: 2, Column: 4}, token.Position{Offset: 15, Line: 2, Column: 5}},
+		{token.STRING, token.Position{Offset: 16, Line: 2, Column: 6}, token.Position{Offset: 20, Line: 2, Column: 10}},
+		{token.EOF, token.Position{Offset: 20, Line: 2, Column: 10}, token.Position{Offset: 20, Line: 2, Column: 10}},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		assert.Equalf(t, tt.expectedType, tok.Type, "[%d] invalid token type", i)
+		assert.Equalf(t, tt.pos, tok.Pos, "[%d] invalid start position", i)
+		assert.Equalf(t, tt.end, tok.End, "[%d] invalid end position", i)
+	}
+}
+
+func TestShebang(t *testing.T) {
+	l := New("#!/usr/bin/env monkey\nlet")
+
+	tok := l.NextToken()
+	assert.Equal(t, token.TokenType(token.LET), tok.Type, "shebang line must be skipped")
+	assert.Equal(t, "2:1", tok.Pos.String(), "positions must account for the shebang line")
+}
+
+func TestNumbers(t *testing.T) {
+	input := "3.14 10 1e3 2.5E-4 6e+2 1.x 7e 1..2"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.FLOAT, "3.14"},
+		{token.INT, "10"},
+		{token.FLOAT, "1e3"},
+		{token.FLOAT, "2.5E-4"},
+		{token.FLOAT, "6e+2"},
+		{token.INT, "1"},
+		{token.ILLEGAL, "unexpected character '.'"},
+		{token.IDENT, "x"},
+		{token.INT, "7"},
+		{token.IDENT, "e"},
+		{token.INT, "1"},
+		{token.ILLEGAL, "unexpected character '.'"},
+		{token.ILLEGAL, "unexpected character '.'"},
+		{token.INT, "2"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		assert.Equalf(t, tt.expectedType, tok.Type, "[%d] invalid token type", i)
+		assert.Equalf(t, tt.expectedLiteral, tok.Literal, "[%d] invalid token literal.", i)
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading
+let x = 10 / 2; // trailing
+/* block
+   comment */ x
+/* open`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.COMMENT, "// leading"},
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "10"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.COMMENT, "// trailing"},
+		{token.COMMENT, "/* block\n   comment */"},
+		{token.IDENT, "x"},
+		{token.ILLEGAL, "unterminated comment"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	l.SetMode(ScanComments)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		assert.Equalf(t, tt.expectedType, tok.Type, "[%d] invalid token type", i)
+		assert.Equalf(t, tt.expectedLiteral, tok.Literal, "[%d] invalid token literal.", i)
+	}
+
+	// comments are skipped by default
+	l = New(input)
+	for _, tt := range tests {
+		if tt.expectedType == token.COMMENT {
+			continue
+		}
+		tok := l.NextToken()
+		assert.Equal(t, tt.expectedType, tok.Type)
+		assert.Equal(t, tt.expectedLiteral, tok.Literal)
+	}
+}
+
+func TestCommentPositions(t *testing.T) {
+	l := New("x /* a\nb */ y")
+	l.SetMode(ScanComments)
+
+	l.NextToken()
+	tok := l.NextToken()
+	assert.Equal(t, "1:3", tok.Pos.String())
+	assert.Equal(t, "2:5", tok.End.String())
+	tok = l.NextToken()
+	assert.Equal(t, "2:6", tok.Pos.String())
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a\nb"`, "a\nb"},
+		{`"tab\there"`, "tab\there"},
+		{`"cr\r"`, "cr\r"},
+		{`"back\\slash"`, `back\slash`},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"\u{41}\u{e9}\u{1F600}"`, "Aé😀"},
+		{`"héllo wörld"`, "héllo wörld"},
+		{`""`, ""},
+	}
+
+	for _, tt := range tests {
+		tok := New(tt.input).NextToken()
+		assert.Equal(t, token.TokenType(token.STRING), tok.Type, tt.input)
+		assert.Equal(t, tt.expected, tok.Literal, tt.input)
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		pos      string
+	}{
+		{`"open`, "unterminated string literal", "1:1"},
+		{`x "open\"`, "unterminated string literal", "1:3"},
+		{`"bad \q"`, `unknown escape sequence \q`, "1:6"},
+		{`"\u41"`, `invalid unicode escape, want \u{XXXX} with 1 to 6 hex digits`, "1:2"},
+		{`"\u{}"`, `invalid unicode escape, want \u{XXXX} with 1 to 6 hex digits`, "1:2"},
+		{`"\u{1234567}"`, `invalid unicode escape, want \u{XXXX} with 1 to 6 hex digits`, "1:2"},
+		{`"\u{D800}"`, "invalid unicode code point U+D800", "1:2"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != token.ILLEGAL {
+			tok = l.NextToken()
+		}
+		assert.Equal(t, token.TokenType(token.ILLEGAL), tok.Type, tt.input)
+		assert.Equal(t, tt.expected, tok.Literal, tt.input)
+		assert.Equal(t, tt.pos, tok.Pos.String(), tt.input)
+	}
+
+	// lexing resumes after a string with a bad escape
+	l := New(`"\q" x`)
+	l.NextToken()
+	tok := l.NextToken()
+	assert.Equal(t, token.TokenType(token.IDENT), tok.Type)
+	assert.Equal(t, "1:6", tok.Pos.String())
+}
+
+func TestUnicode(t *testing.T) {
+	input := "let größe = \"日本\"; größe\n€"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		pos             string
+	}{
+		{token.LET, "let", "1:1"},
+		{token.IDENT, "größe", "1:5"},
+		{token.ASSIGN, "=", "1:11"},
+		{token.STRING, "日本", "1:13"},
+		{token.SEMICOLON, ";", "1:17"},
+		{token.IDENT, "größe", "1:19"},
+		{token.ILLEGAL, "unexpected character '€'", "2:1"},
+		{token.EOF, "", "2:2"},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		assert.Equalf(t, tt.expectedType, tok.Type, "[%d] invalid token type", i)
+		assert.Equalf(t, tt.expectedLiteral, tok.Literal, "[%d] invalid token literal.", i)
+		assert.Equalf(t, tt.pos, tok.Pos.String(), "[%d] invalid position", i)
+	}
+}
@@ -0,0 +1,141 @@
+package lsp
+
+import (
+	"strings"
+
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/checker"
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/NishanthSpShetty/monkey/parser"
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+// document is an open file, parsed and with its names resolved.
+type document struct {
+	uri     string
+	version int
+	text    string
+	lines   []string
+
+	program *ast.Program
+	errors  []*parser.ParseError
+	info    *checker.Info
+	// checks are the checker diagnostics, only run without syntax errors
+	checks []*checker.Diagnostic
+}
+
+func newDocument(uri string, version int, text string) *document {
+	d := &document{uri: uri, version: version, text: text, lines: strings.Split(text, "\n")}
+
+	l := lexer.NewNamed(uri, text)
+	l.SetMode(lexer.ScanComments)
+	p := parser.New(l)
+	d.program = p.ParseProgram()
+	d.errors = p.Errors()
+	d.info = checker.Analyze(d.program)
+	if len(d.errors) == 0 {
+		// scripts are run with their arguments bound to args
+		d.checks = checker.Check(d.program, "args")
+	}
+	return d
+}
+
+// position converts pos from the client to a position in the source. The
+// offset is only filled when pos is within the text.
+func (d *document) position(pos Position) token.Position {
+	p := token.Position{Line: pos.Line + 1, Column: 1}
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return p
+	}
+	offset := 0
+	for _, line := range d.lines[:pos.Line] {
+		offset += len(line) + 1
+	}
+	units := 0
+	for i, r := range d.lines[pos.Line] {
+		if units >= pos.Character {
+			p.Offset = offset + i
+			return p
+		}
+		units += utf16Len(r)
+		p.Column++
+	}
+	p.Offset = offset + len(d.lines[pos.Line])
+	return p
+}
+
+// lspPosition converts pos in the source to a position for the client.
+func (d *document) lspPosition(pos token.Position) Position {
+	if !pos.IsValid() {
+		return Position{}
+	}
+	p := Position{Line: pos.Line - 1}
+	col := 1
+	if pos.Line <= len(d.lines) {
+		for _, r := range d.lines[pos.Line-1] {
+			if col >= pos.Column {
+				break
+			}
+			p.Character += utf16Len(r)
+			col++
+		}
+	}
+	// past the end of the line
+	p.Character += pos.Column - col
+	return p
+}
+
+func (d *document) lspRange(start, end token.Position) Range {
+	return Range{Start: d.lspPosition(start), End: d.lspPosition(end)}
+}
+
+// end returns the position just past the text.
+func (d *document) end() Position {
+	last := d.lines[len(d.lines)-1]
+	units := 0
+	for _, r := range last {
+		units += utf16Len(r)
+	}
+	return Position{Line: len(d.lines) - 1, Character: units}
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// diagnostics returns the syntax errors of the document, or what the checker
+// found when there are none.
+func (d *document) diagnostics() []Diagnostic {
+	out := []Diagnostic{}
+	for _, err := range d.errors {
+		end := err.Found.End
+		if !end.IsValid() || end.Line != err.Pos.Line || end.Column <= err.Pos.Column {
+			// mark a single character
+			end = token.Position{Line: err.Pos.Line, Column: err.Pos.Column + 1}
+		}
+		out = append(out, Diagnostic{
+			Range:    d.lspRange(err.Pos, end),
+			Severity: SeverityError,
+			Code:     string(err.Kind),
+			Source:   "monkey",
+			Message:  err.Message,
+		})
+	}
+	for _, c := range d.checks {
+		severity := SeverityError
+		if c.Severity == checker.Warning {
+			severity = SeverityWarning
+		}
+		out = append(out, Diagnostic{
+			Range:    d.lspRange(c.Pos, c.End),
+			Severity: severity,
+			Code:     string(c.Kind),
+			Source:   "monkey",
+			Message:  c.Message,
+		})
+	}
+	return out
+}
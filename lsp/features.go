@@ -0,0 +1,241 @@
+package lsp
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/checker"
+	"github.com/NishanthSpShetty/monkey/formatter"
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+// at returns the document and the source position a position request is about.
+func (s *Server) at(params json.RawMessage) (*document, token.Position, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, token.Position{}, err
+	}
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, token.Position{}, err
+	}
+	return d, d.position(p.Position), nil
+}
+
+func (s *Server) definition(params json.RawMessage) (any, error) {
+	d, pos, err := s.at(params)
+	if err != nil {
+		return nil, err
+	}
+	r := d.info.RefAt(pos)
+	if r == nil || r.Binding == nil {
+		return nil, nil
+	}
+	b := r.Binding
+	return Location{URI: d.uri, Range: d.lspRange(b.Name.Pos(), b.Name.End())}, nil
+}
+
+func (s *Server) hover(params json.RawMessage) (any, error) {
+	d, pos, err := s.at(params)
+	if err != nil {
+		return nil, err
+	}
+	r := d.info.RefAt(pos)
+	if r == nil {
+		return nil, nil
+	}
+
+	var text string
+	if r.Binding != nil {
+		text = describe(r.Binding)
+	} else if _, ok := runtime.GetBuiltin(r.Ident.Value); ok {
+		text = codeBlock("builtin " + r.Ident.Value)
+	} else {
+		return nil, nil
+	}
+	return Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: text},
+		Range:    d.lspRange(r.Ident.Pos(), r.Ident.End()),
+	}, nil
+}
+
+// describe renders the declaration of b and its doc comment as markdown.
+func describe(b *checker.Binding) string {
+	switch b.Kind {
+	case checker.Let:
+		decl := "let " + b.Name.Value + " = "
+		if fn, ok := b.Let.Value.(*ast.FunctionLiteral); ok {
+			decl += signature(fn)
+		} else if b.Let.Value != nil {
+			decl += formatter.Expression(b.Let.Value)
+		}
+		text := codeBlock(decl)
+		if b.Let.Doc != nil {
+			text += "\n" + b.Let.Doc.Text()
+		}
+		return text
+	case checker.Parameter:
+		decl := "parameter " + b.Name.Value
+		if b.Name == b.Func.Rest {
+			decl = "parameter ..." + b.Name.Value
+		}
+		for i, param := range b.Func.Parameters {
+			if def := b.Func.Default(i); param == b.Name && def != nil {
+				decl += " = " + formatter.Expression(def)
+			}
+		}
+		return codeBlock(decl)
+	}
+	return codeBlock(string(b.Kind) + " " + b.Name.Value)
+}
+
+// signature renders the parameters of fn, fn(a, b = 1, ...rest)
+func signature(fn *ast.FunctionLiteral) string {
+	params := []string{}
+	for i, param := range fn.Parameters {
+		if def := fn.Default(i); def != nil {
+			params = append(params, param.Value+" = "+formatter.Expression(def))
+		} else {
+			params = append(params, param.Value)
+		}
+	}
+	if fn.Rest != nil {
+		params = append(params, "..."+fn.Rest.Value)
+	}
+	return "fn(" + strings.Join(params, ", ") + ")"
+}
+
+func codeBlock(code string) string {
+	return "```monkey\n" + code + "\n```\n"
+}
+
+func (s *Server) completion(params json.RawMessage) (any, error) {
+	d, pos, err := s.at(params)
+	if err != nil {
+		return nil, err
+	}
+	prefix := d.wordBefore(pos)
+
+	items := []CompletionItem{}
+	seen := map[string]bool{}
+	add := func(item CompletionItem) {
+		if strings.HasPrefix(item.Label, prefix) && !seen[item.Label] {
+			seen[item.Label] = true
+			items = append(items, item)
+		}
+	}
+
+	bindings := d.info.ScopeAt(pos).Visible(pos)
+	sort.SliceStable(bindings, func(i, j int) bool { return bindings[i].Name.Value < bindings[j].Name.Value })
+	for _, b := range bindings {
+		item := CompletionItem{Label: b.Name.Value, Kind: CompletionVariable, Detail: string(b.Kind)}
+		if b.Let != nil {
+			if fn, ok := b.Let.Value.(*ast.FunctionLiteral); ok {
+				item.Kind = CompletionFunction
+				item.Detail = signature(fn)
+			}
+		}
+		add(item)
+	}
+	for _, name := range runtime.BuiltinNames() {
+		add(CompletionItem{Label: name, Kind: CompletionFunction, Detail: "builtin"})
+	}
+	for _, word := range token.Keywords() {
+		add(CompletionItem{Label: word, Kind: CompletionKeyword})
+	}
+	return items, nil
+}
+
+// wordBefore returns the part of the identifier before pos.
+func (d *document) wordBefore(pos token.Position) string {
+	if pos.Line > len(d.lines) {
+		return ""
+	}
+	line := []rune(d.lines[pos.Line-1])
+	end := min(pos.Column-1, len(line))
+	start := end
+	for start > 0 && (unicode.IsLetter(line[start-1]) || line[start-1] == '_') {
+		start--
+	}
+	return string(line[start:end])
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (any, error) {
+	var p DocumentSymbolParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return d.symbols(d.program.Statements), nil
+}
+
+// symbols returns the let bindings of list, a function holds the ones of its body.
+func (d *document) symbols(list []ast.Statement) []DocumentSymbol {
+	out := []DocumentSymbol{}
+	for _, st := range list {
+		switch st := st.(type) {
+		case *ast.LetStatement:
+			sym := DocumentSymbol{
+				Name:           st.Name.Value,
+				Kind:           SymbolVariable,
+				Range:          d.lspRange(st.Pos(), st.End()),
+				SelectionRange: d.lspRange(st.Name.Pos(), st.Name.End()),
+			}
+			if fn, ok := st.Value.(*ast.FunctionLiteral); ok {
+				sym.Kind = SymbolFunction
+				sym.Detail = signature(fn)
+				sym.Children = d.symbols(fn.Body.Statements)
+			}
+			out = append(out, sym)
+		// the bindings in blocks belong to the enclosing function
+		case *ast.WhileStatement:
+			out = append(out, d.symbols(st.Body.Statements)...)
+		case *ast.ForStatement:
+			out = append(out, d.symbols(st.Body.Statements)...)
+		case *ast.TryStatement:
+			for _, b := range []*ast.BlockStatement{st.Body, st.Catch, st.Finally} {
+				if b != nil {
+					out = append(out, d.symbols(b.Statements)...)
+				}
+			}
+		case *ast.ExpressionStatement:
+			if ie, ok := st.Expression.(*ast.IfExpression); ok {
+				out = append(out, d.symbols(ie.Consequence.Statements)...)
+				if ie.Alternative != nil {
+					out = append(out, d.symbols(ie.Alternative.Statements)...)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func (s *Server) formatting(params json.RawMessage) (any, error) {
+	var p DocumentFormattingParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	out, err := formatter.Source(d.uri, d.text)
+	if err != nil {
+		// the diagnostics show why
+		return nil, nil
+	}
+	if out == d.text {
+		return []TextEdit{}, nil
+	}
+	return []TextEdit{{
+		Range:   Range{End: d.end()},
+		NewText: out,
+	}}, nil
+}
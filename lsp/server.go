@@ -0,0 +1,201 @@
+// Package lsp is a Language Server Protocol server for monkey, speaking
+// JSON-RPC over a pair of streams such as stdin and stdout. It publishes the
+// syntax errors of the open documents, or what the checker finds in them, and
+// answers go to definition, hover, completion, document symbol and formatting
+// requests.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ErrNoShutdown is returned by Serve when the client asks it to exit
+// without shutting it down first.
+var ErrNoShutdown = errors.New("exit without shutdown")
+
+// Server is the state of a language server session.
+type Server struct {
+	out         io.Writer
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+// requests are the methods answered with a response
+var requests map[string]func(s *Server, params json.RawMessage) (any, error)
+
+// notifications are the methods handled without a response
+var notifications map[string]func(s *Server, params json.RawMessage) error
+
+func init() {
+	requests = map[string]func(s *Server, params json.RawMessage) (any, error){
+		"initialize":                  (*Server).initialize,
+		"shutdown":                    (*Server).shutdownRequest,
+		"textDocument/definition":     (*Server).definition,
+		"textDocument/hover":          (*Server).hover,
+		"textDocument/completion":     (*Server).completion,
+		"textDocument/documentSymbol": (*Server).documentSymbol,
+		"textDocument/formatting":     (*Server).formatting,
+	}
+	notifications = map[string]func(s *Server, params json.RawMessage) error{
+		"initialized":            func(*Server, json.RawMessage) error { return nil },
+		"textDocument/didOpen":   (*Server).didOpen,
+		"textDocument/didChange": (*Server).didChange,
+		"textDocument/didClose":  (*Server).didClose,
+	}
+}
+
+// Serve runs a server reading messages from in and writing to out until
+// the client asks it to exit or in ends.
+func Serve(in io.Reader, out io.Writer) error {
+	s := &Server{out: out, docs: map[string]*document{}}
+	r := bufio.NewReader(in)
+	for {
+		msg, err := readMessage(r)
+		if err == io.EOF {
+			return nil
+		}
+		if rerr, ok := err.(*ResponseError); ok {
+			// the body is not JSON, there is no id to answer to
+			if err := writeMessage(out, errorResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: rerr}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches msg, it only fails when writing to the client does.
+func (s *Server) handle(msg *message) error {
+	if msg.ID == nil {
+		// notifications cannot be answered, errors are dropped
+		if fn, ok := notifications[msg.Method]; ok && s.initialized && !s.shutdown {
+			fn(s, msg.Params)
+		}
+		return nil
+	}
+
+	fn, ok := requests[msg.Method]
+	var result any
+	var err error
+	switch {
+	case !ok:
+		err = &ResponseError{Code: MethodNotFound, Message: "method not found: " + msg.Method}
+	case !s.initialized && msg.Method != "initialize":
+		err = &ResponseError{Code: ServerNotInitialized, Message: "server not initialized"}
+	case s.shutdown:
+		err = &ResponseError{Code: InvalidRequest, Message: "server is shut down"}
+	default:
+		result, err = fn(s, msg.Params)
+	}
+
+	if err != nil {
+		rerr, ok := err.(*ResponseError)
+		if !ok {
+			rerr = &ResponseError{Code: InternalError, Message: err.Error()}
+		}
+		return writeMessage(s.out, errorResponse{JSONRPC: "2.0", ID: msg.ID, Error: rerr})
+	}
+	return writeMessage(s.out, response{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+func (s *Server) notify(method string, params any) error {
+	return writeMessage(s.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// decode unmarshals the params of a request into v.
+func decode(params json.RawMessage, v any) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &ResponseError{Code: InvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// document returns the open document uri.
+func (s *Server) document(uri string) (*document, error) {
+	d, ok := s.docs[uri]
+	if !ok {
+		return nil, &ResponseError{Code: InvalidParams, Message: "document not open: " + uri}
+	}
+	return d, nil
+}
+
+func (s *Server) initialize(params json.RawMessage) (any, error) {
+	if s.initialized {
+		return nil, &ResponseError{Code: InvalidRequest, Message: "server already initialized"}
+	}
+	s.initialized = true
+
+	result := InitializeResult{ServerInfo: ServerInfo{Name: "monkey"}}
+	result.Capabilities.TextDocumentSync = TextDocumentSyncOptions{OpenClose: true, Change: SyncFull}
+	result.Capabilities.DefinitionProvider = true
+	result.Capabilities.HoverProvider = true
+	result.Capabilities.DocumentSymbolProvider = true
+	result.Capabilities.DocumentFormattingProvider = true
+	return result, nil
+}
+
+func (s *Server) shutdownRequest(json.RawMessage) (any, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) error {
+	var p DidOpenTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return err
+	}
+	return s.update(newDocument(p.TextDocument.URI, p.TextDocument.Version, p.TextDocument.Text))
+}
+
+func (s *Server) didChange(params json.RawMessage) error {
+	var p DidChangeTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return err
+	}
+	if len(p.ContentChanges) == 0 {
+		return nil
+	}
+	// with full syncs the last change holds the whole text
+	text := p.ContentChanges[len(p.ContentChanges)-1].Text
+	return s.update(newDocument(p.TextDocument.URI, p.TextDocument.Version, text))
+}
+
+func (s *Server) didClose(params json.RawMessage) error {
+	var p DidCloseTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return err
+	}
+	delete(s.docs, p.TextDocument.URI)
+	// clear the diagnostics of the document
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         p.TextDocument.URI,
+		Diagnostics: []Diagnostic{},
+	})
+}
+
+// update replaces the document d and publishes its diagnostics.
+func (s *Server) update(d *document) error {
+	s.docs[d.uri] = d
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         d.uri,
+		Version:     d.version,
+		Diagnostics: d.diagnostics(),
+	})
+}
@@ -0,0 +1,393 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// client is a scripted LSP client talking to a server over pipes.
+type client struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	out    *bufio.Reader
+	id     int
+	done   chan error
+	notes  []*message
+	closed bool
+}
+
+func newClient(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &client{t: t, in: inW, out: bufio.NewReader(outR), done: make(chan error, 1)}
+	go func() {
+		err := Serve(inR, outW)
+		outW.Close()
+		c.done <- err
+	}()
+	t.Cleanup(func() {
+		if !c.closed {
+			inW.Close()
+			<-c.done
+		}
+	})
+	return c
+}
+
+func (c *client) send(v any) {
+	if err := writeMessage(c.in, v); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// call sends a request and returns its response, the notifications read
+// on the way are kept.
+func (c *client) call(method string, params any) *message {
+	c.id++
+	id, _ := json.Marshal(c.id)
+	p, _ := json.Marshal(params)
+	c.send(message{JSONRPC: "2.0", ID: id, Method: method, Params: p})
+	for {
+		msg := c.read()
+		if msg.ID == nil {
+			c.notes = append(c.notes, msg)
+			continue
+		}
+		assert.Equal(c.t, string(id), string(msg.ID))
+		return msg
+	}
+}
+
+// result calls method and decodes its result into v.
+func (c *client) result(method string, params any, v any) {
+	msg := c.call(method, params)
+	if !assert.Nil(c.t, msg.Error, method) {
+		return
+	}
+	assert.NoError(c.t, json.Unmarshal(msg.Result, v))
+}
+
+func (c *client) notify(method string, params any) {
+	p, _ := json.Marshal(params)
+	c.send(message{JSONRPC: "2.0", Method: method, Params: p})
+}
+
+func (c *client) read() *message {
+	msg, err := readMessage(c.out)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+// diagnostics reads the next notification, which must publish diagnostics.
+func (c *client) diagnostics() PublishDiagnosticsParams {
+	var msg *message
+	if len(c.notes) > 0 {
+		msg, c.notes = c.notes[0], c.notes[1:]
+	} else {
+		msg = c.read()
+	}
+	assert.Equal(c.t, "textDocument/publishDiagnostics", msg.Method)
+	var p PublishDiagnosticsParams
+	assert.NoError(c.t, json.Unmarshal(msg.Params, &p))
+	return p
+}
+
+func (c *client) initialize() {
+	var result InitializeResult
+	c.result("initialize", map[string]any{"capabilities": map[string]any{}}, &result)
+	c.notify("initialized", map[string]any{})
+}
+
+func (c *client) open(uri, text string) PublishDiagnosticsParams {
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "monkey", Version: 1, Text: text},
+	})
+	return c.diagnostics()
+}
+
+func at(uri string, line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: line, Character: character},
+	}
+}
+
+func rng(line, start, end int) Range {
+	return Range{Start: Position{line, start}, End: Position{line, end}}
+}
+
+const uri = "file:///main.mk"
+
+const source = `// add sums a and b
+let add = fn(a, b = 1) {
+	let sum = a + b;
+	sum
+};
+let s = "😀"; let n = add(s, 2);
+for (x in [n]) { puts(x) }
+let outer = fn(...xs) { let add = 0; fn() { add + len(xs) } };
+`
+
+func TestLifecycle(t *testing.T) {
+	c := newClient(t)
+
+	// requests before initialize are refused
+	msg := c.call("textDocument/hover", at(uri, 0, 0))
+	if assert.NotNil(t, msg.Error) {
+		assert.Equal(t, ServerNotInitialized, msg.Error.Code)
+	}
+
+	var result InitializeResult
+	c.result("initialize", map[string]any{}, &result)
+	assert.Equal(t, SyncFull, result.Capabilities.TextDocumentSync.Change)
+	assert.True(t, result.Capabilities.DefinitionProvider)
+	assert.True(t, result.Capabilities.HoverProvider)
+	assert.True(t, result.Capabilities.DocumentSymbolProvider)
+	assert.True(t, result.Capabilities.DocumentFormattingProvider)
+
+	msg = c.call("nosuch/method", nil)
+	if assert.NotNil(t, msg.Error) {
+		assert.Equal(t, MethodNotFound, msg.Error.Code)
+	}
+	msg = c.call("textDocument/hover", map[string]any{"position": "nope"})
+	if assert.NotNil(t, msg.Error) {
+		assert.Equal(t, InvalidParams, msg.Error.Code)
+	}
+
+	msg = c.call("shutdown", nil)
+	assert.Nil(t, msg.Error)
+	assert.Equal(t, "null", string(msg.Result))
+	c.notify("exit", nil)
+	c.closed = true
+	assert.NoError(t, <-c.done)
+
+	// exiting without a shutdown is an error
+	c = newClient(t)
+	c.initialize()
+	c.notify("exit", nil)
+	c.closed = true
+	assert.Equal(t, ErrNoShutdown, <-c.done)
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := newClient(t)
+	c.initialize()
+
+	diags := c.open(uri, "let a = 1;\nlet b = (a + 1;\n")
+	assert.Equal(t, uri, diags.URI)
+	if assert.Len(t, diags.Diagnostics, 1) {
+		d := diags.Diagnostics[0]
+		assert.Equal(t, rng(1, 14, 15), d.Range)
+		assert.Equal(t, SeverityError, d.Severity)
+		assert.Equal(t, "expected next token to be ), got ; instead", d.Message)
+		assert.Equal(t, "UnexpectedToken", d.Code)
+	}
+
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "let a = 1;\nlet b = (a + 1);\nputs(b);\n"}},
+	})
+	diags = c.diagnostics()
+	assert.Equal(t, 2, diags.Version)
+	assert.Empty(t, diags.Diagnostics)
+
+	// without syntax errors the checker reports the rest
+	diags = c.open(uri, "let a = 1;\nputs(b, args);\n")
+	if assert.Len(t, diags.Diagnostics, 2) {
+		d := diags.Diagnostics[0]
+		assert.Equal(t, rng(0, 4, 5), d.Range)
+		assert.Equal(t, SeverityWarning, d.Severity)
+		assert.Equal(t, "a is declared but never used", d.Message)
+		assert.Equal(t, "Unused", d.Code)
+
+		d = diags.Diagnostics[1]
+		assert.Equal(t, rng(1, 5, 6), d.Range)
+		assert.Equal(t, SeverityError, d.Severity)
+		assert.Equal(t, "identifier not found: b", d.Message)
+		assert.Equal(t, "UndefinedName", d.Code)
+	}
+
+	// closing a document clears its diagnostics
+	c.open(uri, "let = ")
+	c.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+	diags = c.diagnostics()
+	assert.Empty(t, diags.Diagnostics)
+	msg := c.call("textDocument/hover", at(uri, 0, 0))
+	assert.NotNil(t, msg.Error)
+}
+
+func TestDefinition(t *testing.T) {
+	c := newClient(t)
+	c.initialize()
+	c.open(uri, source)
+
+	tests := []struct {
+		line, character int
+		expected        *Range
+	}{
+		// a in a + b is the parameter
+		{2, 12, &Range{Start: Position{1, 13}, End: Position{1, 14}}},
+		// sum is the let in the body
+		{3, 2, &Range{Start: Position{2, 5}, End: Position{2, 8}}},
+		// s after an emoji counted as two UTF-16 units
+		{5, 26, &Range{Start: Position{5, 4}, End: Position{5, 5}}},
+		{5, 22, &Range{Start: Position{1, 4}, End: Position{1, 7}}},
+		// the loop variable
+		{6, 22, &Range{Start: Position{6, 5}, End: Position{6, 6}}},
+		// add in the inner function is the nearer let, xs the rest parameter
+		{7, 44, &Range{Start: Position{7, 28}, End: Position{7, 31}}},
+		{7, 55, &Range{Start: Position{7, 18}, End: Position{7, 20}}},
+		// declarations point to themselves
+		{1, 5, &Range{Start: Position{1, 4}, End: Position{1, 7}}},
+		// builtins and keywords have no definition
+		{6, 17, nil},
+		{0, 3, nil},
+	}
+
+	for _, tt := range tests {
+		var loc *Location
+		c.result("textDocument/definition", at(uri, tt.line, tt.character), &loc)
+		if tt.expected == nil {
+			assert.Nil(t, loc, "%d:%d", tt.line, tt.character)
+			continue
+		}
+		if assert.NotNil(t, loc, "%d:%d", tt.line, tt.character) {
+			assert.Equal(t, uri, loc.URI)
+			assert.Equal(t, *tt.expected, loc.Range, "%d:%d", tt.line, tt.character)
+		}
+	}
+}
+
+func TestHover(t *testing.T) {
+	c := newClient(t)
+	c.initialize()
+	c.open(uri, source)
+
+	tests := []struct {
+		line, character int
+		expected        string
+	}{
+		{5, 23, "```monkey\nlet add = fn(a, b = 1)\n```\n\nadd sums a and b"},
+		{3, 1, "```monkey\nlet sum = a + b\n```\n"},
+		{2, 16, "```monkey\nparameter b = 1\n```\n"},
+		{7, 55, "```monkey\nparameter ...xs\n```\n"},
+		{6, 22, "```monkey\nloop variable x\n```\n"},
+		{6, 18, "```monkey\nbuiltin puts\n```\n"},
+	}
+	for _, tt := range tests {
+		var hover *Hover
+		c.result("textDocument/hover", at(uri, tt.line, tt.character), &hover)
+		if assert.NotNil(t, hover, "%d:%d", tt.line, tt.character) {
+			assert.Equal(t, "markdown", hover.Contents.Kind)
+			assert.Equal(t, tt.expected, hover.Contents.Value, "%d:%d", tt.line, tt.character)
+		}
+	}
+
+	var hover *Hover
+	c.result("textDocument/hover", at(uri, 0, 5), &hover)
+	assert.Nil(t, hover, "comments have no hover")
+}
+
+func TestCompletion(t *testing.T) {
+	c := newClient(t)
+	c.initialize()
+	c.open(uri, "let first_name = 1;\nlet f = fn(fizz) { fi };\nf\n")
+
+	labels := func(line, character int) []string {
+		var items []CompletionItem
+		c.result("textDocument/completion", at(uri, line, character), &items)
+		out := []string{}
+		for _, item := range items {
+			out = append(out, item.Label)
+		}
+		return out
+	}
+
+	// bindings in scope first, then builtins and keywords
+	assert.Equal(t, []string{"first_name", "fizz", "filter", "find", "first", "finally"}, labels(1, 21))
+	// fizz is not in scope outside the function
+	assert.Equal(t, []string{"f", "first_name", "filter", "find", "first", "flat_map", "float", "false", "finally", "fn", "for"}, labels(2, 1))
+
+	var items []CompletionItem
+	c.result("textDocument/completion", at(uri, 2, 1), &items)
+	assert.Equal(t, CompletionItem{Label: "f", Kind: CompletionFunction, Detail: "fn(fizz)"}, items[0])
+	assert.Equal(t, CompletionItem{Label: "first_name", Kind: CompletionVariable, Detail: "let"}, items[1])
+}
+
+func TestDocumentSymbol(t *testing.T) {
+	c := newClient(t)
+	c.initialize()
+	c.open(uri, source)
+
+	var symbols []DocumentSymbol
+	c.result("textDocument/documentSymbol", DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: uri}}, &symbols)
+
+	names := func(symbols []DocumentSymbol) []string {
+		out := []string{}
+		for _, s := range symbols {
+			out = append(out, s.Name)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"add", "s", "n", "outer"}, names(symbols))
+
+	add := symbols[0]
+	assert.Equal(t, SymbolFunction, add.Kind)
+	assert.Equal(t, "fn(a, b = 1)", add.Detail)
+	assert.Equal(t, Range{Start: Position{1, 0}, End: Position{4, 1}}, add.Range)
+	assert.Equal(t, rng(1, 4, 7), add.SelectionRange)
+	assert.Equal(t, []string{"sum"}, names(add.Children))
+	assert.Equal(t, SymbolVariable, symbols[1].Kind)
+	assert.Equal(t, []string{"add"}, names(symbols[3].Children))
+}
+
+func TestFormatting(t *testing.T) {
+	c := newClient(t)
+	c.initialize()
+	params := DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: uri}}
+
+	c.open(uri, "let a=1\nputs( a )")
+	var edits []TextEdit
+	c.result("textDocument/formatting", params, &edits)
+	assert.Equal(t, []TextEdit{{
+		Range:   Range{End: Position{1, 9}},
+		NewText: "let a = 1;\nputs(a);\n",
+	}}, edits)
+
+	c.open(uri, "let a = 1;\n")
+	c.result("textDocument/formatting", params, &edits)
+	assert.Empty(t, edits)
+
+	// source which does not parse is left alone
+	c.open(uri, "let = 1")
+	msg := c.call("textDocument/formatting", params)
+	assert.Nil(t, msg.Error)
+	assert.Equal(t, "null", string(msg.Result))
+}
+
+func TestFraming(t *testing.T) {
+	out := &strings.Builder{}
+	input := "Content-Length: 5\r\n\r\n{nope" +
+		"Content-Length: 58\r\n\r\n" + `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+	assert.NoError(t, Serve(strings.NewReader(input), out))
+
+	r := bufio.NewReader(strings.NewReader(out.String()))
+	msg, err := readMessage(r)
+	if assert.NoError(t, err) && assert.NotNil(t, msg.Error) {
+		assert.Equal(t, ParseError, msg.Error.Code)
+	}
+	msg, err = readMessage(r)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1", string(msg.ID))
+		assert.Nil(t, msg.Error)
+	}
+
+	assert.Error(t, Serve(strings.NewReader("Content-Type: x\r\n\r\n"), out))
+}
@@ -2,19 +2,11 @@
 package main
 
 import (
-	"fmt"
 	"os"
-	"os/user"
 
-	"github.com/NishanthSpShetty/monkey/repl"
+	"github.com/NishanthSpShetty/monkey/cli"
 )
 
 func main() {
-	user, err := user.Current()
-	if err != nil {
-		panic(err)
-	}
-
-	fmt.Printf("Hello %s! This is monkey lang\n", user.Username)
-	repl.Start(os.Stdin, os.Stdout)
+	os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
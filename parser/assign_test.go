@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssignExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		operator string
+		target   string
+		expected string
+	}{
+		{"x = 5", "=", "x", "(x = 5)"},
+		{"x += y * 2", "+=", "x", "(x += (y * 2))"},
+		{"x -= 1", "-=", "x", "(x -= 1)"},
+		{"x *= -1", "*=", "x", "(x *= (-1))"},
+		{"x /= f(2)", "/=", "x", "(x /= f(2,))"},
+		{"a[i + 1] = b", "=", "(a[(i + 1)])", "((a[(i + 1)]) = b)"},
+		{`h["k"] += 1`, "+=", "(h[k])", "((h[k]) += 1)"},
+		{"a = b = c", "=", "a", "(a = (b = c))"},
+		{"a = b == c", "=", "a", "(a = (b == c))"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		assert.Equal(t, 1, len(program.Statements), "must return one statement")
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		assert.True(t, ok, "statement must be ExpressionStatement")
+		exp, ok := stmt.Expression.(*ast.AssignExpression)
+		assert.Truef(t, ok, "expression must be AssignExpression, got %T", stmt.Expression)
+		if !ok {
+			continue
+		}
+		assert.Equal(t, tt.operator, exp.Operator)
+		assert.Equal(t, tt.target, exp.Target.String())
+		assert.Equal(t, tt.expected, exp.String())
+	}
+}
+
+func TestInvalidAssignTarget(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   string
+		msg   string
+	}{
+		{"1 = 2", "1:1", "cannot assign to 1"},
+		{"f() = 2", "1:1", "cannot assign to f()"},
+		{"a + b += 1", "1:1", "cannot assign to (a + b)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		assert.Equalf(t, 1, len(errors), "errors for %q", tt.input)
+		if len(errors) == 0 {
+			continue
+		}
+		assert.Equal(t, InvalidAssignment, errors[0].Kind)
+		assert.Equal(t, tt.pos, errors[0].Pos.String())
+		assert.Equal(t, tt.msg, errors[0].Message)
+	}
+}
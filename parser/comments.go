@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+// comments only reach the parser when the lexer runs in lexer.ScanComments mode,
+// they are collected into groups as they are read and attached to statements
+// once a statement is parsed
+
+// addComment adds the comment tok to the last group if nothing but whitespace
+// without blank lines separates them, otherwise it starts a new group. A
+// comment on the line of the token before it starts a group which only takes
+// the comments on that line, so trailing comments are not merged with the
+// comments below them.
+func (p *Parser) addComment(tok token.Token) {
+	c := &ast.Comment{Token: tok}
+	// curToken is the last token read before the comment
+	trailing := p.curToken.End.IsValid() && p.curToken.End.Line == tok.Pos.Line
+
+	if n := len(p.comments); n > 0 {
+		last := p.comments[n-1]
+		adjacent := last.End().Offset >= p.curToken.End.Offset
+		sameLine := last.End().Line == tok.Pos.Line
+		if adjacent && (sameLine || (!p.trailingGroup && tok.Pos.Line-last.End().Line == 1)) {
+			last.List = append(last.List, c)
+			return
+		}
+	}
+	p.comments = append(p.comments, &ast.CommentGroup{List: []*ast.Comment{c}})
+	p.trailingGroup = trailing
+}
+
+// leadComment returns the comment group ending on the line above, or the line
+// of, the statement starting at curToken, nil if there is none.
+func (p *Parser) leadComment() *ast.CommentGroup {
+	start := p.curToken.Pos
+	for i := len(p.comments) - 1; i >= 0; i-- {
+		g := p.comments[i]
+		if g.Pos().Offset >= start.Offset {
+			// read ahead of the statement
+			continue
+		}
+		if g.Pos().Offset < p.prevEnd.Offset || g.End().Line < start.Line-1 {
+			return nil
+		}
+		if p.prevEnd.IsValid() && g.Pos().Line == p.prevEnd.Line {
+			// trailing comment of the previous token
+			return nil
+		}
+		return g
+	}
+	return nil
+}
+
+// lineComment returns the comment group starting on the line the statement
+// ending at curToken ends, nil if there is none.
+func (p *Parser) lineComment() *ast.CommentGroup {
+	end := p.curToken.End
+	for i := len(p.comments) - 1; i >= 0; i-- {
+		g := p.comments[i]
+		if g.Pos().Offset < end.Offset {
+			return nil
+		}
+		if g.Pos().Line == end.Line && (i == 0 || p.comments[i-1].Pos().Offset < end.Offset) {
+			return g
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseWithComments(t *testing.T, input string) *ast.Program {
+	l := lexer.New(input)
+	l.SetMode(lexer.ScanComments)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+	return program
+}
+
+func TestCommentsAreSkipped(t *testing.T) {
+	input := `
+	// the answer
+	let x = 40 /* plus */ + 2; // trailing
+	x`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	assert.Len(t, program.Statements, 2)
+	assert.Equal(t, "let x = (40 + 2)", program.Statements[0].String())
+	assert.Empty(t, program.Comments, "comments are only kept on request")
+}
+
+func TestCommentAttachment(t *testing.T) {
+	input := `// Package doc, not attached.
+
+// add returns the sum
+// of a and b.
+let add = fn(a, b) {
+	// inside
+	a + b // sum
+};
+
+return add(1, 2); /* done */ /* twice */
+/* free */
+
+// last
+add`
+
+	program := parseWithComments(t, input)
+	assert.Len(t, program.Statements, 3)
+
+	let := program.Statements[0].(*ast.LetStatement)
+	assert.NotNil(t, let.Doc)
+	assert.Equal(t, "add returns the sum\nof a and b.", let.Doc.Text())
+	assert.Nil(t, let.Comment)
+
+	body := let.Value.(*ast.FunctionLiteral).Body.Statements[0].(*ast.ExpressionStatement)
+	assert.Equal(t, "// inside", body.Doc.String())
+	assert.Equal(t, "// sum", body.Comment.String())
+
+	ret := program.Statements[1].(*ast.ReturnStatement)
+	assert.Nil(t, ret.Doc)
+	assert.Equal(t, "/* done */ /* twice */", ret.Comment.String())
+
+	last := program.Statements[2].(*ast.ExpressionStatement)
+	assert.Equal(t, "last", last.Doc.Text())
+
+	texts := []string{}
+	for _, g := range program.Comments {
+		texts = append(texts, g.String())
+	}
+	assert.Equal(t, []string{
+		"// Package doc, not attached.",
+		"// add returns the sum\n// of a and b.",
+		"// inside",
+		"// sum",
+		"/* done */ /* twice */",
+		"/* free */",
+		"// last",
+	}, texts)
+}
+
+func TestCommentsInsideExpressions(t *testing.T) {
+	input := `let h = {
+	"a": 1, // first
+	/* second */ "b": 2
+}`
+
+	program := parseWithComments(t, input)
+	assert.Len(t, program.Statements, 1)
+	assert.Len(t, program.Comments, 2)
+	assert.Equal(t, "2:10", program.Comments[0].Pos().String())
+	assert.Equal(t, "3:2", program.Comments[1].Pos().String())
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	l := lexer.New("let x = 1; /* open")
+	p := New(l)
+	p.ParseProgram()
+
+	assert.Len(t, p.Errors(), 1)
+	assert.Equal(t, "1:12", p.Errors()[0].Pos.String())
+}
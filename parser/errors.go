@@ -0,0 +1,142 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+type ErrorKind string
+
+const (
+	// UnexpectedToken is reported when a specific token was expected
+	UnexpectedToken ErrorKind = "UnexpectedToken"
+	// MissingExpression is reported when a token cannot start an expression
+	MissingExpression ErrorKind = "MissingExpression"
+	// InvalidLiteral is reported when a literal cannot be converted to its value
+	InvalidLiteral ErrorKind = "InvalidLiteral"
+	// InvalidToken is reported for source the lexer could not turn into a token,
+	// like an unterminated string
+	InvalidToken ErrorKind = "InvalidToken"
+	// InvalidAssignment is reported when the left side of an assignment is
+	// neither a variable nor an index expression
+	InvalidAssignment ErrorKind = "InvalidAssignment"
+	// InvalidParameter is reported for a parameter without a default after
+	// one with a default, or a rest parameter which is not the last
+	InvalidParameter ErrorKind = "InvalidParameter"
+	// NestingTooDeep is reported for expressions or blocks nested deeper
+	// than maxNesting, the rest of the source is not parsed
+	NestingTooDeep ErrorKind = "NestingTooDeep"
+)
+
+// maxNesting bounds how deep expressions and blocks nest, the parser and the
+// code walking the tree recurse for every level.
+const maxNesting = 1000
+
+// ParseError is a syntax error found by the parser.
+type ParseError struct {
+	Kind    ErrorKind
+	Message string
+	Pos     token.Position
+	// Expected is the token type the parser was looking for, empty when
+	// any of several tokens would have done
+	Expected token.TokenType
+	// Found is the token the parser got instead
+	Found token.Token
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+// illegalTokenError fails on the ILLEGAL token tok, whose literal describes the problem.
+func (p *Parser) illegalTokenError(tok token.Token) {
+	p.fail(&ParseError{
+		Kind:    InvalidToken,
+		Message: tok.Literal,
+		Pos:     tok.Pos,
+		Found:   tok,
+	})
+}
+
+// Render formats the error with its location in f and the offending source line.
+func (e *ParseError) Render(f *token.File) string {
+	return f.Format(e.Pos, e.Message)
+}
+
+// bailout is raised by the parser after recording an error, it unwinds to
+// the statement being parsed which is then dropped.
+type bailout struct{}
+
+func (p *Parser) fail(err *ParseError) {
+	p.errors = append(p.errors, err)
+	panic(bailout{})
+}
+
+// abort is raised by the parser after an error it cannot recover from, it
+// unwinds to ParseProgram.
+type abort struct{}
+
+// nest counts a level of nesting entered at curToken, the caller undoes it
+// when it returns. Past maxNesting the parse is aborted, as recovering from
+// the error would nest as deep again.
+func (p *Parser) nest() {
+	p.nesting++
+	if p.nesting <= maxNesting {
+		return
+	}
+	p.errors = append(p.errors, &ParseError{
+		Kind:    NestingTooDeep,
+		Message: fmt.Sprintf("nesting is too deep, more than %d levels", maxNesting),
+		Pos:     p.curToken.Pos,
+		Found:   p.curToken,
+	})
+	panic(abort{})
+}
+
+// parseStatementOrRecover parses a statement, on a syntax error it skips
+// ahead to the next statement boundary and returns nil.
+func (p *Parser) parseStatementOrRecover() (stmt ast.Statement) {
+	start := p.braces
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(bailout); !ok {
+				panic(r)
+			}
+			stmt = nil
+			p.synchronize(start)
+		}
+	}()
+	return p.parseStatement()
+}
+
+// synchronize skips tokens until curToken ends a statement or peekToken starts
+// one, the caller moves on to the next token as it does after any statement.
+// Blocks opened while skipping are skipped as a whole, a } closing a block
+// opened before the statement at start is left as curToken for the caller.
+func (p *Parser) synchronize(start int) {
+	for !p.curTokenIs(token.EOF) {
+		depth := p.braces - start
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth < 0 {
+				return
+			}
+		case token.SEMICOLON:
+			if depth == 0 {
+				return
+			}
+		}
+
+		if depth == 0 {
+			switch p.peekToken.Type {
+			case token.LET, token.RETURN, token.WHILE, token.FOR, token.RBRACE, token.EOF:
+				return
+			}
+		}
+		p.nextToken()
+	}
+}
@@ -18,15 +18,24 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	if t == token.ILLEGAL {
+		p.illegalTokenError(p.curToken)
+	}
+	p.fail(&ParseError{
+		Kind:    MissingExpression,
+		Message: fmt.Sprintf("expected an expression, got %s instead", t),
+		Pos:     p.curToken.Pos,
+		Found:   p.curToken,
+	})
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	p.nest()
+	defer func() { p.nesting-- }()
+
 	prefixParser := p.prefixParserFns[p.curToken.Type]
 	if prefixParser == nil {
 		p.noPrefixParseFnError(p.curToken.Type)
-		return nil
 	}
 
 	leftExpr := prefixParser()
@@ -68,14 +77,35 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	exp := &ast.AssignExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Target:   target,
+	}
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	default:
+		p.fail(&ParseError{
+			Kind:    InvalidAssignment,
+			Message: fmt.Sprintf("cannot assign to %s", target),
+			Pos:     target.Pos(),
+			Found:   p.curToken,
+		})
+	}
+	p.nextToken()
+
+	// assignments are right associative, a = b = c assigns c to b then to a
+	exp.Value = p.parseExpression(ASSIGN - 1)
+	return exp
+}
+
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST)
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
-	}
+	p.expectPeek(token.RPAREN)
 	return exp
 }
 
@@ -87,60 +117,86 @@ func (p *Parser) parseBoolean() ast.Expression {
 }
 
 func (p *Parser) parseFunctionLiteral() ast.Expression {
-	fn := &ast.FunctionLiteral{Token: p.curToken}
+	fn := &ast.FunctionLiteral{Token: p.curToken, File: p.l.File()}
 
 	// we are at fn, move to (
-	if !p.expectPeek(token.LPAREN) {
-		return nil
-	}
+	p.expectPeek(token.LPAREN)
 
-	fn.Parameters = p.parseFunctionParameters()
+	p.parseFunctionParameters(fn)
 
 	// we are at ), move to {
-	if !p.expectPeek(token.LBRACE) {
-		return nil
-	}
+	p.expectPeek(token.LBRACE)
 
+	// loops around the function do not extend into its body
+	depth := p.loopDepth
+	p.loopDepth = 0
+	defer func() { p.loopDepth = depth }()
 	fn.Body = p.parseBlockStatement()
 
 	return fn
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	ids := []*ast.Identifier{}
+// parseFunctionParameters parses (a, b = 10, ...rest) into fn. Parameters
+// with a default come after the ones without and the rest parameter is last.
+func (p *Parser) parseFunctionParameters(fn *ast.FunctionLiteral) {
+	fn.Parameters = []*ast.Identifier{}
 
 	// if no args present
 	if p.peekTokenIs(token.RPAREN) {
 		// we are in (, move to )
 		p.nextToken()
-		return ids
+		return
 	}
-	// (a,b,c)
-	// we are in (, move to first arg token
-	p.nextToken()
-	ids = append(ids, &ast.Identifier{
-		Token: p.curToken,
-		Value: p.curToken.Literal,
-	})
 
-	// loop till we have comms in next token
-	// a,b,c)
-	for p.peekTokenIs(token.COMMA) {
-		// skip arg ie. a on first iteration
-		p.nextToken()
-		// skip ,
+	// we are in ( or at the previous parameter
+	for {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			p.expectPeek(token.IDENT)
+			fn.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if p.peekTokenIs(token.COMMA) {
+				p.fail(&ParseError{
+					Kind:    InvalidParameter,
+					Message: fmt.Sprintf("rest parameter ...%s must be the last", fn.Rest.Value),
+					Pos:     fn.Rest.Pos(),
+					Found:   p.peekToken,
+				})
+			}
+			break
+		}
+
+		p.expectPeek(token.IDENT)
+		param := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		var def ast.Expression
+		if p.peekTokenIs(token.ASSIGN) {
+			// skip the parameter and =
+			p.nextToken()
+			p.nextToken()
+			def = p.parseExpression(LOWEST)
+		} else if fn.Defaults != nil {
+			p.fail(&ParseError{
+				Kind:    InvalidParameter,
+				Message: fmt.Sprintf("parameter %s without a default follows one with a default", param.Value),
+				Pos:     param.Pos(),
+				Found:   param.Token,
+			})
+		}
+
+		fn.Parameters = append(fn.Parameters, param)
+		if def != nil && fn.Defaults == nil {
+			fn.Defaults = make([]ast.Expression, len(fn.Parameters)-1)
+		}
+		if fn.Defaults != nil {
+			fn.Defaults = append(fn.Defaults, def)
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
 		p.nextToken()
-		ids = append(ids, &ast.Identifier{
-			Token: p.curToken,
-			Value: p.curToken.Literal,
-		})
 	}
 	// we should see )
-	if !p.expectPeek(token.RPAREN) {
-		return nil
-	}
-
-	return ids
+	p.expectPeek(token.RPAREN)
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
@@ -148,11 +204,30 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 		Token:    p.curToken,
 		Function: function,
 	}
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Arguments = p.parseList(token.RPAREN, p.parseArgument)
+	exp.Rparen = p.curToken
 	return exp
 }
 
+// parseArgument parses an argument of a call, which may be spread, f(...xs).
+func (p *Parser) parseArgument() ast.Expression {
+	if !p.curTokenIs(token.ELLIPSIS) {
+		return p.parseExpression(LOWEST)
+	}
+	spread := &ast.SpreadExpression{Token: p.curToken}
+	p.nextToken()
+	spread.Value = p.parseExpression(LOWEST)
+	return spread
+}
+
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	return p.parseList(end, func() ast.Expression {
+		return p.parseExpression(LOWEST)
+	})
+}
+
+// parseList parses the comma separated elements up to end, each with parse.
+func (p *Parser) parseList(end token.TokenType, parse func() ast.Expression) []ast.Expression {
 	args := []ast.Expression{}
 
 	// add ()
@@ -164,7 +239,7 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	// move to (
 	// add (a, x+y, ...)
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	args = append(args, parse())
 
 	// A,B
 	for p.peekTokenIs(token.COMMA) {
@@ -172,12 +247,10 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 		// move to B
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		args = append(args, parse())
 	}
 
 	// we should see )
-	if !p.expectPeek(end) {
-		return nil
-	}
+	p.expectPeek(end)
 	return args
 }
@@ -34,6 +34,31 @@ func TestLiteralExpression(t *testing.T) {
 	assert.Equal(t, "50", literal.TokenLiteral())
 }
 
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14", 3.14},
+		{"1e3", 1000},
+		{"2.5e-2", 0.025},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		assert.True(t, ok, "program statement must be ExpressionStatement")
+
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		assert.True(t, ok, "expression must be FloatLiteral")
+		assert.Equal(t, tt.expected, literal.Value)
+		assert.Equal(t, tt.input, literal.String())
+	}
+}
+
 func TestParsingPrefixExpression(t *testing.T) {
 	prefixTests := []struct {
 		input        string
@@ -54,3 +54,71 @@ func TestCallExpression(t *testing.T) {
 	testIdentifierExpression(t, exp.Function, "add")
 	assert.Equal(t, 2, len(exp.Arguments), "must have arguments")
 }
+
+func TestFunctionParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		required int
+	}{
+		{"fn() {}", "Function fn() {}", 0},
+		{"fn(a, b) {}", "Function fn(a, b) {}", 2},
+		{"fn(a, b = 10) {}", "Function fn(a, b = 10) {}", 1},
+		{"fn(a = 1, b = a * 2) {}", "Function fn(a = 1, b = (a * 2)) {}", 0},
+		{"fn(first, ...rest) {}", "Function fn(first, ...rest) {}", 1},
+		{"fn(...xs) {}", "Function fn(...xs) {}", 0},
+		{"fn(a, b = [], ...rest) {}", "Function fn(a, b = [], ...rest) {}", 1},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function, ok := stmt.Expression.(*ast.FunctionLiteral)
+		if !assert.True(t, ok, "Expression must be FunctionLiteral") {
+			continue
+		}
+		assert.Equal(t, tt.expected, function.String())
+		assert.Equal(t, tt.required, function.Required(), tt.input)
+	}
+}
+
+func TestSpreadArguments(t *testing.T) {
+	p := New(lexer.New("f(1, ...xs, ...[2, 3])"))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.CallExpression)
+	if !assert.True(t, ok, "Expression must be CallExpression") {
+		return
+	}
+	assert.Equal(t, "f(1,...xs,...[2,3],)", exp.String())
+	_, ok = exp.Arguments[1].(*ast.SpreadExpression)
+	assert.True(t, ok, "argument must be SpreadExpression")
+}
+
+func TestParameterErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		errors []string
+	}{
+		{"fn(a = 1, b) {}", []string{"1:11: parameter b without a default follows one with a default"}},
+		{"fn(...rest, a) {}", []string{"1:7: rest parameter ...rest must be the last"}},
+		{"fn(a, ...) {}", []string{"1:10: expected next token to be IDENT, got ) instead"}},
+		{"[...xs]", []string{"1:2: expected an expression, got ... instead"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		var errors []string
+		for _, err := range p.Errors() {
+			errors = append(errors, err.Error())
+		}
+		assert.Equalf(t, tt.errors, errors, "errors for %q", tt.input)
+	}
+}
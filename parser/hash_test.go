@@ -27,23 +27,26 @@ func TestHashLiteral(t *testing.T) {
 
 	assert.Equalf(t, 3, len(m.Pairs), "must have 3 pairs")
 
-	exp := map[string]int64{
-		"one":   1,
-		"two":   2,
-		"three": 3,
+	exp := []struct {
+		key   string
+		value int64
+	}{
+		{"one", 1},
+		{"two", 2},
+		{"three", 3},
 	}
 
-	for k, v := range m.Pairs {
+	// pairs are kept in source order
+	for i, pair := range m.Pairs {
+		key, ok := pair.Key.(*ast.StringLiteral)
+		assert.Truef(t, ok, "Key must be StringLiteral, got %T", pair.Key)
+		val, ok := pair.Value.(*ast.IntegerLiteral)
+		assert.Truef(t, ok, "Value must be IntegerLiteral, got %T", pair.Value)
 
-		key, ok := k.(*ast.StringLiteral)
-		assert.Truef(t, ok, "Key must be StringLiteral, got %T", k)
-		val := v.(*ast.IntegerLiteral)
-		assert.Truef(t, ok, "Value must be IntegerLiteral, got %T", v)
-
-		assert.Equalf(t, exp[key.Value], val.Value, "must match for key %s", key.Value)
-		testIntegerExpression(t, val, exp[key.Value])
+		assert.Equal(t, exp[i].key, key.Value)
+		testIntegerExpression(t, val, exp[i].value)
 	}
-
+	assert.Equal(t, `{one:1, two:2, three:3}`, m.String())
 }
 
 func TestEmptyHashLiteral(t *testing.T) {
@@ -93,13 +96,13 @@ func TestHasExpressions(t *testing.T) {
 			testInfixExpression(t, e, 15, "/", 5)
 		},
 	}
-	for key, value := range m.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
-		assert.Truef(t, ok, "key must be StingLiteral, got %T", key)
+	for _, pair := range m.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
+		assert.Truef(t, ok, "key must be StingLiteral, got %T", pair.Key)
 
 		testFunc, ok := tests[literal.String()]
 		assert.True(t, ok, "no expected test function, got")
 
-		testFunc(value)
+		testFunc(pair.Value)
 	}
 }
@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	st := &ast.WhileStatement{Token: p.curToken}
+
+	// we are at `while`, expect "("
+	p.expectPeek(token.LPAREN)
+	p.nextToken()
+	st.Condition = p.parseExpression(LOWEST)
+	p.expectPeek(token.RPAREN)
+	p.expectPeek(token.LBRACE)
+
+	st.Body = p.parseLoopBody()
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return st
+}
+
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	st := &ast.ForStatement{Token: p.curToken}
+
+	// for (k, v in
+	p.expectPeek(token.LPAREN)
+	p.expectPeek(token.IDENT)
+	st.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.expectPeek(token.IDENT)
+		st.Key = st.Value
+		st.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	p.expectPeek(token.IN)
+
+	p.nextToken()
+	st.Iterable = p.parseExpression(LOWEST)
+	p.expectPeek(token.RPAREN)
+	p.expectPeek(token.LBRACE)
+
+	st.Body = p.parseLoopBody()
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return st
+}
+
+// parseLoopBody parses the block at curToken, in which break and continue are allowed.
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+	return p.parseBlockStatement()
+}
+
+func (p *Parser) parseBranchStatement() *ast.BranchStatement {
+	st := &ast.BranchStatement{Token: p.curToken}
+	if p.loopDepth == 0 {
+		p.fail(&ParseError{
+			Kind:    UnexpectedToken,
+			Message: fmt.Sprintf("%s outside of a loop", p.curToken.Literal),
+			Pos:     p.curToken.Pos,
+			Found:   p.curToken,
+		})
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return st
+}
@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhileStatement(t *testing.T) {
+	p := New(lexer.New(`while (x < y) { let x = x + 1; }`))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	assert.Equal(t, 1, len(program.Statements), "must return one statement")
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	assert.Truef(t, ok, "statement must be WhileStatement, got %T", program.Statements[0])
+	if !ok {
+		return
+	}
+	testInfixExpression(t, stmt.Condition, "x", "<", "y")
+	assert.Equal(t, 1, len(stmt.Body.Statements), "body must have 1 statement")
+}
+
+func TestForStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		key      string
+		value    string
+		iterable string
+	}{
+		{`for (x in xs) { x }`, "", "x", "xs"},
+		{`for (i, x in [1, 2]) { x }`, "i", "x", "[1,2]"},
+		{`for (k, v in range(3)) { k }`, "k", "v", "range(3,)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		assert.Equal(t, 1, len(program.Statements), "must return one statement")
+		stmt, ok := program.Statements[0].(*ast.ForStatement)
+		assert.Truef(t, ok, "statement must be ForStatement, got %T", program.Statements[0])
+		if !ok {
+			continue
+		}
+		if tt.key == "" {
+			assert.Nil(t, stmt.Key, "single variable form has no key")
+		} else {
+			assert.Equal(t, tt.key, stmt.Key.Value)
+		}
+		assert.Equal(t, tt.value, stmt.Value.Value)
+		assert.Equal(t, tt.iterable, stmt.Iterable.String())
+		assert.Equal(t, 1, len(stmt.Body.Statements), "body must have 1 statement")
+	}
+}
+
+func TestBranchStatements(t *testing.T) {
+	tests := []struct {
+		input  string
+		errors []string
+	}{
+		{`while (true) { break; continue }`, nil},
+		{`for (x in xs) { if (x) { continue; } }`, nil},
+		{`while (a) { while (b) { break } break }`, nil},
+		{`break;`, []string{"1:1: break outside of a loop"}},
+		{`if (x) { continue }`, []string{"1:10: continue outside of a loop"}},
+		// a function body is not inside the loop around it
+		{`while (true) { fn() { break } }`, []string{"1:23: break outside of a loop"}},
+		{`while (true) { break }; break`, []string{"1:25: break outside of a loop"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		var errors []string
+		for _, err := range p.Errors() {
+			errors = append(errors, err.Error())
+		}
+		assert.Equalf(t, tt.errors, errors, "errors for %q", tt.input)
+	}
+}
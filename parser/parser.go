@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 
 	"github.com/NishanthSpShetty/monkey/ast"
@@ -17,7 +19,18 @@ type (
 		l         *lexer.Lexer
 		curToken  token.Token
 		peekToken token.Token
-		errors    []string
+		// prevEnd is the end of the token before curToken
+		prevEnd  token.Position
+		errors   []*ParseError
+		comments []*ast.CommentGroup
+		// trailingGroup is set when the last comment group follows a token on its line
+		trailingGroup bool
+		// loopDepth counts the loops around curToken within the current function
+		loopDepth int
+		// braces counts the { before curToken that are not yet closed
+		braces int
+		// nesting counts the expressions and blocks being parsed, see nest
+		nesting int
 
 		prefixParserFns map[token.TokenType]prefixParserFn
 		infixParserFns  map[token.TokenType]infixParserFn
@@ -27,13 +40,14 @@ type (
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:               l,
-		errors:          []string{},
+		errors:          []*ParseError{},
 		prefixParserFns: make(map[token.TokenType]prefixParserFn),
 		infixParserFns:  make(map[token.TokenType]infixParserFn),
 	}
 
 	p.registerPrefixParser(token.IDENT, p.parseIdentifier)
 	p.registerPrefixParser(token.INT, p.parseIntegerLiteral)
+	p.registerPrefixParser(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefixParser(token.BANG, p.parsePrefixExpression)
 	p.registerPrefixParser(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefixParser(token.LPAREN, p.parseGroupedExpression)
@@ -57,6 +71,9 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfixParser(token.GT, p.parseInfixExpression)
 
 	p.registerInfixParser(token.LPAREN, p.parseCallExpression)
+	for _, t := range []token.TokenType{token.ASSIGN, token.PLUS_ASSIGN, token.MINUS_ASSIGN, token.ASTERISK_ASSIGN, token.SLASH_ASSIGN} {
+		p.registerInfixParser(t, p.parseAssignExpression)
+	}
 	p.nextToken()
 	p.nextToken()
 
@@ -72,40 +89,96 @@ func (p *Parser) registerInfixParser(t token.TokenType, fn infixParserFn) {
 }
 
 func (p *Parser) nextToken() {
+	p.prevEnd = p.curToken.End
+	if p.curTokenIs(token.LBRACE) {
+		p.braces++
+	}
 	p.curToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+	if p.curTokenIs(token.RBRACE) && p.braces > 0 {
+		p.braces--
+	}
+	for {
+		p.peekToken = p.l.NextToken()
+		if p.peekToken.Type != token.COMMENT {
+			return
+		}
+		p.addComment(p.peekToken)
+	}
 }
 
-func (p *Parser) Erors() []string {
+// Errors returns the syntax errors found, in source order.
+func (p *Parser) Errors() []*ParseError {
 	return p.errors
 }
 
-// ParseProgram parse whole program and turns into statements
+// ParseProgram parse whole program and turns into statements, statements with
+// syntax errors are left out so the program never holds nil nodes.
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{
 		Statements: []ast.Statement{},
 	}
+	p.parseStatements(program)
+	program.Comments = p.comments
+
+	return program
+}
+
+// parseStatements adds the statements up to EOF to program, or up to the
+// error which aborted the parse.
+func (p *Parser) parseStatements(program *ast.Program) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(abort); !ok {
+				panic(r)
+			}
+		}
+	}()
 
 	for p.curToken.Type != token.EOF {
-		stmnt := p.parseStatement()
+		stmnt := p.parseStatementOrRecover()
 		if stmnt != nil {
 			program.Statements = append(program.Statements, stmnt)
 		}
 		p.nextToken()
 	}
-
-	return program
 }
 
 func (p *Parser) parseStatement() ast.Statement {
+	doc := p.leadComment()
+
 	switch p.curToken.Type {
 	case token.LET:
-		return p.parseLetStatement()
+		stmnt := p.parseLetStatement()
+		stmnt.Doc, stmnt.Comment = doc, p.lineComment()
+		return stmnt
 	case token.RETURN:
-		return p.parseReturnStatement()
+		stmnt := p.parseReturnStatement()
+		stmnt.Doc, stmnt.Comment = doc, p.lineComment()
+		return stmnt
+	case token.WHILE:
+		stmnt := p.parseWhileStatement()
+		stmnt.Doc, stmnt.Comment = doc, p.lineComment()
+		return stmnt
+	case token.FOR:
+		stmnt := p.parseForStatement()
+		stmnt.Doc, stmnt.Comment = doc, p.lineComment()
+		return stmnt
+	case token.BREAK, token.CONTINUE:
+		stmnt := p.parseBranchStatement()
+		stmnt.Doc, stmnt.Comment = doc, p.lineComment()
+		return stmnt
+	case token.TRY:
+		stmnt := p.parseTryStatement()
+		stmnt.Doc, stmnt.Comment = doc, p.lineComment()
+		return stmnt
+	case token.THROW:
+		stmnt := p.parseThrowStatement()
+		stmnt.Doc, stmnt.Comment = doc, p.lineComment()
+		return stmnt
 	default:
-		return p.parseExpressionStatement()
-		// p.errors = append(p.errors, fmt.Sprintf("invalid token: %s", string(p.curToken.Type)))
+		stmnt := p.parseExpressionStatement()
+		stmnt.Doc, stmnt.Comment = doc, p.lineComment()
+		return stmnt
 	}
 }
 
@@ -115,15 +188,11 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 		Token: p.curToken,
 	}
 
-	if !p.expectPeek(token.IDENT) {
-		return nil
-	}
+	p.expectPeek(token.IDENT)
 	// curToken would have been advanced by prev call to expectPeek
 	stmnt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
-	if !p.expectPeek(token.ASSIGN) {
-		return nil
-	}
+	p.expectPeek(token.ASSIGN)
 	p.nextToken()
 	// expr
 	stmnt.Value = p.parseExpression(LOWEST)
@@ -152,8 +221,16 @@ func (p *Parser) curPrecedence() int {
 }
 
 func (p *Parser) peekErrors(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	if p.peekTokenIs(token.ILLEGAL) {
+		p.illegalTokenError(p.peekToken)
+	}
+	p.fail(&ParseError{
+		Kind:     UnexpectedToken,
+		Message:  fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type),
+		Pos:      p.peekToken.Pos,
+		Expected: t,
+		Found:    p.peekToken,
+	})
 }
 
 func (p *Parser) peekTokenIs(t token.TokenType) bool {
@@ -165,14 +242,13 @@ func (p *Parser) curTokenIs(t token.TokenType) bool {
 }
 
 // expectPeek checks if the next token is what we expected,
-// if yes, move to that by calling nextToken
-func (p *Parser) expectPeek(t token.TokenType) bool {
-	if p.peekTokenIs(t) {
-		p.nextToken()
-		return true
+// if yes, move to that by calling nextToken, otherwise it fails
+// the statement being parsed
+func (p *Parser) expectPeek(t token.TokenType) {
+	if !p.peekTokenIs(t) {
+		p.peekErrors(t)
 	}
-	p.peekErrors(t)
-	return false
+	p.nextToken()
 }
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
@@ -200,10 +276,38 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	stmnt := &ast.IntegerLiteral{Token: p.curToken}
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		// too large for an int64
+		if big, ok := new(big.Int).SetString(p.curToken.Literal, 0); ok {
+			stmnt.Big = big
+			return stmnt
+		}
+	}
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
-		return nil
+		p.fail(&ParseError{
+			Kind:    InvalidLiteral,
+			Message: fmt.Sprintf("could not parse %q as integer", p.curToken.Literal),
+			Pos:     p.curToken.Pos,
+			Found:   p.curToken,
+		})
+	}
+
+	stmnt.Value = value
+
+	return stmnt
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	stmnt := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		p.fail(&ParseError{
+			Kind:    InvalidLiteral,
+			Message: fmt.Sprintf("could not parse %q as float", p.curToken.Literal),
+			Pos:     p.curToken.Pos,
+			Found:   p.curToken,
+		})
 	}
 
 	stmnt.Value = value
@@ -217,21 +321,15 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	}
 
 	// we are at `if`, expect "("
-	if !p.expectPeek(token.LPAREN) {
-		return nil
-	}
+	p.expectPeek(token.LPAREN)
 	p.nextToken()
 	exp.Condition = p.parseExpression(LOWEST)
 
 	// expect ")" and skip to ")"
-	if !p.expectPeek(token.RPAREN) {
-		return nil
-	}
+	p.expectPeek(token.RPAREN)
 
 	// skip ) and move to {
-	if !p.expectPeek(token.LBRACE) {
-		return nil
-	}
+	p.expectPeek(token.LBRACE)
 
 	exp.Consequence = p.parseBlockStatement()
 
@@ -241,9 +339,7 @@ func (p *Parser) parseIfExpression() ast.Expression {
 		p.nextToken()
 
 		// move to {
-		if !p.expectPeek(token.LBRACE) {
-			return nil
-		}
+		p.expectPeek(token.LBRACE)
 		exp.Alternative = p.parseBlockStatement()
 	}
 	return exp
@@ -255,16 +351,35 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		Statements: []ast.Statement{},
 	}
 
+	p.nest()
+	defer func() { p.nesting-- }()
+
 	// skip the {
 	p.nextToken()
+	depth := p.braces
 
 	// until we hit the } or end of statements
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		bs.Statements = append(bs.Statements, stmt)
+		stmt := p.parseStatementOrRecover()
+		if stmt != nil {
+			bs.Statements = append(bs.Statements, stmt)
+		} else if p.curTokenIs(token.RBRACE) && p.braces < depth {
+			// recovery stopped on the } closing this block
+			break
+		}
 		// move over
 		p.nextToken()
 	}
+	if p.curTokenIs(token.EOF) {
+		p.fail(&ParseError{
+			Kind:     UnexpectedToken,
+			Message:  "expected } to close the block, got EOF instead",
+			Pos:      p.curToken.Pos,
+			Expected: token.RBRACE,
+			Found:    p.curToken,
+		})
+	}
+	bs.Rbrace = p.curToken
 
 	return bs
 }
@@ -279,9 +394,10 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	// [1,2,3]
 	a := &ast.ArrayLiteral{
-		Token:    p.curToken,
-		Elements: p.parseExpressionList(token.RBRACKET),
+		Token: p.curToken,
 	}
+	a.Elements = p.parseExpressionList(token.RBRACKET)
+	a.Rbracket = p.curToken
 
 	return a
 }
@@ -294,9 +410,8 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	p.nextToken()
 	exp.Index = p.parseExpression(LOWEST)
 
-	if !p.expectPeek(token.RBRACKET) {
-		return nil
-	}
+	p.expectPeek(token.RBRACKET)
+	exp.Rbracket = p.curToken
 
 	return exp
 }
@@ -304,7 +419,7 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 func (p *Parser) parseHashLiteral() ast.Expression {
 	h := &ast.HashLiteral{
 		Token: p.curToken,
-		Pairs: make(map[ast.Expression]ast.Expression),
+		Pairs: []ast.HashPair{},
 	}
 
 	// we are at {
@@ -314,19 +429,16 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 
 		key := p.parseExpression(LOWEST)
 
-		if !p.expectPeek(token.COLON) {
-			return nil
-		}
+		p.expectPeek(token.COLON)
 		p.nextToken()
 		val := p.parseExpression(LOWEST)
-		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
-			return nil
+		if !p.peekTokenIs(token.RBRACE) {
+			p.expectPeek(token.COMMA)
 		}
-		h.Pairs[key] = val
-	}
-	// move to {
-	if !p.expectPeek(token.RBRACE) {
-		return nil
+		h.Pairs = append(h.Pairs, ast.HashPair{Key: key, Value: val})
 	}
+	// move to }
+	p.expectPeek(token.RBRACE)
+	h.Rbrace = p.curToken
 	return h
 }
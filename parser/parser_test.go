@@ -73,7 +73,7 @@ func testLetStmnt(t *testing.T, s ast.Statement, name string, val interface{}) b
 }
 
 func checkParseErrors(t *testing.T, p *Parser) {
-	errors := p.Erors()
+	errors := p.Errors()
 
 	if len(errors) == 0 {
 		return
@@ -198,3 +198,56 @@ func TestParsingIndexExpressions(t *testing.T) {
 	testIdentifierExpression(t, indexExp.Left, "myArray")
 	testInfixExpression(t, indexExp.Index, 1, "+", 1)
 }
+
+func TestErrorPosition(t *testing.T) {
+	input := "let a = 1;\nlet b = (a + 2;\n"
+
+	l := lexer.NewNamed("main.mk", input)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	assert.Equal(t, 1, len(errors), "must report a parse error")
+	assert.Equal(t, "2:15: expected next token to be ), got ; instead", errors[0].Error())
+	assert.Equal(t, "main.mk:2:15: expected next token to be ), got ; instead\n"+
+		"    let b = (a + 2;\n"+
+		"                  ^", errors[0].Render(l.File()))
+}
+
+func TestUnicodeErrorPosition(t *testing.T) {
+	input := "let größe = \"\\q\";"
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	assert.Equal(t, 1, len(errors), "must report a parse error")
+	assert.Equal(t, "1:14: unknown escape sequence \\q\n"+
+		"    let größe = \"\\q\";\n"+
+		"                 ^", errors[0].Render(l.File()))
+}
+
+func TestNodePositions(t *testing.T) {
+	input := "let add = fn(a, b) {\n  a + b\n};\nadd(1, [2][0])"
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	assert.Equal(t, 2, len(program.Statements), "must have 2 statements")
+	let := program.Statements[0].(*ast.LetStatement)
+	assert.Equal(t, "1:1", let.Pos().String())
+	assert.Equal(t, "3:2", let.End().String())
+
+	body := let.Value.(*ast.FunctionLiteral).Body.Statements[0].(*ast.ExpressionStatement)
+	assert.Equal(t, "2:3", body.Pos().String())
+	assert.Equal(t, "2:8", body.End().String())
+
+	call := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	assert.Equal(t, "4:1", call.Pos().String())
+	assert.Equal(t, "4:15", call.End().String())
+	index := call.Arguments[1].(*ast.IndexExpression)
+	assert.Equal(t, "4:8", index.Pos().String())
+	assert.Equal(t, "4:14", index.End().String())
+}
@@ -7,6 +7,7 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // x = y
 	EQUALS      // ==
 	LESSGREATER // > or <
 	SUM         // +
@@ -27,4 +28,19 @@ var precedences = map[token.TokenType]int{
 	token.ASTERISK: PRODUCT,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
+
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+}
+
+// Precedence returns how tightly the infix operator t binds, LOWEST when t
+// is not an infix operator.
+func Precedence(t token.TokenType) int {
+	if pr, ok := precedences[t]; ok {
+		return pr
+	}
+	return LOWEST
 }
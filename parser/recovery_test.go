@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/NishanthSpShetty/monkey/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseErrorDetails(t *testing.T) {
+	tests := []struct {
+		input    string
+		kind     ErrorKind
+		pos      string
+		expected token.TokenType
+		found    token.TokenType
+	}{
+		{"let = 5;", UnexpectedToken, "1:5", token.IDENT, token.ASSIGN},
+		{"let x 5;", UnexpectedToken, "1:7", token.ASSIGN, token.INT},
+		{"1 + ;", MissingExpression, "1:5", "", token.SEMICOLON},
+		{"1e999", InvalidLiteral, "1:1", "", token.FLOAT},
+		{"fn(1) {}", UnexpectedToken, "1:4", token.IDENT, token.INT},
+		{"if (x) { x", UnexpectedToken, "1:11", token.RBRACE, token.EOF},
+		{`let s = "abc`, InvalidToken, "1:9", "", token.ILLEGAL},
+		{`let s "abc`, InvalidToken, "1:7", "", token.ILLEGAL},
+		{`x + #`, InvalidToken, "1:5", "", token.ILLEGAL},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		assert.Equalf(t, 1, len(errors), "errors for %q", tt.input)
+		if len(errors) == 0 {
+			continue
+		}
+		err := errors[0]
+		assert.Equalf(t, tt.kind, err.Kind, "kind for %q", tt.input)
+		assert.Equalf(t, tt.pos, err.Pos.String(), "position for %q", tt.input)
+		assert.Equalf(t, tt.expected, err.Expected, "expected token for %q", tt.input)
+		assert.Equalf(t, tt.found, err.Found.Type, "found token for %q", tt.input)
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	input := `
+let a = ;
+let b = 2;
+let f = fn(x) {
+	let = x;
+	return x +;
+	x * b
+};
+let c = (1 + 2;
+f(b)
+`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+
+	positions := []string{}
+	for _, err := range p.Errors() {
+		positions = append(positions, err.Pos.String())
+	}
+	assert.Equal(t, []string{"2:9", "5:6", "6:12", "9:15"}, positions, "every error must be reported once")
+
+	// the statements with errors are dropped, the rest survive intact
+	assert.Equal(t, 3, len(program.Statements))
+	assert.Equal(t, "b", program.Statements[0].(*ast.LetStatement).Name.Value)
+	fn := program.Statements[1].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	assert.Equal(t, 1, len(fn.Body.Statements))
+	assert.Equal(t, "f(b,)", program.Statements[2].String())
+}
+
+func TestRecoveryStopsAtClosingBrace(t *testing.T) {
+	inputs := []string{
+		"let f = fn() { let x = };\nlet y = 2;",
+		"let f = fn() { let h = {1: }; 1 };\nlet y = 2;",
+		"if (true) { while (x) { let = } }\nlet y = 2;",
+	}
+
+	for _, input := range inputs {
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+
+		assert.Lenf(t, p.Errors(), 1, "%q must report only its own error", input)
+		last := program.Statements[len(program.Statements)-1]
+		assert.Equalf(t, "let y = 2", last.String(), "%q must parse the statement after the block", input)
+	}
+}
+
+func TestNestingTooDeep(t *testing.T) {
+	inputs := []string{
+		strings.Repeat("[", 100000),
+		strings.Repeat("-", 100000) + "1",
+		strings.Repeat("while (x) { ", 100000),
+		"let a = 1;\n" + strings.Repeat("(", 2000) + "1" + strings.Repeat(")", 2000),
+	}
+
+	for _, input := range inputs {
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+
+		if assert.Lenf(t, p.Errors(), 1, "%.20q must stop at the first error", input) {
+			assert.Equal(t, NestingTooDeep, p.Errors()[0].Kind)
+		}
+		for _, st := range program.Statements {
+			assert.Equal(t, "let a = 1", st.String())
+		}
+	}
+
+	// as deep as the limit is fine
+	input := strings.Repeat("-", maxNesting-1) + "1"
+	p := New(lexer.New(input))
+	p.ParseProgram()
+	assert.Empty(t, p.Errors())
+}
+
+func TestProgramHasNoNilNodes(t *testing.T) {
+	inputs := []string{
+		"let",
+		"let x = ",
+		"if (",
+		"if (x) { let } else {",
+		"fn(a, ) { a }",
+		"[1, 2",
+		"{1: }",
+		"{1 2}",
+		"add(1, 2",
+		"a[1",
+		"}} let x = 1; ))",
+		"return",
+	}
+
+	for _, input := range inputs {
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+
+		assert.NotEmptyf(t, p.Errors(), "%q must have errors", input)
+		for _, st := range program.Statements {
+			assert.NotNilf(t, st, "%q must not produce nil statements", input)
+			// String walks the whole tree and would panic on a nil node
+			assert.NotPanicsf(t, func() { _ = st.String() }, "%q", input)
+		}
+	}
+}
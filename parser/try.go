@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	st := &ast.TryStatement{Token: p.curToken}
+
+	p.expectPeek(token.LBRACE)
+	st.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		// catch (e) { or catch {
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			p.expectPeek(token.IDENT)
+			st.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			p.expectPeek(token.RPAREN)
+		}
+		p.expectPeek(token.LBRACE)
+		st.Catch = p.parseBlockStatement()
+	}
+
+	if st.Catch == nil || p.peekTokenIs(token.FINALLY) {
+		// without a catch the finally is required
+		p.expectPeek(token.FINALLY)
+		p.expectPeek(token.LBRACE)
+		st.Finally = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return st
+}
+
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	st := &ast.ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
+	st.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return st
+}
@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTryStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`try { f() } catch (e) { g(e) }`, "try { f() } catch (e) { g(e,) }"},
+		{`try { f() } catch { 1 }`, "try { f() } catch { 1 }"},
+		{`try { f() } finally { close() }`, "try { f() } finally { close() }"},
+		{`try { f() } catch (e) { 1 } finally { 2 };`, "try { f() } catch (e) { 1 } finally { 2 }"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		assert.Equal(t, 1, len(program.Statements), "must return one statement")
+		stmt, ok := program.Statements[0].(*ast.TryStatement)
+		assert.Truef(t, ok, "statement must be TryStatement, got %T", program.Statements[0])
+		if !ok {
+			continue
+		}
+		assert.Equal(t, tt.expected, stmt.String())
+	}
+}
+
+func TestThrowStatement(t *testing.T) {
+	p := New(lexer.New(`throw error("bad " + x); 1`))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	assert.Equal(t, 2, len(program.Statements), "must return two statements")
+	stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+	assert.Truef(t, ok, "statement must be ThrowStatement, got %T", program.Statements[0])
+	if ok {
+		assert.Equal(t, `error((bad  + x),)`, stmt.Value.String())
+	}
+}
+
+func TestTryErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		errors []string
+	}{
+		{`try { f() }`, []string{"1:12: expected next token to be FINALLY, got EOF instead"}},
+		{`try { f() } catch (1) { }`, []string{"1:20: expected next token to be IDENT, got INT instead"}},
+		{`try f() catch { }`, []string{"1:5: expected next token to be {, got IDENT instead"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		var errors []string
+		for _, err := range p.Errors() {
+			errors = append(errors, err.Error())
+		}
+		assert.Equalf(t, tt.errors, errors, "errors for %q", tt.input)
+	}
+}
@@ -0,0 +1,169 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/NishanthSpShetty/monkey/parser"
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+// command is a repl command, typed :name followed by its argument.
+type command struct {
+	// arg describes the argument, empty for commands without one
+	arg  string
+	help string
+	// run runs the command, it returns false to leave the repl
+	run func(s *session, arg string) bool
+	// optional tells the argument may be left out
+	optional bool
+}
+
+var commands map[string]*command
+
+// commandNames lists the commands in the order :help shows them.
+var commandNames = []string{"help", "env", "load", "save", "reset", "ast", "tokens", "type", "time", "exit", "quit"}
+
+func init() {
+	commands = map[string]*command{
+		"help":   {help: "list the commands", run: (*session).help},
+		"env":    {help: "list the names bound in the session", run: (*session).env},
+		"load":   {arg: "<file>", help: "evaluate a script in the session", run: (*session).load},
+		"save":   {arg: "[file]", help: "write what was evaluated in the session to file, or show it", run: (*session).save, optional: true},
+		"reset":  {help: "forget everything bound and evaluated in the session", run: (*session).reset},
+		"ast":    {arg: "<source>", help: "show how source is parsed", run: (*session).ast},
+		"tokens": {arg: "<source>", help: "show the tokens of source", run: (*session).tokens},
+		"type":   {arg: "<source>", help: "evaluate source and show the type of its value", run: (*session).typeOf},
+		"time":   {arg: "<source>", help: "evaluate source and show how long it took", run: (*session).time},
+		"exit":   {help: "leave the repl", run: (*session).exit},
+		"quit":   {help: "leave the repl", run: (*session).exit},
+	}
+}
+
+// command runs the command in line, it returns false to leave the repl.
+func (s *session) command(line string) bool {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(line, ":"), " ")
+	arg = strings.TrimSpace(arg)
+
+	cmd, ok := commands[name]
+	if !ok {
+		io.WriteString(s.out, MONKEY_FACE)
+		io.WriteString(s.out, "Woops! We ran into some monkey business here!\n")
+		fmt.Fprintf(s.out, "invalid command: %s, :help lists the commands\n", name)
+		return true
+	}
+	if arg == "" && cmd.arg != "" && !cmd.optional {
+		fmt.Fprintf(s.out, "usage: :%s %s\n", name, cmd.arg)
+		return true
+	}
+	return cmd.run(s, arg)
+}
+
+func (s *session) help(string) bool {
+	for _, name := range commandNames {
+		cmd := commands[name]
+		usage := ":" + name
+		if cmd.arg != "" {
+			usage += " " + cmd.arg
+		}
+		fmt.Fprintf(s.out, "  %-18s %s\n", usage, cmd.help)
+	}
+	return true
+}
+
+func (s *session) env(string) bool {
+	s.r.PrintVars(s.out)
+	return true
+}
+
+func (s *session) load(path string) bool {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(s.out, "load: %s\n", err)
+		return true
+	}
+	s.eval(path, string(src))
+	return true
+}
+
+func (s *session) save(path string) bool {
+	var out strings.Builder
+	for _, input := range s.inputs {
+		out.WriteString(input)
+		out.WriteString("\n")
+	}
+	if path == "" {
+		io.WriteString(s.out, out.String())
+		return true
+	}
+	if err := os.WriteFile(path, []byte(out.String()), 0644); err != nil {
+		fmt.Fprintf(s.out, "save: %s\n", err)
+		return true
+	}
+	fmt.Fprintf(s.out, "saved %d inputs to %s\n", len(s.inputs), path)
+	return true
+}
+
+func (s *session) reset(string) bool {
+	s.r = runtime.New()
+	s.inputs = nil
+	return true
+}
+
+func (s *session) ast(src string) bool {
+	l := lexer.NewNamed("repl", src)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParseError(s.out, l.File(), p.Errors())
+		return true
+	}
+	for _, stmt := range program.Statements {
+		fmt.Fprintf(s.out, "%T %s\n", stmt, stmt)
+	}
+	return true
+}
+
+func (s *session) tokens(src string) bool {
+	l := lexer.NewNamed("repl", src)
+	for {
+		tok := l.NextToken()
+		if tok.Type == token.EOF {
+			return true
+		}
+		fmt.Fprintf(s.out, "%s\t%s\t%q\n", tok.Pos, tok.Type, tok.Literal)
+	}
+}
+
+func (s *session) typeOf(src string) bool {
+	eval, ok := s.eval("repl", src)
+	if !ok {
+		return true
+	}
+	if eval == nil {
+		// a statement has no value
+		eval = runtime.Nil
+	}
+	fmt.Fprintln(s.out, eval.Type())
+	return true
+}
+
+func (s *session) time(src string) bool {
+	start := time.Now()
+	eval, ok := s.eval("repl", src)
+	elapsed := time.Since(start)
+	if ok && eval != nil {
+		fmt.Fprintln(s.out, eval.Inspect())
+	}
+	fmt.Fprintf(s.out, "time: %s\n", elapsed)
+	return true
+}
+
+func (s *session) exit(string) bool {
+	return false
+}
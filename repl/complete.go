@@ -0,0 +1,54 @@
+package repl
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+// Complete returns the word being typed at the end of line and the
+// keywords, builtins and names bound in r it could be completed to.
+func Complete(r *runtime.Runtime, line string) (word string, candidates []string) {
+	start := len(line)
+	for start > 0 {
+		c, size := utf8.DecodeLastRuneInString(line[:start])
+		if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			break
+		}
+		start -= size
+	}
+	word = line[start:]
+	if word == "" {
+		return "", nil
+	}
+
+	seen := map[string]bool{}
+	for _, names := range [][]string{token.Keywords(), runtime.BuiltinNames(), r.Names()} {
+		for _, name := range names {
+			if strings.HasPrefix(name, word) && !seen[name] {
+				seen[name] = true
+				candidates = append(candidates, name)
+			}
+		}
+	}
+	sort.Strings(candidates)
+	return word, candidates
+}
+
+// commonPrefix returns the longest prefix shared by words.
+func commonPrefix(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	prefix := []rune(words[0])
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, string(prefix)) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return string(prefix)
+}
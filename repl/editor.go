@@ -0,0 +1,306 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrInterrupted is returned by ReadLine when ctrl-c abandons the line.
+var ErrInterrupted = errors.New("interrupted")
+
+// LineReader reads the lines typed at the repl.
+type LineReader interface {
+	// ReadLine shows prompt and returns the line read without its newline,
+	// io.EOF ends the input.
+	ReadLine(prompt string) (string, error)
+}
+
+// plainReader reads lines from input which is not a terminal.
+type plainReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (p *plainReader) ReadLine(prompt string) (string, error) {
+	io.WriteString(p.out, prompt)
+	if !p.scanner.Scan() {
+		if err := p.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return p.scanner.Text(), nil
+}
+
+// Completer returns the word being typed at the end of line and what it
+// could be completed to.
+type Completer func(line string) (word string, candidates []string)
+
+// Editor reads lines key by key from a terminal, it moves through the line
+// with the arrow keys, through the history with up and down and completes
+// words with tab.
+type Editor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	history  *History
+	complete Completer
+	// fd is the terminal switched to raw mode while a line is read
+	fd       uintptr
+	terminal bool
+
+	prompt string
+	buf    []rune
+	pos    int
+	// index is the history line shown, history.Len() for the line being typed
+	index int
+	draft []rune
+}
+
+// NewEditor creates an editor reading keys from in and echoing to out, the
+// lines read are added to history.
+func NewEditor(in io.Reader, out io.Writer, history *History, complete Completer) *Editor {
+	return &Editor{
+		in:       bufio.NewReader(in),
+		out:      out,
+		history:  history,
+		complete: complete,
+	}
+}
+
+// key codes
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyLF        = 10
+	keyCtrlK     = 11
+	keyCR        = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyEsc       = 27
+	keyBackspace = 127
+)
+
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if e.terminal {
+		restore, err := makeRaw(e.fd)
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+
+	e.prompt = prompt
+	e.buf = e.buf[:0]
+	e.pos = 0
+	e.index = e.history.Len()
+	e.draft = nil
+	e.refresh()
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			if err == io.EOF && len(e.buf) > 0 {
+				// the last line had no newline
+				return e.enter()
+			}
+			return "", err
+		}
+
+		switch r {
+		case keyCR, keyLF:
+			return e.enter()
+		case keyCtrlC:
+			io.WriteString(e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case keyCtrlD:
+			if len(e.buf) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.delete()
+		case keyBackspace, keyCtrlH:
+			if e.pos > 0 {
+				e.pos--
+				e.delete()
+			}
+		case keyCtrlA:
+			e.pos = 0
+		case keyCtrlE:
+			e.pos = len(e.buf)
+		case keyCtrlB:
+			e.left()
+		case keyCtrlF:
+			e.right()
+		case keyCtrlK:
+			e.buf = e.buf[:e.pos]
+		case keyCtrlU:
+			e.buf = append(e.buf[:0], e.buf[e.pos:]...)
+			e.pos = 0
+		case keyCtrlP:
+			e.previous()
+		case keyCtrlN:
+			e.next()
+		case keyTab:
+			e.completeWord()
+		case keyEsc:
+			e.escape()
+		default:
+			if r < ' ' {
+				// other control keys are ignored
+				continue
+			}
+			e.insert(string(r))
+		}
+		e.refresh()
+	}
+}
+
+// escape handles the escape sequences sent by the arrow, home, end and delete keys.
+func (e *Editor) escape() {
+	r, _, err := e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return
+	}
+	// parameters, like the 3 of delete ESC [ 3 ~
+	var param strings.Builder
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return
+		}
+		if r < '0' || r > '9' {
+			break
+		}
+		param.WriteRune(r)
+	}
+
+	switch r {
+	case 'A':
+		e.previous()
+	case 'B':
+		e.next()
+	case 'C':
+		e.right()
+	case 'D':
+		e.left()
+	case 'H':
+		e.pos = 0
+	case 'F':
+		e.pos = len(e.buf)
+	case '~':
+		switch param.String() {
+		case "1", "7":
+			e.pos = 0
+		case "4", "8":
+			e.pos = len(e.buf)
+		case "3":
+			e.delete()
+		}
+	}
+}
+
+// enter ends the line, adding it to the history.
+func (e *Editor) enter() (string, error) {
+	io.WriteString(e.out, "\r\n")
+	line := string(e.buf)
+	if err := e.history.Add(line); err != nil {
+		fmt.Fprintf(e.out, "history: %s\r\n", err)
+	}
+	return line, nil
+}
+
+func (e *Editor) insert(s string) {
+	rs := []rune(s)
+	buf := make([]rune, 0, len(e.buf)+len(rs))
+	buf = append(buf, e.buf[:e.pos]...)
+	buf = append(buf, rs...)
+	e.buf = append(buf, e.buf[e.pos:]...)
+	e.pos += len(rs)
+}
+
+// delete removes the character under the cursor.
+func (e *Editor) delete() {
+	if e.pos < len(e.buf) {
+		e.buf = append(e.buf[:e.pos], e.buf[e.pos+1:]...)
+	}
+}
+
+func (e *Editor) left() {
+	if e.pos > 0 {
+		e.pos--
+	}
+}
+
+func (e *Editor) right() {
+	if e.pos < len(e.buf) {
+		e.pos++
+	}
+}
+
+// previous shows the history line before the one shown.
+func (e *Editor) previous() {
+	if e.index == 0 {
+		return
+	}
+	if e.index == e.history.Len() {
+		e.draft = append([]rune{}, e.buf...)
+	}
+	e.index--
+	e.show([]rune(e.history.Line(e.index)))
+}
+
+// next shows the history line after the one shown, or the line being typed.
+func (e *Editor) next() {
+	if e.index == e.history.Len() {
+		return
+	}
+	e.index++
+	if e.index == e.history.Len() {
+		e.show(e.draft)
+		return
+	}
+	e.show([]rune(e.history.Line(e.index)))
+}
+
+func (e *Editor) show(line []rune) {
+	e.buf = append(e.buf[:0], line...)
+	e.pos = len(e.buf)
+}
+
+// completeWord completes the word before the cursor as far as all the
+// candidates agree, and lists them when that adds nothing.
+func (e *Editor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+	word, candidates := e.complete(string(e.buf[:e.pos]))
+	if len(candidates) == 0 {
+		io.WriteString(e.out, "\a")
+		return
+	}
+	if prefix := commonPrefix(candidates); len(prefix) > len(word) {
+		e.insert(prefix[len(word):])
+		return
+	}
+	if len(candidates) > 1 {
+		io.WriteString(e.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+	}
+}
+
+// refresh redraws the line and puts the cursor in place.
+func (e *Editor) refresh() {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.prompt, string(e.buf))
+	if back := len(e.buf) - e.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
@@ -0,0 +1,88 @@
+package repl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxHistory is the number of lines kept from the history file.
+const maxHistory = 1000
+
+// History holds the lines entered, oldest first, and appends them to a
+// file when it has one.
+type History struct {
+	lines []string
+	path  string
+}
+
+// HistoryFile returns where the history is kept, $MONKEY_HISTORY or
+// .monkey_history in the home directory. It is empty when neither is known.
+func HistoryFile() string {
+	if path := os.Getenv("MONKEY_HISTORY"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".monkey_history")
+}
+
+// LoadHistory reads the history kept in path, a missing file is an empty
+// history. With an empty path the history is not kept.
+func LoadHistory(path string) (*History, error) {
+	h := &History{path: path}
+	if path == "" {
+		return h, nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		h.lines = append(h.lines, scanner.Text())
+	}
+	if len(h.lines) > maxHistory {
+		h.lines = h.lines[len(h.lines)-maxHistory:]
+	}
+	return h, scanner.Err()
+}
+
+// Add records line, blank lines and repeats of the last line are skipped.
+func (h *History) Add(line string) error {
+	if strings.TrimSpace(line) == "" || (len(h.lines) > 0 && h.lines[len(h.lines)-1] == line) {
+		return nil
+	}
+	h.lines = append(h.lines, line)
+	if h.path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(line + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Len returns the number of lines in the history.
+func (h *History) Len() int {
+	return len(h.lines)
+}
+
+// Line returns the i-th line, 0 is the oldest.
+func (h *History) Line(i int) string {
+	return h.lines[i]
+}
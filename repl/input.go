@@ -0,0 +1,37 @@
+package repl
+
+import (
+	"strings"
+
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+// Incomplete reports whether src needs more lines: it has brackets, braces
+// or parens left open, or ends inside a string or a block comment.
+func Incomplete(src string) bool {
+	l := lexer.New(src)
+	depth := 0
+	for {
+		tok := l.NextToken()
+		switch tok.Type {
+		case token.LPAREN, token.LBRACE, token.LBRACKET:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACKET:
+			depth--
+		case token.ILLEGAL:
+			if tok.Literal == lexer.UnterminatedString || tok.Literal == lexer.UnterminatedComment {
+				return true
+			}
+		case token.EOF:
+			// too many closing ones are left to the parser to report
+			return depth > 0
+		}
+	}
+}
+
+// isCommand reports whether line names a repl command, like :exit.
+func isCommand(line string) bool {
+	name, _, _ := strings.Cut(strings.TrimPrefix(line, ":"), " ")
+	return strings.HasPrefix(line, ":") && commands[name] != nil
+}
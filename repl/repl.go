@@ -2,15 +2,18 @@ package repl
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"strings"
 
 	"github.com/NishanthSpShetty/monkey/lexer"
 	"github.com/NishanthSpShetty/monkey/parser"
 	"github.com/NishanthSpShetty/monkey/runtime/evaluator"
 	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/NishanthSpShetty/monkey/token"
 )
 
 const (
@@ -29,65 +32,125 @@ const (
 `
 )
 
-func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+// CONTINUE_PROMPT is shown for the lines continuing an incomplete input.
+const CONTINUE_PROMPT = ".."
 
-	r := runtime.New()
+// Start runs the repl until in ends or :exit. When in is a terminal the
+// lines can be edited, the history is kept in HistoryFile and tab completes names.
+func Start(in io.Reader, out io.Writer) {
+	s := &session{r: runtime.New(), out: out}
+	lines := newLineReader(in, out, func(line string) (string, []string) {
+		return Complete(s.r, line)
+	})
 	for {
-		fmt.Printf(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
-			// no more tokens to read
+		line, err := readInput(lines)
+		if err == ErrInterrupted {
+			continue
+		}
+		if err != nil {
+			// no more input
 			return
 		}
 
-		line := scanner.Text()
-
 		if strings.HasPrefix(line, ":") {
 			// runtime/repl commands
-			run(r, out, line)
+			if !s.command(line) {
+				return
+			}
 			continue
 		}
 
-		l := lexer.New(line)
+		if eval, ok := s.eval("repl", line); ok && eval != nil {
+			io.WriteString(out, eval.Inspect())
+			io.WriteString(out, "\n")
+		}
+	}
+}
 
-		p := parser.New(l)
+// session is what the repl keeps between inputs.
+type session struct {
+	r   *runtime.Runtime
+	out io.Writer
+	// inputs are the sources evaluated, for :save
+	inputs []string
+}
 
-		program := p.ParseProgram()
-		if len(p.Erors()) != 0 {
-			printParseError(out, p.Erors())
-			continue
-		}
+// eval parses and evaluates src read from the file name, it reports the
+// errors to out and ok is false when there were any.
+func (s *session) eval(name, src string) (eval runtime.Object, ok bool) {
+	l := lexer.NewNamed(name, src)
 
-		eval := evaluator.Eval(r, program)
-		if eval != nil {
+	p := parser.New(l)
 
-			io.WriteString(out, eval.Inspect())
-			io.WriteString(out, "\n")
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParseError(s.out, l.File(), p.Errors())
+		return nil, false
+	}
+	s.inputs = append(s.inputs, src)
+
+	// ctrl-c stops the evaluation rather than the repl
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	eval = evaluator.Eval(ctx, s.r, program)
+	stop()
+	if err, ok := eval.(*runtime.Error); ok {
+		io.WriteString(s.out, err.Render(l.File()))
+		io.WriteString(s.out, "\n")
+		return nil, false
+	}
+	return eval, true
+}
+
+// newLineReader returns an Editor when in is a terminal and reads plain
+// lines otherwise.
+func newLineReader(in io.Reader, out io.Writer, complete Completer) LineReader {
+	f, ok := in.(*os.File)
+	if !ok || !isTerminal(f.Fd()) {
+		return &plainReader{scanner: bufio.NewScanner(in), out: out}
+	}
+
+	history, err := LoadHistory(HistoryFile())
+	if err != nil {
+		fmt.Fprintf(out, "history: %s\n", err)
+		history = &History{}
+	}
+	e := NewEditor(f, out, history, complete)
+	e.fd = f.Fd()
+	e.terminal = true
+	return e
+}
+
+// readInput reads a line, and more lines while the input is incomplete so
+// functions, blocks and strings can span lines. Commands are a single line,
+// a command read while the input is incomplete drops the input, so :exit
+// leaves even from inside an unterminated string.
+func readInput(lines LineReader) (string, error) {
+	input, err := lines.ReadLine(PROMPT)
+	if err != nil || strings.HasPrefix(input, ":") {
+		return input, err
+	}
+	for Incomplete(input) {
+		line, err := lines.ReadLine(CONTINUE_PROMPT)
+		if err == io.EOF {
+			// the parser reports what is missing
+			return input, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if isCommand(line) {
+			return line, nil
 		}
+		input += "\n" + line
 	}
+	return input, nil
 }
 
-func printParseError(out io.Writer, errors []string) {
+func printParseError(out io.Writer, file *token.File, errors []*parser.ParseError) {
 	io.WriteString(out, MONKEY_FACE)
 	io.WriteString(out, "Woops! We ran into some monkey business here!\n")
 	io.WriteString(out, " parser errors:\n")
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
-	}
-}
-
-func run(r *runtime.Runtime, out io.Writer, line string) {
-	cmd := strings.Split(line, ":")[1]
-	switch cmd {
-	case "env":
-		r.PrintVars()
-	case "exit", "quit":
-		os.Exit(0)
-	default:
-
-		io.WriteString(out, MONKEY_FACE)
-		io.WriteString(out, "Woops! We ran into some monkey business here!\n")
-		io.WriteString(out, fmt.Sprintf("invalid command: %s\n", cmd))
+	for _, err := range errors {
+		io.WriteString(out, err.Render(file)+"\n")
 	}
 }
@@ -0,0 +1,221 @@
+package repl
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input      string
+		incomplete bool
+	}{
+		{"let a = 1", false},
+		{"let f = fn(x) {", true},
+		{"let f = fn(x) {\n  x + 1\n}", false},
+		{"[1, 2,", true},
+		{"f(1,\n[2, 3]", true},
+		{`"a { string"`, false},
+		{`"a string`, true},
+		{"/* a comment", true},
+		{"1 } }", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.incomplete, Incomplete(tt.input), tt.input)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	r := runtime.New()
+	r.Put("fibonacci", runtime.Nil)
+	r.Put("filtered", runtime.Nil)
+
+	tests := []struct {
+		line       string
+		word       string
+		candidates []string
+	}{
+		{"fi", "fi", []string{"fibonacci", "filter", "filtered", "finally", "find", "first"}},
+		{"let x = fib", "fib", []string{"fibonacci"}},
+		{"whi", "whi", []string{"while"}},
+		{"map(xs, ", "", nil},
+		{"zzz", "zzz", nil},
+	}
+
+	for _, tt := range tests {
+		word, candidates := Complete(r, tt.line)
+		assert.Equal(t, tt.word, word, tt.line)
+		assert.Equal(t, tt.candidates, candidates, tt.line)
+	}
+}
+
+// readLines reads lines from keys with an editor until the input ends.
+func readLines(keys string, history *History) ([]string, []error) {
+	r := runtime.New()
+	r.Put("fibonacci", runtime.Nil)
+	e := NewEditor(strings.NewReader(keys), io.Discard, history, func(line string) (string, []string) {
+		return Complete(r, line)
+	})
+
+	var lines []string
+	var errs []error
+	for {
+		line, err := e.ReadLine(PROMPT)
+		if err == io.EOF {
+			return lines, errs
+		}
+		lines = append(lines, line)
+		errs = append(errs, err)
+	}
+}
+
+func TestEditor(t *testing.T) {
+	const (
+		up    = "\x1b[A"
+		down  = "\x1b[B"
+		right = "\x1b[C"
+		left  = "\x1b[D"
+		home  = "\x1b[H"
+		end   = "\x1b[F"
+		del   = "\x1b[3~"
+	)
+
+	tests := []struct {
+		keys     string
+		expected []string
+	}{
+		{"let a = 1\r", []string{"let a = 1"}},
+		{"1 + 3" + left + left + left + "\x7f2\r", []string{"12+ 3"}},
+		{"bc" + home + "a" + end + "d\r", []string{"abcd"}},
+		{"abc" + left + left + del + "\r", []string{"ac"}},
+		{"abc\x01\x0b\rxyz\x02\x15\r", []string{"", "z"}},
+		{"one\rtwo\r" + up + up + "!\r", []string{"one", "two", "one!"}},
+		{"one\rtw" + up + down + "o\r", []string{"one", "two"}},
+		{"fib\t(1)\r", []string{"fibonacci(1)"}},
+		{"fi\tr\t\r", []string{"first"}},
+		{"héllo" + left + "\x7f\r", []string{"hélo"}},
+		{"unfinished", []string{"unfinished"}},
+	}
+
+	for _, tt := range tests {
+		lines, _ := readLines(tt.keys, &History{})
+		assert.Equal(t, tt.expected, lines, "%q", tt.keys)
+	}
+
+	// ctrl-c drops the line, ctrl-d on an empty line ends the input
+	lines, errs := readLines("abc\x03def\r\x04more\r", &History{})
+	assert.Equal(t, []string{"", "def"}, lines)
+	assert.Equal(t, []error{ErrInterrupted, nil}, errs)
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	h, err := LoadHistory(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	readLines("let a = 1\r\rlet a = 1\rputs(a)\r", h)
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "let a = 1\nputs(a)\n", string(content))
+
+	// a new session sees the lines of the previous ones
+	h, err = LoadHistory(path)
+	assert.NoError(t, err)
+	lines, _ := readLines("\x1b[A\x1b[A\r", h)
+	assert.Equal(t, []string{"let a = 1"}, lines)
+}
+
+func TestStart(t *testing.T) {
+	input := `let add = fn(a, b) {
+  a + b
+}
+add(1,
+  2)
+let s = "two
+lines"
+:nope
+`
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	output := out.String()
+	// the function and the call span lines, commands do not
+	assert.True(t, strings.HasPrefix(output, ">>....>>..3\n>>..>>"), output)
+	assert.Contains(t, output, "invalid command: nope")
+}
+
+func TestCommandInIncompleteInput(t *testing.T) {
+	input := `let s = "open
+:nope
+:type s
+let f = fn() {
+:exit
+"never read"
+`
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+	output := out.String()
+
+	// :nope is part of the string, :type drops it and :exit leaves from inside the function
+	assert.Contains(t, output, "identifier not found: s")
+	assert.NotContains(t, output, "invalid command")
+	assert.NotContains(t, output, "never read")
+}
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script.mk")
+	saved := filepath.Join(dir, "saved.mk")
+	err := os.WriteFile(script, []byte("let double = fn(x) { x * 2 }\n"), 0644)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	input := ":load " + script + `
+let a = double(21)
+:env
+:type a
+:type "s"
+:type let b = 1
+:time a + 1
+:ast 1 + 2 * 3
+:tokens let x
+:save ` + saved + `
+:reset
+:type a
+:load
+:help
+:exit
+never read
+`
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+	output := out.String()
+
+	assert.Contains(t, output, ">a = 42 \n>double = ")
+	assert.Contains(t, output, ">>Integer\n")
+	assert.Contains(t, output, ">>String\n")
+	assert.Contains(t, output, ">>Nil\n")
+	assert.Contains(t, output, "43\ntime: ")
+	assert.Contains(t, output, "*ast.ExpressionStatement (1 + (2 * 3))\n")
+	assert.Contains(t, output, "1:1\tLET\t\"let\"\n1:5\tIDENT\t\"x\"\n")
+	assert.Contains(t, output, "identifier not found: a")
+	assert.Contains(t, output, "usage: :load <file>\n")
+	assert.Contains(t, output, ":save [file]")
+	assert.NotContains(t, output, "never read")
+
+	content, err := os.ReadFile(saved)
+	assert.NoError(t, err)
+	assert.Equal(t, "let double = fn(x) { x * 2 }\n\nlet a = double(21)\na\n\"s\"\nlet b = 1\na + 1\n", string(content))
+}
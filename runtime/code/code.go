@@ -0,0 +1,258 @@
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Instructions is the encoded bytecode of a function, each instruction is
+// an Opcode followed by its operands in big endian.
+type Instructions []byte
+
+type Opcode byte
+
+const (
+	// OpConstant pushes constant[u16]
+	OpConstant Opcode = iota
+	// OpPop discards the top of the stack
+	OpPop
+
+	OpTrue
+	OpFalse
+	OpNil
+
+	// infix operators, pop right then left and push the result
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpEqual
+	OpNotEqual
+	OpLessThan
+	OpGreaterThan
+
+	// prefix operators
+	OpMinus
+	OpBang
+
+	// OpJump moves ip to u16
+	OpJump
+	// OpJumpNotTruthy pops the condition and jumps to u16 when it is falsy
+	OpJumpNotTruthy
+
+	// OpGetGlobal and OpSetGlobal read and write global slot u16
+	OpGetGlobal
+	OpSetGlobal
+	// OpAssignGlobal pops into global slot u16 like OpSetGlobal, failing when
+	// the global was never bound
+	OpAssignGlobal
+	// OpGetLocal and OpSetLocal read and write slot u8 of the current frame
+	OpGetLocal
+	OpSetLocal
+	// OpGetFree and OpSetFree read and write captured variable u8 of the running closure
+	OpGetFree
+	OpSetFree
+
+	// OpArray collects the top u16 elements into an array
+	OpArray
+	// OpHash collects the top u16 elements, alternating key and value, into a hash
+	OpHash
+	// OpIndex pops index then the indexed object and pushes obj[index]
+	OpIndex
+	// OpSetIndex pops value, index and the indexed object, stores the value
+	// at obj[index] and pushes it. A non zero u8 is the infix opcode of a
+	// compound assignment combining the current element with the value.
+	OpSetIndex
+
+	// OpCall calls the function below its u8 arguments
+	OpCall
+	// OpReturnValue returns the top of the stack from the current function
+	OpReturnValue
+	// OpReturn returns from the current function without a value
+	OpReturn
+	// OpClosure wraps the compiled function constant[u16] into a closure
+	OpClosure
+
+	// OpIter pops an iterable and pushes an iterator over it
+	OpIter
+	// OpIterNext advances the iterator on top of the stack and pushes the
+	// u8 loop variables it yields, or jumps to u16 once it is exhausted
+	OpIterNext
+	// OpLoop marks the start of a loop, recording the height of the stack
+	OpLoop
+	// OpLoopEnd marks the end of the innermost loop
+	OpLoopEnd
+	// OpJumpLoop drops what was pushed since the innermost loop started and
+	// jumps to u16, for break and continue
+	OpJumpLoop
+	// OpTry starts a try block, an error raised before the matching OpEndTry
+	// unwinds the stack to its height, pushes the error value and jumps to u16
+	OpTry
+	// OpEndTry ends the innermost try block
+	OpEndTry
+	// OpThrow pops a value and raises it as an error
+	OpThrow
+	// OpDefault jumps to u16 when the parameter in local u8 was passed,
+	// the instructions before the target set its default value
+	OpDefault
+	// OpSpread replaces the iterable on top of the stack with its elements,
+	// which OpCallSpread passes as separate arguments
+	OpSpread
+	// OpCallSpread is OpCall with some of the u8 arguments spread
+	OpCallSpread
+)
+
+// Definition describes an opcode for encoding and disassembling.
+type Definition struct {
+	Name string
+	// OperandWidths is the width in bytes of each operand
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant:      {"OpConstant", []int{2}},
+	OpPop:           {"OpPop", []int{}},
+	OpTrue:          {"OpTrue", []int{}},
+	OpFalse:         {"OpFalse", []int{}},
+	OpNil:           {"OpNil", []int{}},
+	OpAdd:           {"OpAdd", []int{}},
+	OpSub:           {"OpSub", []int{}},
+	OpMul:           {"OpMul", []int{}},
+	OpDiv:           {"OpDiv", []int{}},
+	OpEqual:         {"OpEqual", []int{}},
+	OpNotEqual:      {"OpNotEqual", []int{}},
+	OpLessThan:      {"OpLessThan", []int{}},
+	OpGreaterThan:   {"OpGreaterThan", []int{}},
+	OpMinus:         {"OpMinus", []int{}},
+	OpBang:          {"OpBang", []int{}},
+	OpJump:          {"OpJump", []int{2}},
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+	OpGetGlobal:     {"OpGetGlobal", []int{2}},
+	OpSetGlobal:     {"OpSetGlobal", []int{2}},
+	OpAssignGlobal:  {"OpAssignGlobal", []int{2}},
+	OpGetLocal:      {"OpGetLocal", []int{1}},
+	OpSetLocal:      {"OpSetLocal", []int{1}},
+	OpGetFree:       {"OpGetFree", []int{1}},
+	OpSetFree:       {"OpSetFree", []int{1}},
+	OpArray:         {"OpArray", []int{2}},
+	OpHash:          {"OpHash", []int{2}},
+	OpIndex:         {"OpIndex", []int{}},
+	OpSetIndex:      {"OpSetIndex", []int{1}},
+	OpCall:          {"OpCall", []int{1}},
+	OpReturnValue:   {"OpReturnValue", []int{}},
+	OpReturn:        {"OpReturn", []int{}},
+	OpClosure:       {"OpClosure", []int{2}},
+	OpIter:          {"OpIter", []int{}},
+	OpIterNext:      {"OpIterNext", []int{2, 1}},
+	OpLoop:          {"OpLoop", []int{}},
+	OpLoopEnd:       {"OpLoopEnd", []int{}},
+	OpJumpLoop:      {"OpJumpLoop", []int{2}},
+
+	OpTry:    {"OpTry", []int{2}},
+	OpEndTry: {"OpEndTry", []int{}},
+	OpThrow:  {"OpThrow", []int{}},
+
+	OpDefault:    {"OpDefault", []int{1, 2}},
+	OpSpread:     {"OpSpread", []int{}},
+	OpCallSpread: {"OpCallSpread", []int{1}},
+}
+
+func Lookup(op Opcode) (*Definition, error) {
+	def, ok := definitions[op]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+	return def, nil
+}
+
+// Make encodes op and its operands into a single instruction.
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	length := 1
+	for _, w := range def.OperandWidths {
+		length += w
+	}
+
+	ins := make([]byte, length)
+	ins[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		width := def.OperandWidths[i]
+		switch width {
+		case 2:
+			binary.BigEndian.PutUint16(ins[offset:], uint16(o))
+		case 1:
+			ins[offset] = byte(o)
+		}
+		offset += width
+	}
+	return ins
+}
+
+// ReadOperands decodes the operands of an instruction described by def,
+// returning them along with the number of bytes read.
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+		offset += width
+	}
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+func ReadUint8(ins Instructions) uint8 {
+	return uint8(ins[0])
+}
+
+// String disassembles the instructions, one per line prefixed by its offset.
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(Opcode(ins[i]))
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i += 1
+			continue
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+		fmt.Fprintf(&out, "%04d %s\n", i, ins.fmtInstruction(def, operands))
+		i += 1 + read
+	}
+	return out.String()
+}
+
+func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
+	if len(operands) != len(def.OperandWidths) {
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n", len(operands), len(def.OperandWidths))
+	}
+
+	switch len(operands) {
+	case 0:
+		return def.Name
+	case 1:
+		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	}
+	return fmt.Sprintf("ERROR: unhandled operand count for %s\n", def.Name)
+}
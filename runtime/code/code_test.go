@@ -0,0 +1,86 @@
+package code
+
+import (
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+		{OpAdd, []int{}, []byte{byte(OpAdd)}},
+		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
+		{OpCall, []int{3}, []byte{byte(OpCall), 3}},
+		{OpIterNext, []int{258, 2}, []byte{byte(OpIterNext), 1, 2, 2}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Make(tt.op, tt.operands...), "encoded instruction")
+	}
+}
+
+func TestReadOperands(t *testing.T) {
+	tests := []struct {
+		op        Opcode
+		operands  []int
+		bytesRead int
+	}{
+		{OpConstant, []int{65535}, 2},
+		{OpGetLocal, []int{255}, 1},
+		{OpClosure, []int{65535}, 2},
+		{OpIterNext, []int{65535, 1}, 3},
+	}
+
+	for _, tt := range tests {
+		ins := Make(tt.op, tt.operands...)
+		def, err := Lookup(tt.op)
+		assert.NoError(t, err, "opcode must be defined")
+
+		operands, n := ReadOperands(def, ins[1:])
+		assert.Equal(t, tt.bytesRead, n, "bytes read")
+		assert.Equal(t, tt.operands, operands, "decoded operands")
+	}
+}
+
+func TestInstructionsString(t *testing.T) {
+	instructions := []Instructions{
+		Make(OpAdd),
+		Make(OpGetLocal, 1),
+		Make(OpConstant, 2),
+		Make(OpConstant, 65535),
+		Make(OpCall, 2),
+		Make(OpIterNext, 3, 1),
+	}
+
+	expected := `0000 OpAdd
+0001 OpGetLocal 1
+0003 OpConstant 2
+0006 OpConstant 65535
+0009 OpCall 2
+0011 OpIterNext 3 1
+`
+	concatted := Instructions{}
+	for _, ins := range instructions {
+		concatted = append(concatted, ins...)
+	}
+	assert.Equal(t, expected, concatted.String())
+}
+
+func TestPosTable(t *testing.T) {
+	a := token.Position{Offset: 0, Line: 1, Column: 1}
+	b := token.Position{Offset: 4, Line: 1, Column: 5}
+
+	table := PosTable{}.Add(0, a).Add(3, a).Add(3, b).Add(5, a)
+
+	assert.Equal(t, 3, len(table), "unchanged positions must not be recorded")
+	assert.Equal(t, a, table.Lookup(0))
+	assert.Equal(t, a, table.Lookup(2))
+	assert.Equal(t, b, table.Lookup(4))
+	assert.Equal(t, a, table.Lookup(9))
+}
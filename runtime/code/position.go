@@ -0,0 +1,38 @@
+package code
+
+import "github.com/NishanthSpShetty/monkey/token"
+
+// PosEntry marks that instructions from Offset onwards were compiled from source at Pos.
+type PosEntry struct {
+	Offset int
+	Pos    token.Position
+}
+
+// PosTable maps instruction offsets back to source positions, entries are in offset order.
+type PosTable []PosEntry
+
+// Add records pos for the instruction at offset, skipping it when nothing changed.
+func (t PosTable) Add(offset int, pos token.Position) PosTable {
+	if n := len(t); n > 0 {
+		if t[n-1].Pos == pos {
+			return t
+		}
+		if t[n-1].Offset == offset {
+			t[n-1].Pos = pos
+			return t
+		}
+	}
+	return append(t, PosEntry{Offset: offset, Pos: pos})
+}
+
+// Lookup returns the source position of the instruction at offset.
+func (t PosTable) Lookup(offset int) token.Position {
+	pos := token.Position{}
+	for _, e := range t {
+		if e.Offset > offset {
+			break
+		}
+		pos = e.Pos
+	}
+	return pos
+}
@@ -0,0 +1,782 @@
+package compiler
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/runtime/code"
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+// placeholder operand of jumps which are patched once the target is known
+const placeholder = 9999
+
+var infixOperators = map[string]code.Opcode{
+	"+":  code.OpAdd,
+	"-":  code.OpSub,
+	"*":  code.OpMul,
+	"/":  code.OpDiv,
+	"==": code.OpEqual,
+	"!=": code.OpNotEqual,
+	"<":  code.OpLessThan,
+	">":  code.OpGreaterThan,
+}
+
+var prefixOperators = map[string]code.Opcode{
+	"-": code.OpMinus,
+	"!": code.OpBang,
+}
+
+// Bytecode is the output of the compiler, ready to be run by the vm.
+type Bytecode struct {
+	Instructions code.Instructions
+	Positions    code.PosTable
+	Constants    []runtime.Object
+	// Globals holds the name of every global slot
+	Globals []string
+}
+
+// Error is an error in the program being compiled, at Pos in the source.
+type Error struct {
+	Pos     token.Position
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// errorf returns an Error at the node being compiled.
+func (c *Compiler) errorf(format string, a ...interface{}) error {
+	return &Error{Pos: c.pos, Message: fmt.Sprintf(format, a...)}
+}
+
+// compilationScope holds the instructions of the function being compiled.
+type compilationScope struct {
+	instructions code.Instructions
+	positions    code.PosTable
+	// loops are the loops around the statement being compiled, innermost last
+	loops []*loop
+	// tries are the try blocks around the statement being compiled, innermost last
+	tries []tryBlock
+}
+
+// loop collects the jumps of break and continue statements in a loop body.
+type loop struct {
+	// continueTarget is where continue jumps to, known before the body is compiled
+	continueTarget int
+	// breaks are the offsets of the jumps to patch with the end of the loop
+	breaks []int
+}
+
+// tryBlock is a try body, or a catch body followed by a finally, which has
+// to be left with an OpEndTry and its finally run when a statement jumps out.
+type tryBlock struct {
+	finally *ast.BlockStatement
+	// loops is the number of loops around the try
+	loops int
+}
+
+type Compiler struct {
+	constants   []runtime.Object
+	symbolTable *SymbolTable
+
+	scopes     []compilationScope
+	scopeIndex int
+	// pos is the position of the node being compiled, recorded for every emitted instruction
+	pos token.Position
+}
+
+func New() *Compiler {
+	return NewWithState(NewSymbolTable(), []runtime.Object{})
+}
+
+// NewWithState creates a compiler which carries on from the globals and constants
+// of a previous compilation, as a REPL session does.
+func NewWithState(s *SymbolTable, constants []runtime.Object) *Compiler {
+	return &Compiler{
+		constants:   constants,
+		symbolTable: s,
+		scopes:      []compilationScope{{}},
+	}
+}
+
+// SymbolTable returns the global symbol table, to be passed to the next compiler of a session.
+func (c *Compiler) SymbolTable() *SymbolTable {
+	return c.symbolTable
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Positions:    c.scopes[c.scopeIndex].positions,
+		Constants:    c.constants,
+		Globals:      c.symbolTable.GlobalNames(),
+	}
+}
+
+func (c *Compiler) Compile(node ast.Node) error {
+	if node == nil {
+		return c.errorf("cannot compile missing node")
+	}
+	prev := c.pos
+	c.pos = node.Pos()
+	defer func() { c.pos = prev }()
+
+	switch node := node.(type) {
+	case *ast.Program:
+		return c.compileProgram(node)
+
+	case *ast.ExpressionStatement:
+		if err := c.Compile(node.Expression); err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+
+	case *ast.LetStatement:
+		return c.compileLet(node)
+
+	case *ast.ReturnStatement:
+		if err := c.Compile(node.ReturnValue); err != nil {
+			return err
+		}
+		// the value is computed before the finally blocks run
+		if err := c.leaveTries(0); err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+
+	case *ast.BlockStatement:
+		return c.compileBlock(node.Statements)
+
+	case *ast.WhileStatement:
+		return c.compileWhile(node)
+
+	case *ast.ForStatement:
+		return c.compileFor(node)
+
+	case *ast.BranchStatement:
+		return c.compileBranch(node)
+
+	case *ast.TryStatement:
+		return c.compileTry(node)
+
+	case *ast.ThrowStatement:
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.emit(code.OpThrow)
+
+	case *ast.Identifier:
+		c.loadSymbol(c.symbolTable.Resolve(node.Value))
+
+	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			c.emit(code.OpConstant, c.addConstant(&runtime.BigInt{Value: node.Big}))
+			break
+		}
+		c.emit(code.OpConstant, c.addConstant(&runtime.Integer{Value: node.Value}))
+
+	case *ast.FloatLiteral:
+		c.emit(code.OpConstant, c.addConstant(&runtime.Float{Value: node.Value}))
+
+	case *ast.StringLiteral:
+		c.emit(code.OpConstant, c.addConstant(&runtime.String{Value: node.Value}))
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.PrefixExpression:
+		op, ok := prefixOperators[node.Operator]
+		if !ok {
+			return c.errorf("unknown operator %s", node.Operator)
+		}
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+		c.emit(op)
+
+	case *ast.InfixExpression:
+		op, ok := infixOperators[node.Operator]
+		if !ok {
+			return c.errorf("unknown operator %s", node.Operator)
+		}
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+		c.emit(op)
+
+	case *ast.IfExpression:
+		return c.compileIf(node)
+
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			if err := c.Compile(el); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpArray, len(node.Elements))
+
+	case *ast.HashLiteral:
+		return c.compileHash(node)
+
+	case *ast.AssignExpression:
+		return c.compileAssign(node)
+
+	case *ast.IndexExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Index); err != nil {
+			return err
+		}
+		c.emit(code.OpIndex)
+
+	case *ast.FunctionLiteral:
+		return c.compileFunction(node, "")
+
+	case *ast.CallExpression:
+		if len(node.Arguments) > math.MaxUint8 {
+			return c.errorf("too many arguments in call: %d", len(node.Arguments))
+		}
+		if err := c.Compile(node.Function); err != nil {
+			return err
+		}
+		call := code.OpCall
+		for _, arg := range node.Arguments {
+			if spread, ok := arg.(*ast.SpreadExpression); ok {
+				if err := c.Compile(spread.Value); err != nil {
+					return err
+				}
+				c.emit(code.OpSpread)
+				call = code.OpCallSpread
+				continue
+			}
+			if err := c.Compile(arg); err != nil {
+				return err
+			}
+		}
+		c.emit(call, len(node.Arguments))
+
+	default:
+		return c.errorf("unknown node %T", node)
+	}
+
+	return nil
+}
+
+// compileProgram leaves the value of the last expression statement as the
+// result of the program, a program ending in a let has no result.
+func (c *Compiler) compileProgram(program *ast.Program) error {
+	for i, st := range program.Statements {
+		es, ok := st.(*ast.ExpressionStatement)
+		if !ok || i != len(program.Statements)-1 {
+			if err := c.Compile(st); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := c.Compile(es.Expression); err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+		return nil
+	}
+	c.emit(code.OpReturn)
+	return nil
+}
+
+// compileBlock leaves exactly one value on the stack, the value of the last
+// statement in the block or nil.
+func (c *Compiler) compileBlock(statements []ast.Statement) error {
+	if len(statements) == 0 {
+		c.emit(code.OpNil)
+		return nil
+	}
+
+	last := len(statements) - 1
+	for i, st := range statements {
+		es, ok := st.(*ast.ExpressionStatement)
+		if !ok {
+			if err := c.Compile(st); err != nil {
+				return err
+			}
+			if i == last {
+				c.emit(code.OpNil)
+			}
+			continue
+		}
+
+		if err := c.Compile(es.Expression); err != nil {
+			return err
+		}
+		if i != last {
+			c.emit(code.OpPop)
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) compileLet(node *ast.LetStatement) error {
+	var sym Symbol
+	fn, isFn := node.Value.(*ast.FunctionLiteral)
+	if isFn {
+		// define the name first so the function body can refer to itself
+		sym = c.symbolTable.Define(node.Name.Value)
+		if err := c.compileFunction(fn, node.Name.Value); err != nil {
+			return err
+		}
+	} else {
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		sym = c.symbolTable.Define(node.Name.Value)
+	}
+
+	return c.setSymbol(sym)
+}
+
+// setSymbol pops the top of the stack into the variable sym.
+func (c *Compiler) setSymbol(sym Symbol) error {
+	switch sym.Scope {
+	case GlobalScope:
+		if sym.Index > math.MaxUint16 {
+			return c.errorf("too many globals")
+		}
+		c.emit(code.OpSetGlobal, sym.Index)
+	default:
+		if sym.Index > math.MaxUint8 {
+			return c.errorf("too many local bindings in function")
+		}
+		c.emit(code.OpSetLocal, sym.Index)
+	}
+	return nil
+}
+
+func (c *Compiler) compileIf(node *ast.IfExpression) error {
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+
+	jumpNotTruthy := c.emit(code.OpJumpNotTruthy, placeholder)
+	if err := c.Compile(node.Consequence); err != nil {
+		return err
+	}
+	jump := c.emit(code.OpJump, placeholder)
+
+	c.changeOperand(jumpNotTruthy, len(c.currentInstructions()))
+	if node.Alternative == nil {
+		c.emit(code.OpNil)
+	} else if err := c.Compile(node.Alternative); err != nil {
+		return err
+	}
+	c.changeOperand(jump, len(c.currentInstructions()))
+	return nil
+}
+
+// compileWhile compiles
+//
+//	OpLoop
+//	start: <condition>
+//	OpJumpNotTruthy end
+//	<body> OpPop
+//	OpJump start
+//	end: OpLoopEnd
+func (c *Compiler) compileWhile(node *ast.WhileStatement) error {
+	c.emit(code.OpLoop)
+	start := len(c.currentInstructions())
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+	jumpNotTruthy := c.emit(code.OpJumpNotTruthy, placeholder)
+
+	if err := c.compileLoopBody(node.Body, start); err != nil {
+		return err
+	}
+	c.emit(code.OpJump, start)
+
+	end := len(c.currentInstructions())
+	c.changeOperand(jumpNotTruthy, end)
+	c.endLoop(end)
+	return nil
+}
+
+// compileFor compiles
+//
+//	<iterable> OpIter
+//	OpLoop
+//	next: OpIterNext end vars
+//	<set variables>
+//	<body> OpPop
+//	OpJump next
+//	end: OpLoopEnd
+//	OpPop
+func (c *Compiler) compileFor(node *ast.ForStatement) error {
+	if err := c.Compile(node.Iterable); err != nil {
+		return err
+	}
+	// a value that cannot be iterated is reported at the iterable
+	c.pos = node.Iterable.Pos()
+	c.emit(code.OpIter)
+	c.pos = node.Pos()
+	c.emit(code.OpLoop)
+
+	vars := 1
+	if node.Key != nil {
+		vars = 2
+	}
+	next := c.emit(code.OpIterNext, placeholder, vars)
+	// the variables live in the scope around the loop, like a let in its body,
+	// the value is on top of the stack
+	if err := c.setSymbol(c.symbolTable.Define(node.Value.Value)); err != nil {
+		return err
+	}
+	if node.Key != nil {
+		if err := c.setSymbol(c.symbolTable.Define(node.Key.Value)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.compileLoopBody(node.Body, next); err != nil {
+		return err
+	}
+	c.emit(code.OpJump, next)
+
+	end := len(c.currentInstructions())
+	c.changeOperand(next, end, vars)
+	c.endLoop(end)
+	// the iterator
+	c.emit(code.OpPop)
+	return nil
+}
+
+// compileLoopBody compiles the body of a loop whose continue statements jump to continueTarget.
+func (c *Compiler) compileLoopBody(body *ast.BlockStatement, continueTarget int) error {
+	scope := &c.scopes[c.scopeIndex]
+	scope.loops = append(scope.loops, &loop{continueTarget: continueTarget})
+
+	if err := c.Compile(body); err != nil {
+		return err
+	}
+	c.emit(code.OpPop)
+	return nil
+}
+
+// endLoop patches the breaks of the innermost loop to jump to end and closes the loop.
+func (c *Compiler) endLoop(end int) {
+	scope := &c.scopes[c.scopeIndex]
+	l := scope.loops[len(scope.loops)-1]
+	scope.loops = scope.loops[:len(scope.loops)-1]
+
+	for _, pos := range l.breaks {
+		c.changeOperand(pos, end)
+	}
+	c.emit(code.OpLoopEnd)
+}
+
+func (c *Compiler) compileBranch(node *ast.BranchStatement) error {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return c.errorf("%s outside of a loop", node.Token.Literal)
+	}
+	l := loops[len(loops)-1]
+	if err := c.leaveTries(len(loops)); err != nil {
+		return err
+	}
+
+	if node.Token.Type == token.BREAK {
+		l.breaks = append(l.breaks, c.emit(code.OpJumpLoop, placeholder))
+	} else {
+		c.emit(code.OpJumpLoop, l.continueTarget)
+	}
+	return nil
+}
+
+// compileTry compiles
+//
+//	OpTry catch
+//	<body> OpPop
+//	OpEndTry
+//	<finally> OpPop
+//	OpJump end
+//	catch: <set the variable or OpPop>
+//	OpTry rethrow
+//	<catch body> OpPop
+//	OpEndTry
+//	<finally> OpPop
+//	OpJump end
+//	rethrow: <finally> OpPop
+//	OpThrow
+//	end:
+//
+// without a finally the catch body is not in a try block of its own, and
+// without a catch an error goes straight to rethrow.
+func (c *Compiler) compileTry(node *ast.TryStatement) error {
+	block := tryBlock{finally: node.Finally, loops: len(c.scopes[c.scopeIndex].loops)}
+
+	try := c.emit(code.OpTry, placeholder)
+	if err := c.compileTryBody(node.Body, block); err != nil {
+		return err
+	}
+	ends := []int{c.emit(code.OpJump, placeholder)}
+	c.changeOperand(try, len(c.currentInstructions()))
+
+	if node.Catch != nil {
+		// the error value is on top of the stack, it is bound in the scope
+		// around the try like a let in the catch
+		if node.Param != nil {
+			if err := c.setSymbol(c.symbolTable.Define(node.Param.Value)); err != nil {
+				return err
+			}
+		} else {
+			c.emit(code.OpPop)
+		}
+		if node.Finally == nil {
+			if err := c.Compile(node.Catch); err != nil {
+				return err
+			}
+			c.emit(code.OpPop)
+			c.changeOperand(ends[0], len(c.currentInstructions()))
+			return nil
+		}
+
+		try = c.emit(code.OpTry, placeholder)
+		if err := c.compileTryBody(node.Catch, block); err != nil {
+			return err
+		}
+		ends = append(ends, c.emit(code.OpJump, placeholder))
+		c.changeOperand(try, len(c.currentInstructions()))
+	}
+
+	// an error left the try or the catch body, it is raised again after the finally
+	if err := c.compileFinally(node.Finally); err != nil {
+		return err
+	}
+	c.emit(code.OpThrow)
+
+	for _, end := range ends {
+		c.changeOperand(end, len(c.currentInstructions()))
+	}
+	return nil
+}
+
+// compileTryBody compiles body inside the try block, followed by the finally of block.
+func (c *Compiler) compileTryBody(body *ast.BlockStatement, block tryBlock) error {
+	scope := &c.scopes[c.scopeIndex]
+	scope.tries = append(scope.tries, block)
+	err := c.Compile(body)
+	// compiling a function literal may have moved the scopes
+	scope = &c.scopes[c.scopeIndex]
+	scope.tries = scope.tries[:len(scope.tries)-1]
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpPop)
+	c.emit(code.OpEndTry)
+	return c.compileFinally(block.finally)
+}
+
+func (c *Compiler) compileFinally(finally *ast.BlockStatement) error {
+	if finally == nil {
+		return nil
+	}
+	if err := c.Compile(finally); err != nil {
+		return err
+	}
+	c.emit(code.OpPop)
+	return nil
+}
+
+// leaveTries ends the try blocks inside the innermost loops loops, running
+// their finally blocks, before a statement jumps out of them.
+func (c *Compiler) leaveTries(loops int) error {
+	tries := c.scopes[c.scopeIndex].tries
+	// the finally blocks run outside of the tries they belong to
+	defer func() { c.scopes[c.scopeIndex].tries = tries }()
+
+	for i := len(tries) - 1; i >= 0 && tries[i].loops >= loops; i-- {
+		c.scopes[c.scopeIndex].tries = tries[:i]
+		c.emit(code.OpEndTry)
+		if err := c.compileFinally(tries[i].finally); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) compileHash(node *ast.HashLiteral) error {
+	for _, pair := range node.Pairs {
+		if err := c.Compile(pair.Key); err != nil {
+			return err
+		}
+		if err := c.Compile(pair.Value); err != nil {
+			return err
+		}
+	}
+	c.emit(code.OpHash, len(node.Pairs)*2)
+	return nil
+}
+
+func (c *Compiler) compileFunction(node *ast.FunctionLiteral, name string) error {
+	c.enterScope()
+
+	params := make([]Symbol, len(node.Parameters))
+	for i, p := range node.Parameters {
+		params[i] = c.symbolTable.Define(p.Value)
+	}
+	if node.Rest != nil {
+		c.symbolTable.Define(node.Rest.Value)
+	}
+	// the parameters which were not passed get their defaults
+	for i, p := range params {
+		def := node.Default(i)
+		if def == nil {
+			continue
+		}
+		jump := c.emit(code.OpDefault, p.Index, placeholder)
+		if err := c.Compile(def); err != nil {
+			return err
+		}
+		c.emit(code.OpSetLocal, p.Index)
+		c.changeOperand(jump, p.Index, len(c.currentInstructions()))
+	}
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+	c.emit(code.OpReturnValue)
+
+	free := c.symbolTable.Free
+	numLocals := c.symbolTable.NumDefinitions()
+	if numLocals > math.MaxUint8+1 {
+		return c.errorf("too many local bindings in function")
+	}
+	scope := c.leaveScope()
+
+	fn := &runtime.CompiledFunction{
+		Instructions:  scope.instructions,
+		Positions:     scope.positions,
+		NumLocals:     numLocals,
+		NumParameters: len(node.Parameters),
+		NumRequired:   node.Required(),
+		Variadic:      node.Rest != nil,
+		Free:          free,
+		Name:          name,
+		File:          node.File,
+	}
+	c.emit(code.OpClosure, c.addConstant(fn))
+	return nil
+}
+
+// compileAssign compiles an assignment, which leaves the assigned value on the stack.
+func (c *Compiler) compileAssign(node *ast.AssignExpression) error {
+	var op code.Opcode
+	if node.Operator != "=" {
+		// "+=" applies "+"
+		var ok bool
+		op, ok = infixOperators[strings.TrimSuffix(node.Operator, "=")]
+		if !ok {
+			return c.errorf("unknown operator %s", node.Operator)
+		}
+	}
+
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		sym := c.symbolTable.Resolve(target.Value)
+		if op != 0 {
+			c.loadSymbol(sym)
+		}
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		if op != 0 {
+			c.emit(op)
+		}
+
+		switch sym.Scope {
+		case GlobalScope:
+			c.emit(code.OpAssignGlobal, sym.Index)
+		case LocalScope:
+			c.emit(code.OpSetLocal, sym.Index)
+		case FreeScope:
+			c.emit(code.OpSetFree, sym.Index)
+		}
+		c.loadSymbol(sym)
+
+	case *ast.IndexExpression:
+		if err := c.Compile(target.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(target.Index); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.emit(code.OpSetIndex, int(op))
+
+	default:
+		return c.errorf("cannot assign to %s", node.Target)
+	}
+	return nil
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	}
+}
+
+func (c *Compiler) addConstant(obj runtime.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+// emit appends an instruction to the current scope and returns its offset.
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	scope := &c.scopes[c.scopeIndex]
+	pos := len(scope.instructions)
+	scope.positions = scope.positions.Add(pos, c.pos)
+	scope.instructions = append(scope.instructions, code.Make(op, operands...)...)
+	return pos
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+// changeOperand rewrites the operands of the instruction at pos, used to patch jumps.
+func (c *Compiler) changeOperand(pos int, operands ...int) {
+	ins := c.currentInstructions()
+	op := code.Opcode(ins[pos])
+	copy(ins[pos:], code.Make(op, operands...))
+}
+
+func (c *Compiler) enterScope() {
+	c.scopes = append(c.scopes, compilationScope{})
+	c.scopeIndex++
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() compilationScope {
+	scope := c.scopes[c.scopeIndex]
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+	c.symbolTable = c.symbolTable.Outer
+	return scope
+}
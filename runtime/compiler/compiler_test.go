@@ -0,0 +1,424 @@
+package compiler
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/NishanthSpShetty/monkey/parser"
+	"github.com/NishanthSpShetty/monkey/runtime/code"
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+type compilerTestCase struct {
+	input                string
+	expectedConstants    []interface{}
+	expectedInstructions []code.Instructions
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "1 + 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			input:             "1; -2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpMinus),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			input:             "1 < 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpLessThan),
+				code.Make(code.OpReturnValue),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "if (true) { 10 }; 3333;",
+			expectedConstants: []interface{}{10, 3333},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 10),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpJump, 11),
+				// 0010
+				code.Make(code.OpNil),
+				// 0011
+				code.Make(code.OpPop),
+				// 0012
+				code.Make(code.OpConstant, 1),
+				// 0015
+				code.Make(code.OpReturnValue),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "while (true) { break; }",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpLoop),
+				// 0001
+				code.Make(code.OpTrue),
+				// 0002
+				code.Make(code.OpJumpNotTruthy, 13),
+				// 0005
+				code.Make(code.OpJumpLoop, 13),
+				// 0008
+				code.Make(code.OpNil),
+				// 0009
+				code.Make(code.OpPop),
+				// 0010
+				code.Make(code.OpJump, 1),
+				// 0013
+				code.Make(code.OpLoopEnd),
+				// 0014
+				code.Make(code.OpReturn),
+			},
+		},
+		{
+			input:             "for (x in [1]) { continue }",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpArray, 1),
+				// 0006
+				code.Make(code.OpIter),
+				// 0007
+				code.Make(code.OpLoop),
+				// 0008
+				code.Make(code.OpIterNext, 23, 1),
+				// 0012
+				code.Make(code.OpSetGlobal, 0),
+				// 0015
+				code.Make(code.OpJumpLoop, 8),
+				// 0018
+				code.Make(code.OpNil),
+				// 0019
+				code.Make(code.OpPop),
+				// 0020
+				code.Make(code.OpJump, 8),
+				// 0023
+				code.Make(code.OpLoopEnd),
+				// 0024
+				code.Make(code.OpPop),
+				// 0025
+				code.Make(code.OpReturn),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestTry(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "try { 1 } catch (e) { e }",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTry, 11),
+				// 0003
+				code.Make(code.OpConstant, 0),
+				// 0006
+				code.Make(code.OpPop),
+				// 0007
+				code.Make(code.OpEndTry),
+				// 0008
+				code.Make(code.OpJump, 18),
+				// 0011
+				code.Make(code.OpSetGlobal, 0),
+				// 0014
+				code.Make(code.OpGetGlobal, 0),
+				// 0017
+				code.Make(code.OpPop),
+				// 0018
+				code.Make(code.OpReturn),
+			},
+		},
+		{
+			// the finally is compiled for both ways out of the body
+			input:             "try { throw 1 } finally { 2 }",
+			expectedConstants: []interface{}{1, 2, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTry, 17),
+				// 0003
+				code.Make(code.OpConstant, 0),
+				// 0006
+				code.Make(code.OpThrow),
+				// 0007
+				code.Make(code.OpNil),
+				// 0008
+				code.Make(code.OpPop),
+				// 0009
+				code.Make(code.OpEndTry),
+				// 0010
+				code.Make(code.OpConstant, 1),
+				// 0013
+				code.Make(code.OpPop),
+				// 0014
+				code.Make(code.OpJump, 22),
+				// 0017
+				code.Make(code.OpConstant, 2),
+				// 0020
+				code.Make(code.OpPop),
+				// 0021
+				code.Make(code.OpThrow),
+				// 0022
+				code.Make(code.OpReturn),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestAssignments(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "let x = 1; x += 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpAssignGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			input:             "let a = [1]; a[0] *= 2",
+			expectedConstants: []interface{}{1, 0, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpSetIndex, int(code.OpMul)),
+				code.Make(code.OpReturnValue),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "let one = 1; let two = one;",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpReturn),
+			},
+		},
+		{
+			// unknown names are bound to a global slot and checked at runtime
+			input:             "len(x)",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpCall, 1),
+				code.Make(code.OpReturnValue),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestTooManyGlobals(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i <= math.MaxUint16+1; i++ {
+		// identifiers are letters only
+		name := []byte("v")
+		for n := i; n > 0; n /= 26 {
+			name = append(name, byte('a'+n%26))
+		}
+		fmt.Fprintf(&b, "let %s = %d;\n", name, i)
+	}
+	program := parser.New(lexer.New(b.String())).ParseProgram()
+
+	err := New().Compile(program)
+	cerr, ok := err.(*Error)
+	if !ok {
+		assert.Failf(t, "assert failed", "must be a compile error, got %v", err)
+		return
+	}
+	assert.Equal(t, "too many globals", cerr.Message)
+	assert.Equal(t, "65537:1", cerr.Pos.String(), "must point at the let binding one global too many")
+}
+
+func TestFunctions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: "fn(a) { let b = a; fn() { a + b } }",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpGetFree, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpSetLocal, 1),
+					code.Make(code.OpClosure, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			input: "fn(a, b = 2, ...c) { b }",
+			expectedConstants: []interface{}{
+				2,
+				[]code.Instructions{
+					// 0000
+					code.Make(code.OpDefault, 1, 9),
+					// 0004
+					code.Make(code.OpConstant, 0),
+					// 0007
+					code.Make(code.OpSetLocal, 1),
+					// 0009
+					code.Make(code.OpGetLocal, 1),
+					// 0011
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			input:             "f(1, ...xs)",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpSpread),
+				code.Make(code.OpCallSpread, 2),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			input:             "fn() { }",
+			expectedConstants: []interface{}{[]code.Instructions{code.Make(code.OpNil), code.Make(code.OpReturnValue)}},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0),
+				code.Make(code.OpReturnValue),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestClosureCaptures(t *testing.T) {
+	input := "fn(a) { fn(b) { fn(c) { a + b + c } } }"
+
+	c := New()
+	assert.NoError(t, c.Compile(parser.New(lexer.New(input)).ParseProgram()))
+
+	constants := c.Bytecode().Constants
+	inner := constants[0].(*runtime.CompiledFunction)
+	middle := constants[1].(*runtime.CompiledFunction)
+
+	assert.Equal(t, []runtime.FreeVar{{Local: false, Index: 0}, {Local: true, Index: 0}}, inner.Free,
+		"innermost function captures a through the middle one and b from its frame")
+	assert.Equal(t, []runtime.FreeVar{{Local: true, Index: 0}}, middle.Free)
+}
+
+func runCompilerTests(t *testing.T, tests []compilerTestCase) {
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		c := New()
+		err := c.Compile(program)
+		assert.NoErrorf(t, err, "compiling %q", tt.input)
+
+		bytecode := c.Bytecode()
+		assert.Equalf(t, concatInstructions(tt.expectedInstructions).String(), bytecode.Instructions.String(),
+			"instructions of %q", tt.input)
+		testConstants(t, tt.expectedConstants, bytecode.Constants)
+	}
+}
+
+func testConstants(t *testing.T, expected []interface{}, actual []runtime.Object) {
+	assert.Equal(t, len(expected), len(actual), "number of constants")
+	if len(expected) != len(actual) {
+		return
+	}
+
+	for i, constant := range expected {
+		switch constant := constant.(type) {
+		case int:
+			integer, ok := actual[i].(*runtime.Integer)
+			assert.Truef(t, ok, "constant %d must be Integer, got %T", i, actual[i])
+			if ok {
+				assert.Equal(t, int64(constant), integer.Value)
+			}
+		case []code.Instructions:
+			fn, ok := actual[i].(*runtime.CompiledFunction)
+			assert.Truef(t, ok, "constant %d must be CompiledFunction, got %T", i, actual[i])
+			if ok {
+				assert.Equal(t, concatInstructions(constant).String(), fn.Instructions.String())
+			}
+		}
+	}
+}
+
+func concatInstructions(s []code.Instructions) code.Instructions {
+	out := code.Instructions{}
+	for _, ins := range s {
+		out = append(out, ins...)
+	}
+	return out
+}
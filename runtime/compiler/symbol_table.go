@@ -0,0 +1,100 @@
+package compiler
+
+import "github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+
+type SymbolScope string
+
+const (
+	GlobalScope SymbolScope = "GLOBAL"
+	LocalScope  SymbolScope = "LOCAL"
+	FreeScope   SymbolScope = "FREE"
+)
+
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+// SymbolTable resolves names to slots, there is one table for the globals
+// and one per function literal being compiled.
+type SymbolTable struct {
+	Outer *SymbolTable
+	// Free lists the variables captured from enclosing functions, in slot order
+	Free []runtime.FreeVar
+
+	store          map[string]Symbol
+	numDefinitions int
+	// names of the global slots, only kept by the outermost table
+	names []string
+}
+
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{
+		store: map[string]Symbol{},
+	}
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// Define binds name in this table, a name defined again keeps its slot.
+func (s *SymbolTable) Define(name string) Symbol {
+	if sym, ok := s.store[name]; ok && sym.Scope != FreeScope {
+		return sym
+	}
+
+	sym := Symbol{Name: name, Index: s.numDefinitions, Scope: LocalScope}
+	if s.Outer == nil {
+		sym.Scope = GlobalScope
+		s.names = append(s.names, name)
+	}
+	s.store[name] = sym
+	s.numDefinitions++
+	return sym
+}
+
+// Resolve looks name up through the enclosing tables. Names that are not
+// bound anywhere resolve to a global slot, which is checked when the code
+// runs just like the evaluator looks identifiers up at runtime.
+func (s *SymbolTable) Resolve(name string) Symbol {
+	if sym, ok := s.store[name]; ok {
+		return sym
+	}
+	if s.Outer == nil {
+		return s.Define(name)
+	}
+
+	sym := s.Outer.Resolve(name)
+	if sym.Scope == GlobalScope {
+		return sym
+	}
+	return s.defineFree(sym)
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.Free = append(s.Free, runtime.FreeVar{
+		Local: original.Scope == LocalScope,
+		Index: original.Index,
+	})
+
+	sym := Symbol{Name: original.Name, Index: len(s.Free) - 1, Scope: FreeScope}
+	s.store[original.Name] = sym
+	return sym
+}
+
+// NumDefinitions is the number of slots defined in this table.
+func (s *SymbolTable) NumDefinitions() int {
+	return s.numDefinitions
+}
+
+// GlobalNames returns the names of the global slots in slot order.
+func (s *SymbolTable) GlobalNames() []string {
+	for s.Outer != nil {
+		s = s.Outer
+	}
+	return s.names
+}
@@ -0,0 +1,35 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	first := NewEnclosedSymbolTable(global)
+	first.Define("b")
+
+	second := NewEnclosedSymbolTable(first)
+	second.Define("c")
+
+	assert.Equal(t, Symbol{Name: "a", Scope: GlobalScope, Index: 0}, second.Resolve("a"))
+	assert.Equal(t, Symbol{Name: "b", Scope: FreeScope, Index: 0}, second.Resolve("b"))
+	assert.Equal(t, Symbol{Name: "c", Scope: LocalScope, Index: 0}, second.Resolve("c"))
+
+	// unknown names end up as globals to be checked at runtime
+	assert.Equal(t, Symbol{Name: "d", Scope: GlobalScope, Index: 1}, second.Resolve("d"))
+	assert.Equal(t, []string{"a", "d"}, second.GlobalNames())
+}
+
+func TestDefineKeepsSlot(t *testing.T) {
+	global := NewSymbolTable()
+	a := global.Define("a")
+	global.Define("b")
+
+	assert.Equal(t, a, global.Define("a"), "redefining must reuse the slot")
+	assert.Equal(t, 2, global.NumDefinitions())
+}
@@ -15,23 +15,220 @@ func TestBuiltins(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("héllo")`, 5},
+		{`byte_len("héllo")`, 6},
+		{`len("\u{1F600}")`, 1},
+		{`byte_len("\u{1F600}")`, 4},
+		{`byte_len(1)`, "argument 1 to `byte_len` must be String, got Integer"},
 
 		{`len(1)`, "argument to `len` not supported, got Integer"},
+
+		{`int(3.99)`, 3},
+		{`int(-3.99)`, -3},
+		{`int(" 42 ")`, 42},
+		{`int(7)`, 7},
+		{`int("4.2")`, "cannot convert \"4.2\" to Integer"},
+		{`int(1e308 * 10)`, "cannot convert +Inf to Integer"},
+		{`int(true)`, "argument to `int` not supported, got Boolean"},
+		{`float(2)`, 2.0},
+		{`float("2.5")`, 2.5},
+		{`float(0.5)`, 0.5},
+		{`float("x")`, "cannot convert \"x\" to Float"},
+		{`float(1, 2)`, "wrong number of arguments to `float`. got=2, want=1"},
+
+		{`len(range(5))`, 5},
+		{`len(range(2, 5))`, 3},
+		{`len(range(1, 10, 3))`, 3},
+		{`len(range(5, 0, -2))`, 3},
+		{`len(range(5, 0))`, 0},
+		{`len(range(0, 9223372036854775807, 4611686018427387904))`, 2},
+		{`len(range(9223372036854775807, 0, -4611686018427387904))`, 2},
+		{`len(range(-9223372036854775807, 9223372036854775807, 9223372036854775807))`, 2},
+		{`range(1, 2, 0)`, "range step must not be zero"},
+		{`range("5")`, "argument 1 to `range` must be Integer, got String"},
+		{`range()`, "wrong number of arguments to `range`. got=0, want=1 to 3"},
 	}
 
 	for _, tt := range tests {
-		eval := testEval(tt.input)
-
-		switch expected := tt.expected.(type) {
-		case int:
-			testIntegerObject(t, eval, int64(expected))
-		case string:
-			err, ok := eval.(*runtime.Error)
-			if !ok {
-				assert.Failf(t, "assert failed", "result must be error, got %T", eval)
-				continue
+		forEachBackend(t, tt.input, func(t *testing.T, eval runtime.Object) {
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, eval, int64(expected))
+			case float64:
+				testFloatObject(t, eval, expected)
+			case string:
+				err, ok := eval.(*runtime.Error)
+				if !ok {
+					assert.Failf(t, "assert failed", "result must be error, got %T", eval)
+					return
+				}
+				assert.Equal(t, expected, err.Message, "error message dint match")
 			}
-			assert.Equal(t, expected, err.Message, "error message dint match")
-		}
+		})
+	}
+}
+
+func TestCollectionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// more integers than an int64 holds
+		{`len(range(-9223372036854775807, 9223372036854775807))`, "18446744073709551614"},
+		{`len(range(-9223372036854775807, 9223372036854775807)) - len(range(0, 9223372036854775807))`, "9223372036854775807"},
+		{`let a = [1]; push(a, 2, 3); a`, "[1, 2, 3]"},
+		{`push([], "x")`, "[x]"},
+		{`push(1, 2)`, "Error: argument 1 to `push` must be Array, got Integer"},
+		{`push()`, "Error: wrong number of arguments to `push`. got=0, want=at least 1"},
+		{`let a = [1, 2]; let x = pop(a); [x, a]`, "[2, [1]]"},
+		{`pop([])`, "Nil"},
+		{`first([1, 2])`, "1"},
+		{`first([])`, "Nil"},
+		{`last([1, 2])`, "2"},
+		{`last([])`, "Nil"},
+		{`let a = [1, 2, 3]; let r = rest(a); push(r, 4); [a, r]`, "[[1, 2, 3], [2, 3, 4]]"},
+		{`rest([])`, "[]"},
+
+		{`slice([1, 2, 3, 4], 1)`, "[2, 3, 4]"},
+		{`slice([1, 2, 3, 4], 1, 3)`, "[2, 3]"},
+		{`slice([1, 2, 3, 4], -2)`, "[3, 4]"},
+		{`slice([1, 2, 3, 4], 0, -1)`, "[1, 2, 3]"},
+		{`slice([1, 2, 3, 4], 3, 1)`, "[]"},
+		{`slice([1, 2, 3, 4], -10, 10)`, "[1, 2, 3, 4]"},
+		{`slice("héllo", 1, 3)`, "él"},
+		{`slice({}, 1)`, "Error: argument 1 to `slice` must be Array or String, got Hash"},
+		{`slice([1], "1")`, "Error: argument 2 to `slice` must be Integer, got String"},
+		{`slice([1])`, "Error: wrong number of arguments to `slice`. got=1, want=2 to 3"},
+
+		{`let a = [1]; let c = concat(a, [2], [], [3, 4]); [a, c]`, "[[1], [1, 2, 3, 4]]"},
+		{`concat([1], 2)`, "Error: argument 2 to `concat` must be Array, got Integer"},
+
+		{`sorted(keys({"b": 1, "a": 2}))`, "[a, b]"},
+		{`sorted(values({"b": 1, "a": 2}))`, "[1, 2]"},
+		{`keys({})`, "[]"},
+		{`has({"a": 1}, "a")`, "true"},
+		{`has({"a": 1}, "b")`, "false"},
+		{`has({1: 1}, 1.0)`, "true"},
+		{`has({}, [1])`, "Error: unusable as hash key: Array"},
+		{`let h = {"a": 1, "b": 2}; let v = delete(h, "a"); [v, len(keys(h)), has(h, "a")]`, "[1, 1, false]"},
+		{`delete({}, "a")`, "Nil"},
+		{`let a = {"x": 1}; let m = merge(a, {"x": 2, "y": 3}); [a["x"], m["x"], m["y"], len(keys(a))]`, "[1, 2, 3, 1]"},
+		{`let a = {"x": 1}; update(a, {"x": 2}, {"y": 3}); [a["x"], a["y"]]`, "[2, 3]"},
+		{`merge({}, [])`, "Error: argument 2 to `merge` must be Hash, got Array"},
+
+		{`contains([1, "a", true], "a")`, "true"},
+		{`contains([1, 2], 2.0)`, "true"},
+		{`contains([1, 2], 3)`, "false"},
+		{`contains("monkey", "key")`, "true"},
+		{`contains("monkey", 1)`, "Error: argument 2 to `contains` must be String, got Integer"},
+		{`contains(1, 1)`, "Error: argument 1 to `contains` must be Array, Tuple or String, got Integer"},
+		{`index_of([5, 6, 7], 7)`, "2"},
+		{`index_of([5, 6, 7], 8)`, "-1"},
+		{`index_of("héllo", "llo")`, "2"},
+		{`index_of("hello", "z")`, "-1"},
+
+		{`let a = [1, 2, 3]; reverse(a); a`, "[3, 2, 1]"},
+		{`let a = [1, 2, 3]; let r = reversed(a); [a, r]`, "[[1, 2, 3], [3, 2, 1]]"},
+		{`reversed("héllo")`, "olléh"},
+
+		{`let a = [3, 1, 2]; sort(a); a`, "[1, 2, 3]"},
+		{`let a = [3, 1, 2]; let s = sorted(a); [a, s]`, "[[3, 1, 2], [1, 2, 3]]"},
+		{`sorted([2.5, 1, 9223372036854775808, -1])`, "[-1, 1, 2.5, 9223372036854775808]"},
+		{`sorted(["pear", "apple", "fig"])`, "[apple, fig, pear]"},
+		{`sorted([3, 1, 2], fn(a, b) { a > b })`, "[3, 2, 1]"},
+		{`sorted([3, 1, 2], fn(a, b) { b - a })`, "[3, 2, 1]"},
+		{`sorted(["bb", "a", "ccc"], fn(a, b) { len(a) < len(b) })`, "[a, bb, ccc]"},
+		{`let key = fn(x) { -x }; sorted([1, 3, 2], fn(a, b) { key(a) < key(b) })`, "[3, 2, 1]"},
+		{`sorted([[3, 1], [2]], fn(a, b) { first(sorted(a)) < first(sorted(b)) })`, "[[3, 1], [2]]"},
+		// sorting is stable
+		{`sorted([[2, "a"], [1, "b"], [2, "c"], [1, "d"]], fn(x, y) { x[0] < y[0] })`, "[[1, b], [1, d], [2, a], [2, c]]"},
+		{`sorted([1, "a"])`, "Error: cannot compare String with Integer"},
+		{`sorted([2, 1], fn(a, b) { a + true })`, "Error: type mismatch: Integer + Boolean"},
+		{`sorted([2, 1], fn(a, b) { "yes" })`, "Error: comparator must return Boolean or Integer, got String"},
+		{`sorted([2, 1], fn(a) { true })`, "Error: wrong number of arguments: want=1, got=2"},
+		{`sorted([2, 1], 3)`, "Error: argument 2 to `sorted` must be Function, got Integer"},
+		{`sort([2, 1], fn(a, b) { a < b }, 1)`, "Error: wrong number of arguments to `sort`. got=3, want=1 to 2"},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			assert.Equal(t, tt.expected, evaluated.Inspect(), tt.input)
+		})
+	}
+}
+
+func TestHigherOrderBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`map([1, 2, 3], fn(x) { x * 2 })`, "[2, 4, 6]"},
+		{`map(range(3), fn(x) { x * x })`, "[0, 1, 4]"},
+		{`map("abc", fn(c) { c + c })`, "[aa, bb, cc]"},
+		{`map([], fn(x) { x })`, "[]"},
+		{`map([-1, 2], int)`, "[-1, 2]"},
+		{`let n = 10; map([1, 2], fn(x) { x + n })`, "[11, 12]"},
+		{`map(1, fn(x) { x })`, "Error: argument 1 to `map` must be iterable, got Integer"},
+		{`map([1], 2)`, "Error: argument 2 to `map` must be Function, got Integer"},
+		{`map([1], fn(x) { x + "a" })`, "Error: type mismatch: Integer + String"},
+		{`map([1])`, "Error: wrong number of arguments to `map`. got=1, want=2"},
+
+		{`filter(range(10), fn(x) { x / 3 * 3 == x })`, "[0, 3, 6, 9]"},
+		{`filter(["a", "", "b"], fn(s) { len(s) > 0 })`, "[a, b]"},
+
+		{`reduce([1, 2, 3, 4], fn(acc, x) { acc + x })`, "10"},
+		{`reduce([1, 2, 3], fn(acc, x) { acc * x }, 10)`, "60"},
+		{`reduce([], fn(acc, x) { acc + x }, 0)`, "0"},
+		{`reduce(["a", "b"], fn(acc, x) { push(acc, x) }, [])`, "[a, b]"},
+		{`reduce([], fn(acc, x) { acc + x })`, "Error: `reduce` of an empty Array needs an initial value"},
+
+		{`let total = 0; each([1, 2, 3], fn(x) { total += x }); total`, "6"},
+		{`each([1], fn(x) { x })`, "Nil"},
+
+		{`find([1, 5, 8, 10], fn(x) { x > 4 })`, "5"},
+		{`find([1, 2], fn(x) { x > 4 })`, "Nil"},
+		{`find([1, 2], fn(x) { x + true })`, "Error: type mismatch: Integer + Boolean"},
+		{`let calls = 0; find([1, 2, 3], fn(x) { calls += 1; x == 2 }); calls`, "2"},
+
+		{`any([1, 2, 3], fn(x) { x > 2 })`, "true"},
+		{`any([1, 2, 3], fn(x) { x > 3 })`, "false"},
+		{`any([])`, "false"},
+		{`any([false, 0])`, "true"},
+		{`all([1, 2, 3], fn(x) { x > 0 })`, "true"},
+		{`all([1, 2, 3], fn(x) { x > 1 })`, "false"},
+		{`all([])`, "true"},
+		{`all([true, false])`, "false"},
+		{`let calls = 0; all([1, 2, 3], fn(x) { calls += 1; x < 2 }); calls`, "2"},
+		{`any([1], 1)`, "Error: argument 2 to `any` must be Function, got Integer"},
+
+		{`zip([1, 2, 3], ["a", "b", "c"])`, "[[1, a], [2, b], [3, c]]"},
+		{`zip([1, 2, 3], "ab", range(10, 20))`, "[[1, a, 10], [2, b, 11]]"},
+		{`zip([1, 2], [])`, "[]"},
+		{`zip([1], 2)`, "Error: argument 2 to `zip` must be iterable, got Integer"},
+
+		{`flat_map([1, 2], fn(x) { [x, x * 10] })`, "[1, 10, 2, 20]"},
+		{`flat_map([[1], [2, [3]]], fn(x) { x })`, "[1, 2, [3]]"},
+		{`flat_map([1, 2], fn(x) { x })`, "[1, 2]"},
+
+		{`let g = group_by(range(6), fn(x) { x / 2 * 2 == x }); [g[true], g[false]]`, "[[0, 2, 4], [1, 3, 5]]"},
+		{`let g = group_by(["apple", "avocado", "banana"], fn(s) { slice(s, 0, 1) }); [g["a"], g["b"], len(keys(g))]`, "[[apple, avocado], [banana], 2]"},
+		{`group_by([1], fn(x) { [x] })`, "Error: unusable as hash key: Array"},
+
+		{`sort_by(["ccc", "a", "bb"], len)`, "[a, bb, ccc]"},
+		{`sort_by([3, 1, 2], fn(x) { -x })`, "[3, 2, 1]"},
+		{`sort_by([[2, "x"], [1, "y"], [2, "z"]], first)`, "[[1, y], [2, x], [2, z]]"},
+		{`let calls = 0; sort_by([3, 1, 2, 5, 4], fn(x) { calls += 1; x }); calls`, "5"},
+		{`sort_by([1, 2], fn(x) { if (x == 1) { "a" } else { 1 } })`, "Error: cannot compare Integer with String"},
+
+		// callbacks nest and compose
+		{`map([[1, 2], [3]], fn(xs) { reduce(xs, fn(a, b) { a + b }) })`, "[3, 3]"},
+		{`let compose = fn(f, g) { fn(x) { f(g(x)) } }; map([1, 2], compose(fn(x) { x + 1 }, fn(x) { x * 10 }))`, "[11, 21]"},
+		{`let f = fn() { map([1, 2, 3], fn(x) { if (x == 2) { return 20; } x }) }; f()`, "[1, 20, 3]"},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			assert.Equal(t, tt.expected, evaluated.Inspect(), tt.input)
+		})
 	}
 }
@@ -1,21 +1,50 @@
 package evaluator
 
 import (
+	"context"
+	"strings"
+
 	"github.com/NishanthSpShetty/monkey/ast"
 	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/NishanthSpShetty/monkey/token"
 )
 
-func Eval(r *runtime.Runtime, node ast.Node) runtime.Object {
+// Eval evaluates node in r. The evaluation stops with an error whose Limit
+// is set when ctx is done or one of the limits of r is exceeded.
+func Eval(ctx context.Context, r *runtime.Runtime, node ast.Node) runtime.Object {
+	r.Budget().Start(ctx)
+	return evalNode(r, node)
+}
+
+func evalNode(r *runtime.Runtime, node ast.Node) runtime.Object {
+	var obj runtime.Object
+	if err := r.Budget().Step(); err != nil {
+		obj = err
+	} else {
+		obj = eval(r, node)
+	}
+	// the innermost node which failed gets to set the position
+	if err, ok := obj.(*runtime.Error); ok && !err.Pos.IsValid() && node != nil {
+		err.Pos = node.Pos()
+	}
+	return obj
+}
+
+func eval(r *runtime.Runtime, node ast.Node) runtime.Object {
 	switch node := node.(type) {
 	case *ast.Program:
 		return evalProgram(r, node)
 
 	case *ast.LetStatement:
-		val := Eval(r, node.Value)
+		val := evalNode(r, node.Value)
 
 		if runtime.IsError(val) {
 			return val
 		}
+		if _, ok := node.Value.(*ast.FunctionLiteral); ok {
+			// named like the compiler names it, for stack traces
+			val.(*runtime.Function).Name = node.Name.Value
+		}
 		r.Put(node.Name.Value, val)
 		return nil
 
@@ -24,37 +53,45 @@ func Eval(r *runtime.Runtime, node ast.Node) runtime.Object {
 		return evalIdentifier(r, node)
 
 	case *ast.ExpressionStatement:
-		return Eval(r, node.Expression)
+		return evalNode(r, node.Expression)
 
 	// expressions
 	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			return &runtime.BigInt{Value: node.Big}
+		}
 		return &runtime.Integer{
 			Value: node.Value,
 		}
 
+	case *ast.FloatLiteral:
+		return &runtime.Float{
+			Value: node.Value,
+		}
+
 	case *ast.Boolean:
-		return nativeBool(node.Value)
+		return runtime.NativeBool(node.Value)
 
 	case *ast.PrefixExpression:
-		right := Eval(r, node.Right)
+		right := evalNode(r, node.Right)
 		if runtime.IsError(right) {
 			return right
 		}
-		return evalPrefixExp(node.Operator, right)
+		return alloc(r, runtime.EvalPrefix(node.Operator, right))
 		// end
 
 	case *ast.InfixExpression:
 
-		left := Eval(r, node.Left)
+		left := evalNode(r, node.Left)
 		if runtime.IsError(left) {
 			return left
 		}
 
-		right := Eval(r, node.Right)
+		right := evalNode(r, node.Right)
 		if runtime.IsError(right) {
 			return right
 		}
-		return evalInfixOperator(node.Operator, left, right)
+		return alloc(r, runtime.EvalInfix(node.Operator, left, right))
 
 	case *ast.IfExpression:
 		return evaluateIfExpression(r, node)
@@ -62,8 +99,30 @@ func Eval(r *runtime.Runtime, node ast.Node) runtime.Object {
 	case *ast.BlockStatement:
 		return evalBlockStmnt(r, node)
 
+	case *ast.WhileStatement:
+		return evalWhileStmnt(r, node)
+
+	case *ast.ForStatement:
+		return evalForStmnt(r, node)
+
+	case *ast.BranchStatement:
+		if node.Token.Type == token.BREAK {
+			return runtime.BreakSignal
+		}
+		return runtime.ContinueSignal
+
+	case *ast.TryStatement:
+		return evalTryStmnt(r, node)
+
+	case *ast.ThrowStatement:
+		val := evalNode(r, node.Value)
+		if runtime.IsError(val) {
+			return val
+		}
+		return runtime.Throw(val)
+
 	case *ast.ReturnStatement:
-		val := Eval(r, node.ReturnValue)
+		val := evalNode(r, node.ReturnValue)
 
 		if runtime.IsError(val) {
 			return val
@@ -73,49 +132,55 @@ func Eval(r *runtime.Runtime, node ast.Node) runtime.Object {
 		}
 
 	case *ast.FunctionLiteral:
-		return &runtime.Function{
-			Params:  node.Parameters,
-			Body:    node.Body,
-			Runtime: r,
-		}
+		return alloc(r, &runtime.Function{
+			Params:   node.Parameters,
+			Defaults: node.Defaults,
+			Rest:     node.Rest,
+			Body:     node.Body,
+			Runtime:  r,
+			File:     node.File,
+		})
 
 	case *ast.CallExpression:
-		function := Eval(r, node.Function)
+		function := evalNode(r, node.Function)
 
 		if runtime.IsError(function) {
 			return function
 		}
 
-		args := evalExpression(r, node.Arguments)
-		if len(args) == 1 && runtime.IsError(args[0]) {
-			return args[0]
+		args, err := evalArguments(r, node.Arguments)
+		if err != nil {
+			return err
 		}
 
-		return applyFunction(function, args)
+		return applyFunction(r, node.Pos(), function, args)
 	case *ast.StringLiteral:
-		return &runtime.String{Value: node.Value}
+		return alloc(r, &runtime.String{Value: node.Value})
 
 	case *ast.ArrayLiteral:
 		ele := evalExpression(r, node.Elements)
 		if len(ele) == 1 && runtime.IsError(ele[0]) {
 			return ele[0]
 		}
-		return &runtime.Array{
+		return alloc(r, &runtime.Array{
 			Elements: ele,
-		}
+		})
+
+	case *ast.AssignExpression:
+		return evalAssignExpression(r, node)
 
 	case *ast.IndexExpression:
-		left := Eval(r, node.Left)
+		left := evalNode(r, node.Left)
 		if runtime.IsError(left) {
 			return left
 		}
 
-		idx := Eval(r, node.Index)
+		idx := evalNode(r, node.Index)
 		if runtime.IsError(idx) {
 			return idx
 		}
 
-		return evaluateIndexExpression(left, idx)
+		return runtime.EvalIndex(left, idx)
 	case *ast.HashLiteral:
 		return evalHashLiteral(r, node)
 
@@ -124,18 +189,10 @@ func Eval(r *runtime.Runtime, node ast.Node) runtime.Object {
 	return runtime.NewError("unknown program statement: %T", node)
 }
 
-func nativeBool(b bool) *runtime.Boolean {
-	if b {
-		return runtime.True
-	}
-
-	return runtime.False
-}
-
 func evalProgram(r *runtime.Runtime, program *ast.Program) runtime.Object {
 	var result runtime.Object
 	for _, stmnt := range program.Statements {
-		result = Eval(r, stmnt)
+		result = evalNode(r, stmnt)
 		switch result := result.(type) {
 		case *runtime.ReturnValue:
 			return result.Value
@@ -149,11 +206,11 @@ func evalProgram(r *runtime.Runtime, program *ast.Program) runtime.Object {
 func evalBlockStmnt(r *runtime.Runtime, block *ast.BlockStatement) runtime.Object {
 	var result runtime.Object
 	for _, stmnt := range block.Statements {
-		result = Eval(r, stmnt)
+		result = evalNode(r, stmnt)
 
 		if result != nil {
 			rt := result.Type()
-			if rt == runtime.ObjReturn || rt == runtime.ObjError {
+			if rt == runtime.ObjReturn || rt == runtime.ObjError || rt == runtime.ObjLoopControl {
 				return result
 			}
 		}
@@ -161,138 +218,115 @@ func evalBlockStmnt(r *runtime.Runtime, block *ast.BlockStatement) runtime.Objec
 	return result
 }
 
-func evalPrefixExp(op string, right runtime.Object) runtime.Object {
-	switch op {
-	case "!":
-		return evalBangOperatorExp(right)
-	case "-":
-		return evalMinusPrefixOperator(right)
-	default:
-		return runtime.NewError("unknown operator: %s%s", op, right.Type())
+// loopBody runs the body of a loop once, stop is set when the loop must end
+// and result holds the value the loop statement passes on: an error or a
+// return value.
+func loopBody(r *runtime.Runtime, body *ast.BlockStatement) (result runtime.Object, stop bool) {
+	switch res := evalNode(r, body).(type) {
+	case *runtime.LoopControl:
+		return nil, res.Break
+	case *runtime.ReturnValue, *runtime.Error:
+		return res, true
 	}
+	return nil, false
 }
 
-func evalBangOperatorExp(right runtime.Object) runtime.Object {
-	switch right {
-	case runtime.True:
-		return runtime.False
+// evalTryStmnt runs the catch when the body raises an error which can be
+// caught, and the finally however the body and catch end. A return, break
+// or continue in the finally replaces the way they ended.
+func evalTryStmnt(r *runtime.Runtime, ts *ast.TryStatement) runtime.Object {
+	result := evalNode(r, ts.Body)
+	if err, ok := result.(*runtime.Error); ok && err.Catchable() && ts.Catch != nil {
+		if ts.Param != nil {
+			// the error lives in the scope around the try, like a let in the catch
+			r.Put(ts.Param.Value, &runtime.ErrorValue{Err: err})
+		}
+		result = evalNode(r, ts.Catch)
+	}
+	if err, ok := result.(*runtime.Error); ok && !err.Catchable() {
+		return err
+	}
 
-	case runtime.False:
-		return runtime.True
-	case runtime.Nil:
-		// not of null ? == True
-		return runtime.True
-	default:
-		return runtime.False
+	if ts.Finally != nil {
+		if jump := jumpOut(evalNode(r, ts.Finally)); jump != nil {
+			return jump
+		}
 	}
+	return jumpOut(result)
 }
 
-func evalMinusPrefixOperator(right runtime.Object) runtime.Object {
-	if right.Type() != runtime.ObjInteger {
-		return runtime.NewError("unknown operator: -%s", right.Type())
-	}
-	value := right.(*runtime.Integer).Value
-	return &runtime.Integer{
-		Value: -value,
+// jumpOut returns obj when it leaves the statements around it: an error, a
+// return value or a break or continue.
+func jumpOut(obj runtime.Object) runtime.Object {
+	switch obj.(type) {
+	case *runtime.Error, *runtime.ReturnValue, *runtime.LoopControl:
+		return obj
 	}
+	return nil
 }
 
-func evalInfixOperator(op string, left, right runtime.Object) runtime.Object {
-	switch {
-	case left.Type() == runtime.ObjInteger && right.Type() == runtime.ObjInteger:
-		return evalIntegerInfixExpression(op, left, right)
-	case left.Type() == runtime.ObjString && right.Type() == runtime.ObjString:
-		return evalStringInfixExpression(op, left, right)
-
-	case left.Type() != right.Type():
-		return runtime.NewError("type mismatch: %s %s %s", left.Type(), op, right.Type())
-	case op == "==":
-		return nativeBool(left == right)
+func evalWhileStmnt(r *runtime.Runtime, ws *ast.WhileStatement) runtime.Object {
+	for {
+		cond := evalNode(r, ws.Condition)
+		if runtime.IsError(cond) {
+			return cond
+		}
+		if !runtime.IsTruthy(cond) {
+			return nil
+		}
 
-	case op == "!=":
-		return nativeBool(left != right)
-	default:
-		return runtime.NewError("unknown operator: %s %s %s", left.Type(), op, right.Type())
+		if result, stop := loopBody(r, ws.Body); stop {
+			return result
+		}
 	}
 }
 
-func evalStringInfixExpression(op string, left, right runtime.Object) runtime.Object {
-	if op != "+" {
-		return runtime.NewError("unknown operator: %s %s %s", left.Type(), op, right.Type())
+func evalForStmnt(r *runtime.Runtime, fs *ast.ForStatement) runtime.Object {
+	iterable := evalNode(r, fs.Iterable)
+	if runtime.IsError(iterable) {
+		return iterable
 	}
-	l := left.(*runtime.String)
-	r := right.(*runtime.String)
-	return &runtime.String{
-		Value: l.Value + r.Value,
+	it, err := runtime.NewIterator(iterable)
+	if err != nil {
+		err.Pos = fs.Iterable.Pos()
+		return err
 	}
-}
-
-func evalIntegerInfixExpression(op string, left, right runtime.Object) runtime.Object {
-	lval := left.(*runtime.Integer).Value
-	rval := right.(*runtime.Integer).Value
-
-	res := int64(0)
-	switch op {
-	case "+":
-		res = lval + rval
-
-	case "-":
-		res = lval - rval
-
-	case "*":
-		res = lval * rval
-
-	case "/":
-		res = lval / rval
-	case "<":
-		return nativeBool(lval < rval)
 
-	case ">":
-		return nativeBool(lval > rval)
-
-	case "==":
-		return nativeBool(lval == rval)
-	case "!=":
-
-		return nativeBool(lval != rval)
-
-	default:
-		return runtime.NewError("unknown operator: %s %s %s", left.Type(), op, right.Type())
-	}
+	for {
+		key, value, ok := it.Next()
+		if !ok {
+			return nil
+		}
+		// the variables live in the scope around the loop, like a let in its body
+		if fs.Key != nil {
+			r.Put(fs.Key.Value, key)
+			r.Put(fs.Value.Value, value)
+		} else {
+			r.Put(fs.Value.Value, it.Single(key, value))
+		}
 
-	return &runtime.Integer{
-		Value: res,
+		if result, stop := loopBody(r, fs.Body); stop {
+			return result
+		}
 	}
 }
 
 func evaluateIfExpression(r *runtime.Runtime, ie *ast.IfExpression) runtime.Object {
-	cond := Eval(r, ie.Condition)
+	cond := evalNode(r, ie.Condition)
 
 	if runtime.IsError(cond) {
 		return cond
 	}
 
-	if isTruthy(cond) {
-		return Eval(r, ie.Consequence)
+	if runtime.IsTruthy(cond) {
+		return evalNode(r, ie.Consequence)
 	} else if ie.Alternative != nil {
-		return Eval(r, ie.Alternative)
+		return evalNode(r, ie.Alternative)
 	} else {
 		return runtime.Nil
 	}
 }
 
-func isTruthy(obj runtime.Object) bool {
-	switch obj {
-	case runtime.Nil:
-		return false
-	case runtime.True:
-		return true
-	case runtime.False:
-		return false
-	}
-	return true
-}
-
 func evalIdentifier(r *runtime.Runtime, node *ast.Identifier) runtime.Object {
 	val, ok := r.Get(node.Value)
 	if ok {
@@ -302,14 +336,14 @@ func evalIdentifier(r *runtime.Runtime, node *ast.Identifier) runtime.Object {
 	if bf, ok := runtime.GetBuiltin(node.Value); ok {
 		return bf
 	}
-	return runtime.NewError("identifier not found: %s", node.Value)
+	return runtime.NewNameError("identifier not found: %s", node.Value)
 }
 
 func evalExpression(r *runtime.Runtime, exps []ast.Expression) []runtime.Object {
 	var res []runtime.Object
 
 	for _, exp := range exps {
-		eval := Eval(r, exp)
+		eval := evalNode(r, exp)
 		if runtime.IsError(eval) {
 			return []runtime.Object{eval}
 		}
@@ -318,90 +352,224 @@ func evalExpression(r *runtime.Runtime, exps []ast.Expression) []runtime.Object
 	return res
 }
 
-func applyFunction(fn runtime.Object, args []runtime.Object) runtime.Object {
+// evalArguments evaluates the arguments of a call, spreading the elements
+// of the ones written ...xs.
+func evalArguments(r *runtime.Runtime, exps []ast.Expression) ([]runtime.Object, runtime.Object) {
+	args := []runtime.Object{}
+	for _, exp := range exps {
+		spread, ok := exp.(*ast.SpreadExpression)
+		if !ok {
+			arg := evalNode(r, exp)
+			if runtime.IsError(arg) {
+				return nil, arg
+			}
+			args = append(args, arg)
+			continue
+		}
+
+		val := evalNode(r, spread.Value)
+		if runtime.IsError(val) {
+			return nil, val
+		}
+		elements, err := runtime.Spread(&caller{r: r, pos: spread.Pos()}, val)
+		if err != nil {
+			err.Pos = spread.Pos()
+			return nil, err
+		}
+		args = append(args, elements...)
+	}
+	return args, nil
+}
+
+// applyFunction calls fn with args on behalf of code running in r, pos is
+// the position of the call. An error coming out of a function gets the call
+// added to its stack.
+func applyFunction(r *runtime.Runtime, pos token.Position, fn runtime.Object, args []runtime.Object) runtime.Object {
 	switch fn := fn.(type) {
 	case *runtime.Function:
-		env := extendFunctionEnv(fn, args)
-		eval := Eval(env, fn.Body)
+		least, most := fn.Arity()
+		if err := runtime.CheckArity(least, most, len(args)); err != nil {
+			return err
+		}
+		budget := r.Budget()
+		if err := budget.Enter(); err != nil {
+			return err
+		}
+		defer budget.Leave()
+
+		var eval runtime.Object
+		if env, err := extendFunctionEnv(fn, args); err != nil {
+			eval = err
+		} else {
+			eval = evalNode(env, fn.Body)
+		}
 		if rv, ok := eval.(*runtime.ReturnValue); ok {
 			return rv.Value
 		}
+		if err, ok := eval.(*runtime.Error); ok {
+			err.Unwind(fn.File)
+			err.Stack = append(err.Stack, runtime.NewStackFrame(fn.Name, pos, args))
+		}
+		if eval == nil {
+			// the body ended with a statement
+			return runtime.Nil
+		}
 		return eval
 	case *runtime.Builtin:
-		return fn.Fn(args...)
+		var before int64
+		if len(args) > 0 {
+			before = runtime.SizeOf(args[0])
+		}
+		c := &caller{r: r, pos: pos}
+		res := fn.Call(c, args...)
+		if len(args) > 0 && res == args[0] {
+			// changed in place, like push, only the growth is new
+			return allocSize(r, res, runtime.SizeOf(res)-before-c.charged)
+		}
+		// what the builtin charged while building res is paid already
+		return allocSize(r, res, runtime.SizeOf(res)-c.charged)
 	default:
-		return runtime.NewError("not a function: %s", fn.Type())
+		return runtime.NewTypeError("not a function: %s", fn.Type())
 	}
 }
 
-func extendFunctionEnv(fn *runtime.Function, args []runtime.Object) *runtime.Runtime {
-	env := runtime.NewScope(fn.Runtime)
+// caller is the runtime.Caller of builtins called from r at pos, they run
+// their function arguments and charge their work through it.
+type caller struct {
+	r   *runtime.Runtime
+	pos token.Position
+	// charged is what the builtin allocated so far
+	charged int64
+}
 
-	for i, param := range fn.Params {
-		env.Put(param.Value, args[i])
-	}
-	return env
+func (c *caller) Call(fn runtime.Object, args ...runtime.Object) runtime.Object {
+	return applyFunction(c.r, c.pos, fn, args)
 }
 
-func evaluateIndexExpression(left, idx runtime.Object) runtime.Object {
-	switch {
+func (c *caller) Step() *runtime.Error {
+	return c.r.Budget().Step()
+}
 
-	case left.Type() == runtime.ObjArray && idx.Type() == runtime.ObjInteger:
-		return evalArrayIndexExpression(left, idx)
-	case left.Type() == runtime.ObjHash:
-		return evalHashIndexExpression(left, idx)
-	default:
-		return runtime.NewError("index operator not supported: %s", left.Type())
-	}
+func (c *caller) Alloc(size int64) *runtime.Error {
+	c.charged += size
+	return c.r.Budget().Alloc(size)
 }
 
-func evalArrayIndexExpression(left, idx runtime.Object) runtime.Object {
-	arr := left.(*runtime.Array)
-	i := idx.(*runtime.Integer).Value
-	max := arr.Len() - 1
-	if i < 0 || i > max {
-		// out of bound access os nil
-		return runtime.Nil
+// alloc charges the size of obj, which was just created, to the budget of r.
+func alloc(r *runtime.Runtime, obj runtime.Object) runtime.Object {
+	return allocSize(r, obj, runtime.SizeOf(obj))
+}
+
+func allocSize(r *runtime.Runtime, obj runtime.Object, size int64) runtime.Object {
+	if size <= 0 {
+		return obj
 	}
-	return arr.Elements[i]
+	if err := r.Budget().Alloc(size); err != nil {
+		return err
+	}
+	return obj
 }
-func evalHashIndexExpression(hash, idx runtime.Object) runtime.Object {
-	mp := hash.(*runtime.Hash)
 
-	index, ok := idx.(runtime.Hashtable)
-	if !ok {
-		return runtime.NewError("unusable as hash key: %s", idx.Type())
+// extendFunctionEnv binds the parameters of fn to args in a new scope, the
+// second result is the error of a default value.
+func extendFunctionEnv(fn *runtime.Function, args []runtime.Object) (*runtime.Runtime, runtime.Object) {
+	env := runtime.NewScope(fn.Runtime)
+
+	for i, param := range fn.Params {
+		if i < len(args) {
+			env.Put(param.Value, args[i])
+			continue
+		}
+		// defaults are evaluated at every call, they see the parameters before them
+		val := evalNode(env, fn.Defaults[i])
+		if runtime.IsError(val) {
+			return nil, val
+		}
+		env.Put(param.Value, val)
+	}
+	if fn.Rest != nil {
+		rest := []runtime.Object{}
+		if len(args) > len(fn.Params) {
+			rest = append(rest, args[len(fn.Params):]...)
+		}
+		arr := alloc(env, &runtime.Array{Elements: rest})
+		if runtime.IsError(arr) {
+			return nil, arr
+		}
+		env.Put(fn.Rest.Value, arr)
 	}
+	return env, nil
+}
 
-	pairs, ok := mp.Pairs[index.HashKey()]
-	if !ok {
-		return runtime.Nil
+func evalAssignExpression(r *runtime.Runtime, ae *ast.AssignExpression) runtime.Object {
+	// "+=" applies "+"
+	op := strings.TrimSuffix(ae.Operator, "=")
+
+	switch target := ae.Target.(type) {
+	case *ast.Identifier:
+		var current runtime.Object
+		if op != "" {
+			current = evalIdentifier(r, target)
+			if runtime.IsError(current) {
+				return current
+			}
+		}
+		value := evalNode(r, ae.Value)
+		if runtime.IsError(value) {
+			return value
+		}
+		if op != "" {
+			value = alloc(r, runtime.EvalInfix(op, current, value))
+			if runtime.IsError(value) {
+				return value
+			}
+		}
+		if !r.Set(target.Value, value) {
+			return runtime.NewNameError("assignment to undeclared identifier: %s", target.Value)
+		}
+		return value
+
+	case *ast.IndexExpression:
+		left := evalNode(r, target.Left)
+		if runtime.IsError(left) {
+			return left
+		}
+		idx := evalNode(r, target.Index)
+		if runtime.IsError(idx) {
+			return idx
+		}
+		value := evalNode(r, ae.Value)
+		if runtime.IsError(value) {
+			return value
+		}
+		before := runtime.SizeOf(left)
+		value = runtime.AssignIndex(left, idx, op, value)
+		return allocSize(r, value, runtime.SizeOf(left)-before)
+
+	default:
+		return runtime.NewError("cannot assign to %s", ae.Target)
 	}
-	return pairs.Value
 }
 
 func evalHashLiteral(r *runtime.Runtime, hl *ast.HashLiteral) runtime.Object {
-	h := &runtime.Hash{
-		Pairs: make(map[runtime.HashKey]runtime.HashPair),
-	}
+	h := runtime.NewHash()
 
-	for k, v := range hl.Pairs {
-		ek := Eval(r, k)
+	for _, pair := range hl.Pairs {
+		ek := evalNode(r, pair.Key)
 
 		if runtime.IsError(ek) {
 			return ek
 		}
 
-		hashKey, ok := ek.(runtime.Hashtable)
-
-		if !ok {
-			runtime.NewError("unusable as hash key: %s", ek.Type())
+		val := evalNode(r, pair.Value)
+		if runtime.IsError(val) {
+			return val
 		}
 
-		val := Eval(r, v)
-
-		h.Pairs[hashKey.HashKey()] = runtime.HashPair{Key: ek, Value: val}
+		if err := h.Set(ek, val); err != nil {
+			return err
+		}
 	}
 
-	return h
+	return alloc(r, h)
 }
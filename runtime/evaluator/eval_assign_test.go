@@ -0,0 +1,113 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x = x + 1", 2},
+		{"let x = 10; x += 5; x", 15},
+		{"let x = 10; x -= 5; x", 5},
+		{"let x = 10; x *= 5; x", 50},
+		{"let x = 10; x /= 5; x", 2},
+		{"let x = 1.5; x *= 2; x", 3.0},
+		{`let s = "a"; s += "b"; s`, "ab"},
+		{"let a = 1; let b = 2; a = b = 5; a + b", 10},
+		{"let x = 0; let y = (x += 3) * 2; x + y", 9},
+		{"let s = 0; for (x in range(5)) { s += x }; s", 10},
+
+		// assignment updates the scope the variable was declared in
+		{"let x = 1; let f = fn() { x = 5 }; f(); x", 5},
+		{"let x = 1; let f = fn() { let x = 2; x = 3; x }; f() + x", 4},
+		{"let f = fn(n) { n += 1; n }; let n = 10; f(1) + n", 12},
+		{`let counter = fn() {
+		    let n = 0;
+		    fn() { n += 1 }
+		  };
+		  let c = counter();
+		  c(); c();
+		  let d = counter();
+		  d();
+		  c()`, 3},
+		{`let make = fn() {
+		    let n = 0;
+		    [fn() { n += 1 }, fn() { n }]
+		  };
+		  let p = make();
+		  p[0](); p[0]();
+		  p[1]()`, 2},
+		{`let outer = fn() {
+		    let n = 1;
+		    let mid = fn() { fn() { n *= 10 } };
+		    mid()();
+		    n
+		  };
+		  outer()`, 10},
+
+		// index assignment
+		{"let a = [1, 2, 3]; a[1] = 20; a[1]", 20},
+		{"let a = [1, 2, 3]; a[2] += 5; a[2]", 8},
+		{"let a = [1, 2, 3]; let b = a; b[0] = 9; a[0]", 9},
+		{`let h = {"a": 1}; h["a"] = 2; h["a"]`, 2},
+		{`let h = {"a": 1}; h["b"] = 3; h["b"]`, 3},
+		{`let h = {"n": 1}; h["n"] *= 7; h["n"]`, 7},
+		{`let h = {"xs": [1, 2]}; h["xs"][1] -= 2; h["xs"][1]`, 0},
+		{"let a = [0]; let i = 0; a[i] = a[i] + 4", 4},
+
+		// errors
+		{"y = 1", "assignment to undeclared identifier: y"},
+		{"let f = fn() { z = 1 }; f()", "assignment to undeclared identifier: z"},
+		{"y += 1", "identifier not found: y"},
+		{"len = 1", "assignment to undeclared identifier: len"},
+		{"let a = [1]; a[1] = 2", "index out of range: 1 with length 1"},
+		{"let a = [1]; a[-1] = 2", "index out of range: -1 with length 1"},
+		{`let a = [1]; a["0"] = 2`, "array index must be Integer, got String"},
+		{`let h = {}; h[[1]] = 2`, "unusable as hash key: Array"},
+		{`let s = "abc"; s[0] = "x"`, "index assignment not supported: String"},
+		{`let x = 1; x += "a"`, "type mismatch: Integer + String"},
+		{`let h = {}; h["n"] += 1`, "type mismatch: Nil + Integer"},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case float64:
+				testFloatObject(t, evaluated, expected)
+			case string:
+				if s, ok := evaluated.(*runtime.String); ok {
+					assert.Equal(t, expected, s.Value)
+					return
+				}
+				err, ok := evaluated.(*runtime.Error)
+				if !ok {
+					assert.Failf(t, "assert failed", "result must be error, got %T", evaluated)
+					return
+				}
+				assert.Equal(t, expected, err.Message)
+			}
+		})
+	}
+}
+
+func TestAssignmentErrorPosition(t *testing.T) {
+	input := "let x = 1;\nlet f = fn() {\n  y = x\n};\nf()"
+
+	forEachBackend(t, input, func(t *testing.T, evaluated runtime.Object) {
+		err, ok := evaluated.(*runtime.Error)
+		if !ok {
+			assert.Failf(t, "assert failed", "result must be error, got %T", evaluated)
+			return
+		}
+		assert.Equal(t, "3:3", err.Pos.String())
+	})
+}
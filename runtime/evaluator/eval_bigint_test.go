@@ -0,0 +1,84 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBigIntPromotion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"4294967296 * 4294967296", "18446744073709551616"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"(-9223372036854775807 - 1) / -1", "9223372036854775808"},
+		{"99999999999999999999", "99999999999999999999"},
+		{"-99999999999999999999", "-99999999999999999999"},
+		{"99999999999999999999 * 99999999999999999999", "9999999999999999999800000000000000000001"},
+		{"99999999999999999999 / 3", "33333333333333333333"},
+		{"int(1e19)", "10000000000000000000"},
+		{`int("123456789012345678901234567890")`, "123456789012345678901234567890"},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			big, ok := evaluated.(*runtime.BigInt)
+			assert.Truef(t, ok, "runtime must be BigInt object, got %T (%s)", evaluated, evaluated.Inspect())
+			if big == nil {
+				return
+			}
+			assert.Equal(t, tt.expected, big.Inspect())
+		})
+	}
+}
+
+func TestBigIntDemotion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"9223372036854775807 + 1 - 1", 9223372036854775807},
+		{"99999999999999999999 - 99999999999999999998", 1},
+		{"99999999999999999999 / 99999999999999999999", 1},
+		{"int(9223372036854775807 + 1 - 10)", 9223372036854775798},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			testIntegerObject(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestBigIntComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"99999999999999999999 > 1", true},
+		{"-99999999999999999999 < 1", true},
+		{"99999999999999999999 == 99999999999999999999", true},
+		{"99999999999999999999 != 99999999999999999998", true},
+		{"99999999999999999999 > 1.5", true},
+		{"1e19 == 10000000000000000000", true},
+		{`{99999999999999999999: true}[99999999999999999998 + 1]`, true},
+		{`{10000000000000000000: true}[1e19]`, true},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			testBoolObject(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestBigIntMixedWithFloat(t *testing.T) {
+	forEachBackend(t, "99999999999999999999 * 0.5", func(t *testing.T, evaluated runtime.Object) {
+		testFloatObject(t, evaluated, 5e19)
+	})
+}
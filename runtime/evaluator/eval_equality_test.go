@@ -0,0 +1,114 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2] == [1, 2]", "true"},
+		{"[1, 2] != [1, 2]", "false"},
+		{"[1, 2] == [2, 1]", "false"},
+		{"[1, 2] == [1, 2, 3]", "false"},
+		{"[1, [2, 3]] == [1, [2, 3]]", "true"},
+		{"[1, 2.0] == [1.0, 2]", "true"},
+		{`[1, "a"] == [1, 2]`, "false"},
+		{"[] == []", "true"},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, "true"},
+		{`{"a": 1} == {"a": 2}`, "false"},
+		{`{"a": 1} == {"a": 1, "b": 2}`, "false"},
+		{`{"a": 1} != {"b": 1}`, "true"},
+		{"let a = [1]; let b = a; push(b, 2); a == b", "true"},
+		{`"abc" == "abc"`, "true"},
+		{`"abc" != "abd"`, "true"},
+		{"tuple([1, 2]) == tuple([1, 2])", "true"},
+
+		// functions are only equal to themselves
+		{"let f = fn(x) { x }; f == f", "true"},
+		{"fn(x) { x } == fn(x) { x }", "false"},
+		{"len == len", "true"},
+
+		// ordering
+		{`"apple" < "banana"`, "true"},
+		{`"b" > "abc"`, "true"},
+		{`"ab" < "ab"`, "false"},
+		{"[1, 2] < [1, 3]", "true"},
+		{"[1, 2] < [1, 2, 0]", "true"},
+		{"[2] > [1, 9]", "true"},
+		{`[["a"], 1] < [["b"], 0]`, "true"},
+		{"tuple([1, 2]) < tuple([2])", "true"},
+		{"sorted([[2, 1], [1, 2], [1]])", "[[1], [1, 2], [2, 1]]"},
+
+		// integers compare with floats exactly
+		{"9007199254740993 == 9007199254740992.0", "false"},
+		{"9007199254740993 != 9007199254740992.0", "true"},
+		{"9007199254740992 == 9007199254740992.0", "true"},
+		{"9007199254740993 > 9007199254740992.0", "true"},
+		{"9007199254740992.0 < 9007199254740993", "true"},
+		{"[9007199254740993] == [9007199254740992.0]", "false"},
+		{"[9007199254740992.0] < [9007199254740993]", "true"},
+		{"100000000000000000000000 > 99999999999999991611392.0", "true"},
+		{`let h = {9007199254740992.0: "f"}; [h[9007199254740992], h[9007199254740993]]`, "[f, Nil]"},
+
+		// values holding themselves
+		{"let a = [1]; push(a, a); let b = [1]; push(b, b); a == b", "true"},
+		{"let a = [1]; push(a, a); let b = [2]; push(b, b); a == b", "false"},
+		{"let a = [1]; push(a, a); let b = [1]; push(b, a); a == b", "true"},
+		{`let h = {}; h["h"] = h; let g = {}; g["h"] = g; h == g`, "true"},
+		{`let a = [1]; push(a, a); let b = [1]; push(b, b); let r = 0; try { a < b } catch (e) { r = e["message"] }; r`, "cannot compare Array holding itself"},
+
+		// errors
+		{"[1, 2] == tuple([1, 2])", "Error: type mismatch: Array == Tuple"},
+		{`[1] < ["a"]`, "Error: cannot compare Integer with String"},
+		{`{"a": 1} < {"a": 2}`, "Error: unknown operator: Hash < Hash"},
+		{`"a" - "b"`, "Error: unknown operator: String - String"},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			assert.Equal(t, tt.expected, evaluated.Inspect(), tt.input)
+		})
+	}
+}
+
+func TestTuples(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"tuple([1, 2])", "(1, 2)"},
+		{"tuple([1])", "(1,)"},
+		{"tuple()", "()"},
+		{`tuple("ab")`, "(a, b)"},
+		{"tuple(range(3))", "(0, 1, 2)"},
+		{"array(tuple([1, 2]))", "[1, 2]"},
+		{"let t = tuple([1, 2, 3]); [len(t), t[1], t[5], first(t), last(t)]", "[3, 2, Nil, 1, 3]"},
+		{"let s = 0; for (x in tuple([1, 2, 3])) { s += x }; s", "6"},
+		{"contains(tuple([1, 2]), 2)", "true"},
+		{"map(tuple([1, 2]), fn(x) { x * 2 })", "[2, 4]"},
+
+		// tuples as hash keys
+		{"let h = {tuple([1, 2]): \"a\"}; h[tuple([1, 2])]", "a"},
+		{"let h = {}; h[tuple([1, tuple([2])])] = 1; h[tuple([1, tuple([2])])]", "1"},
+		{"let h = {}; h[tuple([1, 2])] = 1; h[tuple([2, 1])]", "Nil"},
+		{`group_by([1, 2, 3, 4], fn(x) { tuple([x > 2]) })`, "{(false,):[1, 2], (true,):[3, 4]}"},
+
+		// errors
+		{"let t = tuple([1]); t[0] = 2", "Error: index assignment not supported: Tuple"},
+		{"let h = {}; h[tuple([[1]])] = 1", "Error: unusable as hash key: Array"},
+		{"tuple(1)", "Error: argument 1 to `tuple` must be iterable, got Integer"},
+		{"push(tuple([1]), 2)", "Error: argument 1 to `push` must be Array, got Tuple"},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			assert.Equal(t, tt.expected, evaluated.Inspect(), tt.input)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvalFloatExpr(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14", 3.14},
+		{"-2.5", -2.5},
+		{"1e3", 1000},
+		{"0.1 + 0.2", 0.30000000000000004},
+		{"7 / 2.0", 3.5},
+		{"2.5 * 4", 10},
+		{"10 - 0.5", 9.5},
+		{"1 / 4.0 * 100", 25},
+		{"-(1.5 + 1)", -2.5},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			testFloatObject(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func testFloatObject(t *testing.T, obj runtime.Object, exp float64) {
+	if er, ok := obj.(*runtime.Error); ok {
+		assert.False(t, ok, er.Inspect())
+		return
+	}
+	fo, ok := obj.(*runtime.Float)
+	assert.Truef(t, ok, "runtime must be Float object, got %T", obj)
+	if fo == nil {
+		return
+	}
+	assert.Equal(t, exp, fo.Value)
+}
+
+func TestMixedComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1 == 1.0", true},
+		{"1.0 != 1", false},
+		{"1 < 1.5", true},
+		{"2.5 > 3", false},
+		{"0.1 + 0.2 == 0.3", false},
+		{"{1: true}[1.0]", true},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			testBoolObject(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2.0", "2.0"},
+		{"4 * 0.5", "2.0"},
+		{"-1.25", "-1.25"},
+		{"1e21", "1e+21"},
+		{"1 / 3.0", "0.3333333333333333"},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			assert.Equal(t, tt.expected, evaluated.Inspect())
+		})
+	}
+}
+
+func TestFloatErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1.5 / 0", "division by zero"},
+		{"1.5 + true", "type mismatch: Float + Boolean"},
+		{`"a" * 2.0`, "type mismatch: String * Float"},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			err, ok := evaluated.(*runtime.Error)
+			if !ok {
+				assert.Failf(t, "assert failed", "result must be error, got %T", evaluated)
+				return
+			}
+			assert.Equal(t, tt.expected, err.Message)
+		})
+	}
+}
@@ -1,8 +1,13 @@
 package evaluator
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/NishanthSpShetty/monkey/parser"
 	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
 	"github.com/stretchr/testify/assert"
 )
@@ -33,7 +38,9 @@ func TestFunctionApplication(t *testing.T) {
 		{"fn(x) { x; }(5)", 5},
 	}
 	for _, tt := range tests {
-		testIntegerObject(t, testEval(tt.input), tt.expected)
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			testIntegerObject(t, evaluated, tt.expected)
+		})
 	}
 }
 
@@ -45,5 +52,158 @@ func TestClosure(t *testing.T) {
 	let addTwo = newAdder(2);
 
 	addTwo(3)`
-	testIntegerObject(t, testEval(input), 5)
+	forEachBackend(t, input, func(t *testing.T, evaluated runtime.Object) {
+		testIntegerObject(t, evaluated, 5)
+	})
+}
+
+func TestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// defaults
+		{"let f = fn(a, b = 10) { a + b }; [f(1), f(1, 2)]", "[11, 3]"},
+		{"let f = fn(a = 1, b = a * 2) { [a, b] }; [f(), f(5), f(5, 0)]", "[[1, 2], [5, 10], [5, 0]]"},
+		{"let f = fn(xs = []) { push(xs, 1) }; f(); f()", "[1]"},
+		{"let n = 0; let next = fn() { n += 1 }; let f = fn(x = next()) { x }; f(); f(); [f(), f(7), n]", "[3, 7, 3]"},
+		{"let f = fn(a, b = a) { fn() { a + b } }; f(2)()", "4"},
+		{"let f = fn(a, b = 1 + true) { a }; f(1, 2)", "1"},
+
+		// rest parameters
+		{"let f = fn(first, ...rest) { [first, rest] }; [f(1), f(1, 2, 3)]", "[[1, []], [1, [2, 3]]]"},
+		{"let sum = fn(...xs) { reduce(xs, fn(a, b) { a + b }, 0) }; [sum(), sum(1, 2, 3)]", "[0, 6]"},
+		{"let f = fn(a, b = 2, ...rest) { [a, b, rest] }; [f(1), f(1, 3), f(1, 3, 4, 5)]", "[[1, 2, []], [1, 3, []], [1, 3, [4, 5]]]"},
+		{"let f = fn(...xs) { fn() { len(xs) } }; f(1, 2)()", "2"},
+
+		// spread
+		{"let add = fn(a, b, c) { a + b + c }; let xs = [1, 2, 3]; add(...xs)", "6"},
+		{"let add = fn(a, b, c) { a + b + c }; add(1, ...[2], ...tuple([3]))", "6"},
+		{"let f = fn(...xs) { xs }; f(0, ...range(3), 9)", "[0, 0, 1, 2, 9]"},
+		{"let f = fn(...xs) { xs }; f(...[])", "[]"},
+		{"concat(...[[1], [2, 3]], [4])", "[1, 2, 3, 4]"},
+		{`let f = fn(...xs) { xs }; f(..."ab")`, "[a, b]"},
+		{"map([[1, 2], [3, 4]], fn(p) { fn(a, b) { a * b }(...p) })", "[2, 12]"},
+
+		// arity
+		{"let f = fn(a, b) { a }; f(1)", "Error: wrong number of arguments: want=2, got=1"},
+		{"let f = fn(a, b) { a }; f(1, 2, 3)", "Error: wrong number of arguments: want=2, got=3"},
+		{"let f = fn(a, b = 1) { a }; f()", "Error: wrong number of arguments: want=1 to 2, got=0"},
+		{"let f = fn(a, b = 1) { a }; f(1, 2, 3)", "Error: wrong number of arguments: want=1 to 2, got=3"},
+		{"let f = fn(a, b, ...c) { a }; f(1)", "Error: wrong number of arguments: want=at least 2, got=1"},
+		{"let f = fn(a, b) { a }; f(...[1, 2, 3])", "Error: wrong number of arguments: want=2, got=3"},
+
+		// errors
+		{"let f = fn(...xs) { xs }; f(...1)", "Error: cannot spread Integer"},
+		{"let f = fn(a, b = a + true) { b }; f(1)", "Error: type mismatch: Integer + Boolean"},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			assert.Equal(t, tt.expected, evaluated.Inspect(), tt.input)
+		})
+	}
+}
+
+func TestStackTrace(t *testing.T) {
+	tests := []struct {
+		input    string
+		pos      string
+		expected []string
+	}{
+		{"1 + true", "1:1", nil},
+		{"let f = fn(x) { x + true };\nf(1)", "1:17", []string{"f(1) at 2:1"}},
+		{`let inner = fn(s, n) { s + n };
+let outer = fn(xs) { inner("a", xs[0]) };
+let main = fn() { outer([1, 2]) };
+main()`, "1:24", []string{`inner("a", 1) at 2:22`, "outer([1, 2]) at 3:19", "main() at 4:1"}},
+		// functions called by builtins have the position of the builtin call
+		{"let g = fn(xs) { map(xs, fn(x) { x + true }) };\ng([3])", "1:34",
+			[]string{"fn(3) at 1:18", "g([3]) at 2:1"}},
+		{`let f = fn(s, h) { s + h };
+f("a very long string which is cut", {1: 2})`, "1:20",
+			[]string{`f("a very long string w..., {1:2}) at 2:1`}},
+		// large values and values holding themselves are cut too
+		{"let f = fn(xs) { xs + true };\nf(array(range(200000)))", "1:18", []string{"f([0, 1, 2, 3, 4, 5, 6,...) at 2:1"}},
+		{"let f = fn(h) { h + true };\nlet h = {};\nh[1] = h;\nf(h)", "1:17", []string{"f({1:{...}}) at 4:1"}},
+		// the arguments as passed, spread and without defaults
+		{"let f = fn(a, b = 2, ...c) { a + true };\nf(...[1])", "1:30", []string{"f(1) at 2:1"}},
+		{"let f = fn(a, b = 2, ...c) { a + true };\nf(1, 3, 4, 5)", "1:30", []string{"f(1, 3, 4, 5) at 2:1"}},
+		{"let f = fn(a, b = a + true) { a };\nf(1)", "1:19", []string{"f(1) at 2:1"}},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			err, ok := evaluated.(*runtime.Error)
+			if !ok {
+				assert.Failf(t, "assert failed", "result must be error, got %T", evaluated)
+				return
+			}
+			assert.Equal(t, tt.pos, err.Pos.String())
+			var calls []string
+			for _, sf := range err.Stack {
+				calls = append(calls, sf.Call()+" at "+sf.Pos.String())
+			}
+			assert.Equal(t, tt.expected, calls)
+		})
+	}
+}
+
+func TestDeepThrowWithLargeArguments(t *testing.T) {
+	// every frame summarizes its arguments, which must not print them whole
+	input := `let f = fn(xs, n) { if (n == 0) { throw "deep" } f(xs, n - 1) };
+let r = 0;
+try { f(array(range(200000)), 1000) } catch (e) { r = e["message"] };
+r`
+	start := time.Now()
+	forEachBackend(t, input, func(t *testing.T, evaluated runtime.Object) {
+		assert.Equal(t, "deep", evaluated.Inspect())
+	})
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestRenderStackTrace(t *testing.T) {
+	input := "let f = fn(n) {\n  if (n == 0) { n + true } else { f(n - 1) }\n};\nf(30)"
+	l := lexer.NewNamed("main.mk", input)
+	err, ok := Eval(context.Background(), runtime.New(), parser.New(l).ParseProgram()).(*runtime.Error)
+	if !ok {
+		assert.Fail(t, "result must be error")
+		return
+	}
+
+	lines := strings.Split(err.Render(l.File()), "\n")
+	assert.Equal(t, []string{
+		"main.mk:2:17: Error: type mismatch: Integer + Boolean",
+		"      if (n == 0) { n + true } else { f(n - 1) }",
+		"                    ^",
+		"stack trace (most recent call first):",
+		"    f(0) at main.mk:2:35",
+		"    f(1) at main.mk:2:35",
+	}, lines[:6])
+	assert.Equal(t, "    ... 6 more calls", lines[24])
+	assert.Equal(t, []string{
+		"    f(29) at main.mk:2:35",
+		"    f(30) at main.mk:4:1",
+	}, lines[len(lines)-2:])
+}
+
+func TestRenderFunctionFromOtherSource(t *testing.T) {
+	r := runtime.New()
+	Eval(context.Background(), r, parser.New(lexer.NewNamed("lib.mk", "let f = fn(x) { x + true }")).ParseProgram())
+
+	l := lexer.NewNamed("main.mk", "let y = 1;\nf(y)")
+	err, ok := Eval(context.Background(), r, parser.New(l).ParseProgram()).(*runtime.Error)
+	if !ok {
+		assert.Fail(t, "result must be error")
+		return
+	}
+
+	// the error is in the body of f, the call in main.mk
+	assert.Equal(t, []string{
+		"lib.mk:1:17: Error: type mismatch: Integer + Boolean",
+		"    let f = fn(x) { x + true }",
+		"                    ^",
+		"stack trace (most recent call first):",
+		"    f(1) at main.mk:2:1",
+	}, strings.Split(err.Render(l.File()), "\n"))
 }
@@ -1,7 +1,10 @@
-
 package evaluator
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+)
 
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
@@ -14,6 +17,8 @@ func TestLetStatements(t *testing.T) {
 		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
 	}
 	for _, tt := range tests {
-		testIntegerObject(t, testEval(tt.input), tt.expected)
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			testIntegerObject(t, evaluated, tt.expected)
+		})
 	}
 }
@@ -0,0 +1,183 @@
+package evaluator
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/NishanthSpShetty/monkey/parser"
+	"github.com/NishanthSpShetty/monkey/runtime/compiler"
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/NishanthSpShetty/monkey/runtime/vm"
+	"github.com/stretchr/testify/assert"
+)
+
+func evalWithLimits(ctx context.Context, limits runtime.Limits, input string) runtime.Object {
+	r := runtime.New()
+	r.SetLimits(limits)
+	return Eval(ctx, r, parser.New(lexer.New(input)).ParseProgram())
+}
+
+func vmWithLimits(ctx context.Context, limits runtime.Limits, input string) runtime.Object {
+	c := compiler.New()
+	if err := c.Compile(parser.New(lexer.New(input)).ParseProgram()); err != nil {
+		return runtime.NewError("compile error: %s", err)
+	}
+	machine := vm.New(c.Bytecode())
+	machine.SetLimits(limits)
+	return machine.RunContext(ctx)
+}
+
+// limitBackends are the engines the limits are checked on, like backends.
+var limitBackends = []struct {
+	name string
+	eval func(ctx context.Context, limits runtime.Limits, input string) runtime.Object
+}{
+	{"evaluator", evalWithLimits},
+	{"vm", vmWithLimits},
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input    string
+		limits   runtime.Limits
+		limit    runtime.Limit
+		expected string
+	}{
+		{"let f = fn() { f() }; f()", runtime.DefaultLimits, runtime.DepthLimit, "maximum call depth exceeded: 10000"},
+		{"let f = fn(n) { if (n > 0) { f(n - 1) } else { 0 } }; f(50)", runtime.Limits{MaxDepth: 10}, runtime.DepthLimit, "maximum call depth exceeded: 10"},
+		{"let xs = [1, 2]; map(xs, fn(x) { map(xs, fn(y) { x }) })", runtime.Limits{MaxDepth: 1}, runtime.DepthLimit, "maximum call depth exceeded: 1"},
+		{"while (true) {}", runtime.Limits{MaxSteps: 1000}, runtime.StepLimit, "step limit exceeded: 1000 steps"},
+		{`let s = "x"; while (true) { s += s }`, runtime.Limits{MaxAlloc: 1 << 20}, runtime.AllocLimit, "allocation limit exceeded: 1048576 bytes"},
+		{"let a = []; while (true) { push(a, 1) }", runtime.Limits{MaxAlloc: 1 << 16}, runtime.AllocLimit, "allocation limit exceeded: 65536 bytes"},
+		{"let h = {}; let i = 0; while (true) { h[i] = i; i += 1 }", runtime.Limits{MaxAlloc: 1 << 16}, runtime.AllocLimit, "allocation limit exceeded: 65536 bytes"},
+		{"range(100000)", runtime.Limits{MaxAlloc: 1 << 16}, runtime.NoLimit, ""},
+		{"array(range(100000))", runtime.Limits{MaxAlloc: 1 << 16}, runtime.AllocLimit, "allocation limit exceeded: 65536 bytes"},
+		// builtins building values charge them element by element
+		{"array(range(9223372036854775807))", runtime.Limits{MaxAlloc: 1 << 20}, runtime.AllocLimit, "allocation limit exceeded: 1048576 bytes"},
+		{"tuple(range(9223372036854775807))", runtime.Limits{MaxAlloc: 1 << 20}, runtime.AllocLimit, "allocation limit exceeded: 1048576 bytes"},
+		{"zip(range(9223372036854775807), range(9223372036854775807))", runtime.Limits{MaxAlloc: 1 << 20}, runtime.AllocLimit, "allocation limit exceeded: 1048576 bytes"},
+		{"map(range(9223372036854775807), fn(x) { x })", runtime.Limits{MaxAlloc: 1 << 20}, runtime.AllocLimit, "allocation limit exceeded: 1048576 bytes"},
+		{"flat_map(range(9223372036854775807), fn(x) { [x, x] })", runtime.Limits{MaxAlloc: 1 << 20}, runtime.AllocLimit, "allocation limit exceeded: 1048576 bytes"},
+		{"let a = array(range(1000)); concat(a, a, a, a, a, a, a, a)", runtime.Limits{MaxAlloc: 1 << 16}, runtime.AllocLimit, "allocation limit exceeded: 65536 bytes"},
+		{"let f = fn(...xs) { len(xs) }; f(...range(9223372036854775807))", runtime.Limits{MaxAlloc: 1 << 20}, runtime.AllocLimit, "allocation limit exceeded: 1048576 bytes"},
+		{"all(range(1, 9223372036854775807))", runtime.Limits{MaxSteps: 100000}, runtime.StepLimit, "step limit exceeded: 100000 steps"},
+		{"let a = array(range(5000, 0, -1)); len(a)", runtime.Limits{MaxSteps: 20000}, runtime.NoLimit, ""},
+		{"let a = array(range(5000, 0, -1)); sort(a)", runtime.Limits{MaxSteps: 20000}, runtime.StepLimit, "step limit exceeded: 20000 steps"},
+		{"len(concat(array(range(1000)), array(range(10))))", runtime.Limits{MaxAlloc: 1 << 16}, runtime.NoLimit, ""},
+		{"let f = fn(n) { if (n > 0) { f(n - 1) } else { 0 } }; f(50)", runtime.Limits{MaxDepth: 51, MaxSteps: 10000}, runtime.NoLimit, ""},
+	}
+
+	for _, b := range limitBackends {
+		for _, tt := range tests {
+			t.Run(b.name+"/"+tt.input, func(t *testing.T) {
+				evaluated := b.eval(context.Background(), tt.limits, tt.input)
+				err, ok := evaluated.(*runtime.Error)
+				if tt.limit == runtime.NoLimit {
+					assert.False(t, ok, "unexpected error %s", evaluated.Inspect())
+					return
+				}
+				if !ok {
+					assert.Failf(t, "assert failed", "result must be error, got %T", evaluated)
+					return
+				}
+				assert.Equal(t, tt.limit, err.Limit)
+				assert.Equal(t, tt.expected, err.Message)
+			})
+		}
+	}
+}
+
+func TestDefaultLimitsOnEveryBackend(t *testing.T) {
+	elements := make([]string, 3000)
+	for i := range elements {
+		elements[i] = strconv.Itoa(i)
+	}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"len([" + strings.Join(elements, ", ") + "])", "3000"},
+		{"let f = fn(n) { if (n > 0) { 1 + f(n - 1) } else { 0 } }; f(1500)", "1500"},
+		{"let f = fn(n) { if (n > 0) { 1 + f(n - 1) } else { 0 } }; f(9999)", "9999"},
+		// the stack grows under the variables captured by a closure
+		{"let deep = fn(n) { if (n > 0) { deep(n - 1) } else { 0 } }; let g = fn() { let x = 1; let inc = fn() { x += 1 }; deep(3000); inc(); x }; g()", "2"},
+		{"let f = fn(n) { if (n > 0) { 1 + f(n - 1) } else { 0 } }; f(10000)", "Error: maximum call depth exceeded: 10000"},
+		{"let f = fn(n) { if (n > 0) { 1 + f(n - 1) } else { 0 } }; let r = 0; try { f(10000) } catch (e) { r = 1 }; r", "Error: maximum call depth exceeded: 10000"},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			assert.Equal(t, tt.expected, evaluated.Inspect())
+		})
+	}
+}
+
+func TestLimitsAreReset(t *testing.T) {
+	r := runtime.New()
+	r.SetLimits(runtime.Limits{MaxSteps: 500})
+	program := parser.New(lexer.New("let i = 0; while (i < 10) { i += 1 }; i")).ParseProgram()
+
+	// every evaluation gets the whole budget
+	for i := 0; i < 5; i++ {
+		testIntegerObject(t, Eval(context.Background(), r, program), 10)
+	}
+
+	// the depth goes back down after an error deep in the calls
+	r.SetLimits(runtime.Limits{MaxDepth: 20})
+	program = parser.New(lexer.New("let f = fn(n) { if (n > 0) { f(n - 1) } else { 1 + true } }; f(15)")).ParseProgram()
+	for i := 0; i < 3; i++ {
+		err, ok := Eval(context.Background(), r, program).(*runtime.Error)
+		if assert.True(t, ok) {
+			assert.Equal(t, runtime.NoLimit, err.Limit, err.Message)
+		}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	for _, b := range limitBackends {
+		t.Run(b.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			evaluated := b.eval(ctx, runtime.Limits{}, "let f = fn() { 1 }; while (true) { f() }")
+			err, ok := evaluated.(*runtime.Error)
+			if !ok {
+				assert.Failf(t, "assert failed", "result must be error, got %T", evaluated)
+				return
+			}
+			assert.Equal(t, runtime.Canceled, err.Limit)
+			assert.Equal(t, "evaluation canceled: context deadline exceeded", err.Message)
+			assert.True(t, err.Pos.IsValid(), "the error is raised where the evaluation was")
+		})
+	}
+}
+
+func TestCancelBuiltins(t *testing.T) {
+	tests := []string{
+		"array(range(9223372036854775807))",
+		"sort(array(range(9223372036854775807)))",
+		"any(range(9223372036854775807), fn(x) { false })",
+		"let f = fn(...xs) { 1 }; f(...range(9223372036854775807))",
+	}
+
+	for _, b := range limitBackends {
+		for _, input := range tests {
+			t.Run(b.name+"/"+input, func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+
+				evaluated := b.eval(ctx, runtime.Limits{}, input)
+				err, ok := evaluated.(*runtime.Error)
+				if !ok {
+					assert.Failf(t, "assert failed", "result must be error, got %T", evaluated)
+					return
+				}
+				assert.Equal(t, runtime.Canceled, err.Limit)
+			})
+		}
+	}
+}
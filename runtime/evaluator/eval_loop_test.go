@@ -0,0 +1,95 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let i = 0; while (i < 10) { let i = i + 1; }; i", 10},
+		{"let i = 0; while (false) { let i = i + 1; }; i", 0},
+		{"let s = 0; for (x in [1, 2, 3]) { let s = s + x; }; s", 6},
+		{"let s = 0; for (i, x in [10, 20, 30]) { let s = s + i * x; }; s", 80},
+		{"let s = 0; for (x in range(5)) { let s = s + x; }; s", 10},
+		{"let s = 0; for (x in range(10, 0, -3)) { let s = s + x; }; s", 22},
+		{"let s = 0; for (i in range(10000)) { let s = s + 1; }; s", 10000},
+		{"let n = 0; for (i in range(0, 9223372036854775807, 4611686018427387904)) { let n = n + 1; }; n", 2},
+		{"let s = 0; for (x in range(9223372036854775807, 9223372036854775806, -9223372036854775807)) { let s = x; }; s", 9223372036854775807},
+		{"let s = 0; for (k, v in {1: 10, 2: 20}) { let s = s + k * v; }; s", 50},
+		{"let s = 0; for (k in {1: 10, 2: 20}) { let s = s + k; }; s", 3},
+		{`let n = 0; for (c in "héllo") { let n = n + 1; }; n`, 5},
+		{`let n = 0; for (i, c in "ab") { let n = n + i; }; n`, 1},
+		{"let x = 0; for (x in []) { }; x", 0},
+		{"for (x in [1, 2, 3]) { }; x", 3},
+
+		// break and continue
+		{"let i = 0; while (true) { if (i == 5) { break; } let i = i + 1; }; i", 5},
+		{"let s = 0; for (x in range(10)) { if (x / 2 * 2 != x) { continue } let s = s + x; }; s", 20},
+		{"let s = 0; for (x in range(10)) { if (x > 3) { break } let s = s + x; }; s", 6},
+		{"let s = 0; let i = 0; while (i < 10) { let i = i + 1; if (i > 2) { continue; } let s = s + i; }; s", 3},
+		{`let s = 0;
+		  for (i in range(1, 4)) {
+		    for (j in range(1, 4)) {
+		      if (j > i) { break; }
+		      let s = s + j;
+		    }
+		  };
+		  s`, 10},
+
+		// the loop is left by a return of the function around it
+		{"let find = fn(xs, y) { for (i, x in xs) { if (x == y) { return i; } }; -1 }; find([4, 5, 6], 6)", 2},
+		{"let find = fn(xs, y) { for (i, x in xs) { if (x == y) { return i; } }; -1 }; find([4, 5, 6], 7)", -1},
+		{"let f = fn() { let i = 0; while (true) { let i = i + 1; if (i == 3) { return i * 10; } } }; f()", 30},
+		// break in a nested function ends the loop inside it only
+		{"let s = 0; for (x in range(3)) { let f = fn() { while (true) { break; }; x }; let s = s + f(); }; s", 3},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			testIntegerObject(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestLoopIsNotAValue(t *testing.T) {
+	tests := []string{
+		"let f = fn() { for (x in [1]) { x } }; f()",
+		"let f = fn() { while (false) { } }; f()",
+	}
+
+	for _, input := range tests {
+		forEachBackend(t, input, func(t *testing.T, evaluated runtime.Object) {
+			testNilObject(t, evaluated)
+		})
+	}
+}
+
+func TestLoopErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		pos      string
+	}{
+		{"for (x in 5) { x }", "cannot iterate over Integer", "1:11"},
+		{"let i = 0;\nwhile (i < 3) { let i = i + true; }", "type mismatch: Integer + Boolean", "2:25"},
+		{"for (x in [1, 2]) {\n  x - \"a\"\n}", "type mismatch: Integer - String", "2:3"},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			err, ok := evaluated.(*runtime.Error)
+			if !ok {
+				assert.Failf(t, "assert failed", "result must be error, got %T", evaluated)
+				return
+			}
+			assert.Equal(t, tt.expected, err.Message)
+			assert.Equal(t, tt.pos, err.Pos.String())
+		})
+	}
+}
@@ -1,11 +1,15 @@
 package evaluator
 
 import (
+	"context"
 	"testing"
 
 	"github.com/NishanthSpShetty/monkey/lexer"
 	"github.com/NishanthSpShetty/monkey/parser"
+	"github.com/NishanthSpShetty/monkey/runtime/compiler"
 	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/NishanthSpShetty/monkey/runtime/vm"
+	"github.com/NishanthSpShetty/monkey/token"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -32,17 +36,43 @@ func TestEvalIntegerExpr(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
-
-		testIntegerObject(t, evaluated, tt.expected)
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			testIntegerObject(t, evaluated, tt.expected)
+		})
 	}
 }
 
 func testEval(input string) runtime.Object {
-	return Eval(runtime.New(),
+	return Eval(context.Background(), runtime.New(),
 		parser.New(lexer.New(input)).ParseProgram())
 }
 
+func testVM(input string) runtime.Object {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	c := compiler.New()
+	if err := c.Compile(program); err != nil {
+		return runtime.NewError("compile error: %s", err)
+	}
+	return vm.New(c.Bytecode()).Run()
+}
+
+// backends are the engines the eval test cases run against, they must agree on every result.
+var backends = []struct {
+	name string
+	eval func(input string) runtime.Object
+}{
+	{"evaluator", testEval},
+	{"vm", testVM},
+}
+
+func forEachBackend(t *testing.T, input string, check func(t *testing.T, evaluated runtime.Object)) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			check(t, b.eval(input))
+		})
+	}
+}
+
 func testIntegerObject(t *testing.T, obj runtime.Object, exp int64) {
 	er, ok := obj.(*runtime.Error)
 	if ok {
@@ -86,15 +116,18 @@ func TestEvalBoolean(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
-
-		testBoolObject(t, evaluated, tt.expected)
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			testBoolObject(t, evaluated, tt.expected)
+		})
 	}
 }
 
 func testBoolObject(t *testing.T, obj runtime.Object, exp bool) {
 	io, ok := obj.(*runtime.Boolean)
-	assert.True(t, ok, "runtime must be Boolean object")
+	assert.Truef(t, ok, "runtime must be Boolean object, got %T", obj)
+	if io == nil {
+		return
+	}
 	assert.Equal(t, exp, io.Value)
 	assert.Equal(t, runtime.ObjBoolean, io.Type())
 }
@@ -112,8 +145,9 @@ func TestBangExp(t *testing.T) {
 		{"!!5", true},
 	}
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
-		testBoolObject(t, evaluated, tt.expected)
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			testBoolObject(t, evaluated, tt.expected)
+		})
 	}
 }
 
@@ -132,14 +166,15 @@ func TestIfExpression(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		eval := testEval(tt.input)
-		i, ok := tt.expected.(int)
-
-		if ok {
-			testIntegerObject(t, eval, int64(i))
-		} else {
-			testNilObject(t, eval)
-		}
+		forEachBackend(t, tt.input, func(t *testing.T, eval runtime.Object) {
+			i, ok := tt.expected.(int)
+
+			if ok {
+				testIntegerObject(t, eval, int64(i))
+			} else {
+				testNilObject(t, eval)
+			}
+		})
 	}
 }
 
@@ -165,8 +200,9 @@ if (10 > 1) {
 }`, 10},
 	}
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
-		testIntegerObject(t, evaluated, tt.expected)
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			testIntegerObject(t, evaluated, tt.expected)
+		})
 	}
 }
 
@@ -219,40 +255,45 @@ return 1;
 		},
 	}
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
-		errObj, ok := evaluated.(*runtime.Error)
-		assert.Truef(t, ok, "result must be Error Object, got %T", evaluated)
-		assert.Equal(t, tt.expectedMessage, errObj.Message)
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			errObj, ok := evaluated.(*runtime.Error)
+			assert.Truef(t, ok, "result must be Error Object, got %T", evaluated)
+			if ok {
+				assert.Equal(t, tt.expectedMessage, errObj.Message)
+			}
+		})
 	}
 }
 
 func TestString(t *testing.T) {
 	input := `"hello world"`
 
-	eval := testEval(input)
-	str, ok := eval.(*runtime.String)
-	assert.True(t, ok, "expected string literal")
-	assert.Equal(t, "hello world", str.Value, "string dint match")
+	forEachBackend(t, input, func(t *testing.T, eval runtime.Object) {
+		str, ok := eval.(*runtime.String)
+		assert.True(t, ok, "expected string literal")
+		assert.Equal(t, "hello world", str.Value, "string dint match")
+	})
 }
 
 func TestStringConcat(t *testing.T) {
 	input := `"hello" + " " + "nishanth!"`
-	eval := testEval(input)
-
-	str, ok := eval.(*runtime.String)
-	assert.True(t, ok, "expected string literal")
-	assert.Equal(t, "hello nishanth!", str.Value, "string dint match")
+	forEachBackend(t, input, func(t *testing.T, eval runtime.Object) {
+		str, ok := eval.(*runtime.String)
+		assert.True(t, ok, "expected string literal")
+		assert.Equal(t, "hello nishanth!", str.Value, "string dint match")
+	})
 }
 
 func TestArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
-	evaluated := testEval(input)
-	result, ok := evaluated.(*runtime.Array)
-	assert.True(t, ok, "expected array literal")
-	assert.Equal(t, 3, len(result.Elements), "must have 3 elements")
-	testIntegerObject(t, result.Elements[0], 1)
-	testIntegerObject(t, result.Elements[1], 4)
-	testIntegerObject(t, result.Elements[2], 6)
+	forEachBackend(t, input, func(t *testing.T, evaluated runtime.Object) {
+		result, ok := evaluated.(*runtime.Array)
+		assert.True(t, ok, "expected array literal")
+		assert.Equal(t, 3, len(result.Elements), "must have 3 elements")
+		testIntegerObject(t, result.Elements[0], 1)
+		testIntegerObject(t, result.Elements[1], 4)
+		testIntegerObject(t, result.Elements[2], 6)
+	})
 }
 
 func TestArrayIndexExpressions(t *testing.T) {
@@ -302,13 +343,14 @@ func TestArrayIndexExpressions(t *testing.T) {
 		},
 	}
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
-		integer, ok := tt.expected.(int)
-		if ok {
-			testIntegerObject(t, evaluated, int64(integer))
-		} else {
-			testNilObject(t, evaluated)
-		}
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			integer, ok := tt.expected.(int)
+			if ok {
+				testIntegerObject(t, evaluated, int64(integer))
+			} else {
+				testNilObject(t, evaluated)
+			}
+		})
 	}
 }
 
@@ -322,28 +364,60 @@ two: 1 + 1,
 true: 5,
 false: 6
 }`
-	evaluated := testEval(input)
-	result, ok := evaluated.(*runtime.Hash)
-	assert.True(t, ok, "evaluated result is a hash")
-
-	expected := map[runtime.HashKey]int64{
-		(&runtime.String{Value: "one"}).HashKey():   1,
-		(&runtime.String{Value: "two"}).HashKey():   2,
-		(&runtime.String{Value: "three"}).HashKey(): 3,
-		(&runtime.Integer{Value: 4}).HashKey():      4,
-		runtime.True.HashKey():                      5,
-		runtime.False.HashKey():                     6,
-	}
+	forEachBackend(t, input, func(t *testing.T, evaluated runtime.Object) {
+		result, ok := evaluated.(*runtime.Hash)
+		assert.True(t, ok, "evaluated result is a hash")
+
+		expected := map[runtime.HashKey]int64{
+			(&runtime.String{Value: "one"}).HashKey():   1,
+			(&runtime.String{Value: "two"}).HashKey():   2,
+			(&runtime.String{Value: "three"}).HashKey(): 3,
+			(&runtime.Integer{Value: 4}).HashKey():      4,
+			runtime.True.HashKey():                      5,
+			runtime.False.HashKey():                     6,
+		}
+
+		assert.Equal(t, result.Len(), len(expected), "must return expected number of pairs")
 
-	assert.Equal(t, len(result.Pairs), len(expected), "must return expected number of pairs")
+		for ek, ev := range expected {
+			var val runtime.Object
+			for _, pair := range result.Pairs() {
+				if pair.Key.(runtime.Hashtable).HashKey() == ek {
+					val = pair.Value
+				}
+			}
+			assert.NotNil(t, val, "must have a value in hash")
+
+			testIntegerObject(t, val, ev)
+		}
+		assert.Equal(t, "{one:1, two:2, three:3, 4:4, true:5, false:6}", result.Inspect(), "pairs are in source order")
+	})
 
-	for ek, ev := range expected {
-		val, ok := result.Pairs[ek]
-		assert.True(t, ok, "must have a value in hash")
+}
 
-		testIntegerObject(t, val.Value, ev)
+func TestHashOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"z": 1, "a": 2, "m": 3}`, "{z:1, a:2, m:3}"},
+		{`let h = {"z": 1, "a": 2}; h["b"] = 3; h["z"] = 4; h`, "{z:4, a:2, b:3}"},
+		{`let h = {"z": 1, "a": 2, "b": 3}; delete(h, "a"); h["a"] = 5; h`, "{z:1, b:3, a:5}"},
+		{`{1: "x", 1.0: "y"}`, "{1:y}"},
+		{`keys({"z": 1, "a": 2, "m": 3})`, "[z, a, m]"},
+		{`values({"z": 1, "a": 2, "m": 3})`, "[1, 2, 3]"},
+		{`let out = []; for (k, v in {"z": 1, "a": 2, "m": 3}) { push(out, k) }; out`, "[z, a, m]"},
+		{`merge({"b": 1, "a": 2}, {"c": 3, "b": 4})`, "{b:4, a:2, c:3}"},
+		{`map({"x": 1, "y": 2}, fn(k) { k + k })`, "[xx, yy]"},
+		{`group_by([3, 1, 2, 4], fn(x) { x / 2 * 2 == x })`, "{false:[3, 1], true:[2, 4]}"},
+		{`{}`, "{}"},
 	}
 
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			assert.Equal(t, tt.expected, evaluated.Inspect(), tt.input)
+		})
+	}
 }
 
 func TestHashIndexExpression(t *testing.T) {
@@ -381,13 +455,31 @@ func TestHashIndexExpression(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
-		integer, ok := tt.expected.(int)
-		if ok {
-			testIntegerObject(t, evaluated, int64(integer))
-		} else {
-			testNilObject(t, evaluated)
-		}
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			integer, ok := tt.expected.(int)
+			if ok {
+				testIntegerObject(t, evaluated, int64(integer))
+			} else {
+				testNilObject(t, evaluated)
+			}
+		})
 	}
 
 }
+
+func TestErrorPosition(t *testing.T) {
+	input := "let a = 5;\nlet b = a +\n  true;"
+
+	forEachBackend(t, input, func(t *testing.T, evaluated runtime.Object) {
+		errObj, ok := evaluated.(*runtime.Error)
+		assert.Truef(t, ok, "result must be Error Object, got %T", evaluated)
+		if !ok {
+			return
+		}
+
+		assert.Equal(t, "2:9", errObj.Pos.String())
+		assert.Equal(t, "main.mk:2:9: Error: type mismatch: Integer + Boolean\n"+
+			"    let b = a +\n"+
+			"            ^", errObj.Render(token.NewFile("main.mk", input)))
+	})
+}
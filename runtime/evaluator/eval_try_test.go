@@ -0,0 +1,123 @@
+package evaluator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let r = 0; try { r = 1 + true } catch (e) { r = e["message"] }; r`, "type mismatch: Integer + Boolean"},
+		{`let r = 0; try { r = 1 + true } catch (e) { r = e["kind"] }; r`, "TypeError"},
+		{`let r = 0; try { r = nope } catch (e) { r = [e["kind"], e["message"]] }; r`, "[NameError, identifier not found: nope]"},
+		{`let r = 0; try { len(1, 2) } catch (e) { r = e }; r`, "ArgumentError: wrong number of arguments. got=2, want=1"},
+		{`let r = 0; try { throw "boom" } catch (e) { r = [e["kind"], e["message"], e["value"]] }; r`, "[Error, boom, boom]"},
+		{`let r = 0; try { throw {"code": 2} } catch (e) { r = e["value"]["code"] }; r`, "2"},
+		{`let r = 0; try { throw error("bad input", "ValueError") } catch (e) { r = e }; r`, "ValueError: bad input"},
+		{`let r = 0; try { throw error("bad") } catch (e) { r = [e["kind"], e["value"], e["line"], e["column"]] }; r`, "[Error, Nil, 1, 18]"},
+		{`let r = 0; try { r = 1 } catch (e) { r = 2 }; r`, "1"},
+		{`let r = 0; try { throw 1 } catch { r = 2 }; r`, "2"},
+
+		// errors raised in functions, and in functions called by builtins
+		{`let f = fn(x) { if (x > 1) { throw x } x }
+let r = []; for (x in range(4)) { try { push(r, f(x)) } catch (e) { push(r, -e["value"]) } }; r`, "[0, 1, -2, -3]"},
+		{`let r = 0; try { map([1, 2], fn(x) { x + "a" }) } catch (e) { r = e["message"] }; r`, "type mismatch: Integer + String"},
+		{`map([1, 2, 3], fn(x) { try { if (x == 2) { throw x }; return x } catch (e) { return 0 } })`, "[1, 0, 3]"},
+		{`let f = fn() { try { throw 1 } catch (e) { return e["value"] + 1 }; 0 }; f()`, "2"},
+		{`let f = fn(n) { if (n == 0) { throw "deep" } f(n - 1) }
+let r = 0; try { f(10) } catch (e) { r = len(e["stack"]) }; r`, "11"},
+		{`let f = fn(x) { x + true }; let r = 0; try { f(1) } catch (e) { r = e["stack"] }; r`, "[f(1) at 1:46]"},
+
+		// nested tries and rethrowing
+		{`let r = []; try { try { throw 1 } catch (e) { push(r, "inner"); throw e } } catch (e) { push(r, e["value"]) }; r`, "[inner, 1]"},
+		{`let r = 0; try { try { throw 1 } catch (e) { throw e["value"] + 1 } } catch (e) { r = e["value"] }; r`, "2"},
+		{`let r = 0; try { try { throw 1 } finally { r = 5 } } catch (e) { r += e["value"] }; r`, "6"},
+
+		// finally
+		{`let r = []; try { push(r, 1) } catch (e) { push(r, 2) } finally { push(r, 3) }; r`, "[1, 3]"},
+		{`let r = []; try { throw 0 } catch (e) { push(r, 2) } finally { push(r, 3) }; r`, "[2, 3]"},
+		{`let r = []; try { try { throw 0 } catch (e) { throw 1 } finally { push(r, 3) } } catch (e) { push(r, e["value"]) }; r`, "[3, 1]"},
+		{`let r = []; let f = fn() { try { return 1 } finally { push(r, 2) } }; push(r, f()); r`, "[2, 1]"},
+		{`let f = fn() { try { return 1 } finally { return 2 } }; f()`, "2"},
+		{`let f = fn() { try { throw 1 } finally { return 2 } }; f()`, "2"},
+		{`let r = []; for (i in range(4)) { try { if (i == 1) { continue } if (i == 3) { break } push(r, i) } finally { push(r, -i) } }; r`, "[0, 0, -1, 2, -2, -3]"},
+		{`let r = []; try { for (i in range(3)) { try { if (i == 1) { break } } finally { push(r, i) } } } finally { push(r, "out") }; r`, "[0, 1, out]"},
+		{`let r = 0; let f = fn() { for (i in range(3)) { try { return i } finally { r = 7 } } }; [f(), r]`, "[0, 7]"},
+		{`let r = 0; let a = []; let f = fn() { try { a = map([1], fn(x) { try { throw x } finally { r += 1 } }) } catch (e) { r += 10 } }; f(); r`, "11"},
+
+		// the error is bound in the scope around the try, like a let in the catch
+		{`let e = 1; try { throw 2 } catch (e) { e }; e["value"]`, "2"},
+		{`let e = 5; try { throw 1 } catch (e) { 0 }; e`, "Error: 1"},
+		{`let e = 5; let f = fn() { try { throw 1 } catch (e) { 0 }; e }; [f(), e]`, "[Error: 1, 5]"},
+		{`let f = fn() { let e = 5; try { throw 1 } catch (e) { 0 }; e }; f()`, "Error: 1"},
+
+		// a try is a statement
+		{`let f = fn() { try { 1 } catch (e) { 2 } }; f()`, "Nil"},
+
+		// uncaught errors
+		{`try { throw "no catch" } finally { 1 }`, "Error: no catch"},
+		{`throw error("bad", "ValueError")`, "Error: bad"},
+		{`throw 1 + true`, "Error: type mismatch: Integer + Boolean"},
+		{`try { 1 } catch (e) { e + 1 }; e`, "Error: identifier not found: e"},
+		{`error(1)`, "Error: argument 1 to `error` must be String, got Integer"},
+		{`let e = error("x"); e["nope"]`, "Nil"},
+		{`let e = error("x"); e[0]`, "Error: error field must be String, got Integer"},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			assert.Equal(t, tt.expected, evaluated.Inspect(), tt.input)
+		})
+	}
+}
+
+func TestThrowPosition(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   string
+		kind  string
+	}{
+		{"let f = fn() {\n  throw error(\"x\", \"E\")\n};\nf()", "2:3", "E"},
+		{"try { 1 } catch (e) { 2 };\n[1][0] + nil", "2:10", runtime.KindName},
+		{"let f = fn() { 1 + true };\ntry { f() } catch (e) { throw e }", "1:16", runtime.KindType},
+	}
+
+	for _, tt := range tests {
+		forEachBackend(t, tt.input, func(t *testing.T, evaluated runtime.Object) {
+			err, ok := evaluated.(*runtime.Error)
+			if !ok {
+				assert.Failf(t, "assert failed", "result must be error, got %T", evaluated)
+				return
+			}
+			assert.Equal(t, tt.pos, err.Pos.String())
+			assert.Equal(t, tt.kind, err.KindName())
+		})
+	}
+}
+
+func TestLimitsAreNotCaught(t *testing.T) {
+	tests := []struct {
+		input  string
+		limits runtime.Limits
+		limit  runtime.Limit
+	}{
+		{"let f = fn() { f() }; try { f() } catch (e) { 1 }", runtime.Limits{MaxDepth: 50}, runtime.DepthLimit},
+		{"try { while (true) {} } catch (e) { 1 } finally { 2 }", runtime.Limits{MaxSteps: 1000}, runtime.StepLimit},
+	}
+
+	for _, tt := range tests {
+		evaluated := evalWithLimits(context.Background(), tt.limits, tt.input)
+		err, ok := evaluated.(*runtime.Error)
+		if !ok {
+			assert.Failf(t, "assert failed", "result must be error, got %T", evaluated)
+			continue
+		}
+		assert.Equal(t, tt.limit, err.Limit, tt.input)
+	}
+}
@@ -0,0 +1,126 @@
+package runtime
+
+import (
+	"hash/fnv"
+	"math"
+	"math/big"
+)
+
+// BigInt is an integer outside the int64 range. Integer arithmetic promotes
+// to a BigInt when it overflows and results are brought back to an Integer
+// once they fit again, so a BigInt never holds a value an Integer can.
+type BigInt struct {
+	Value *big.Int
+}
+
+func (b *BigInt) Inspect() string {
+	return b.Value.String()
+}
+
+func (b *BigInt) Type() ObjectType {
+	return ObjBigInt
+}
+
+func (b *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	if b.Value.Sign() < 0 {
+		h.Write([]byte{'-'})
+	}
+	h.Write(b.Value.Bytes())
+	return HashKey{
+		Type:  b.Type(),
+		Value: h.Sum64(),
+	}
+}
+
+// NewInteger returns v as an Integer when it fits into an int64 and as a BigInt otherwise.
+func NewInteger(v *big.Int) Object {
+	if v.IsInt64() {
+		return &Integer{Value: v.Int64()}
+	}
+	return &BigInt{Value: v}
+}
+
+// ToBig returns the value of an Integer or BigInt as a big.Int, which must not be modified.
+func ToBig(obj Object) (*big.Int, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return big.NewInt(obj.Value), true
+	case *BigInt:
+		return obj.Value, true
+	default:
+		return nil, false
+	}
+}
+
+func isInteger(obj Object) bool {
+	return obj.Type() == ObjInteger || obj.Type() == ObjBigInt
+}
+
+// floatToInteger truncates f towards zero, promoting to a BigInt when needed.
+func floatToInteger(f float64) (Object, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, false
+	}
+	if math.Abs(f) < 1<<63 {
+		return &Integer{Value: int64(f)}, true
+	}
+	i, _ := big.NewFloat(f).Int(nil)
+	return NewInteger(i), true
+}
+
+func evalBigIntInfixExpression(op string, left, right Object) Object {
+	lval, _ := ToBig(left)
+	rval, _ := ToBig(right)
+
+	res := new(big.Int)
+	switch op {
+	case "+":
+		res.Add(lval, rval)
+	case "-":
+		res.Sub(lval, rval)
+	case "*":
+		res.Mul(lval, rval)
+	case "/":
+		if rval.Sign() == 0 {
+			return NewError("division by zero")
+		}
+		// truncated like int64 division
+		res.Quo(lval, rval)
+	case "<":
+		return NativeBool(lval.Cmp(rval) < 0)
+	case ">":
+		return NativeBool(lval.Cmp(rval) > 0)
+	case "==":
+		return NativeBool(lval.Cmp(rval) == 0)
+	case "!=":
+		return NativeBool(lval.Cmp(rval) != 0)
+	default:
+		return NewTypeError("unknown operator: %s %s %s", left.Type(), op, right.Type())
+	}
+
+	return NewInteger(res)
+}
+
+// overflows reports whether lval op rval does not fit into an int64.
+func overflows(op string, lval, rval int64) bool {
+	switch op {
+	case "+":
+		res := lval + rval
+		return (lval > 0 && rval > 0 && res < 0) || (lval < 0 && rval < 0 && res >= 0)
+	case "-":
+		res := lval - rval
+		return (lval >= 0 && rval < 0 && res < 0) || (lval < 0 && rval > 0 && res >= 0)
+	case "*":
+		if lval == 0 || rval == 0 {
+			return false
+		}
+		if (lval == -1 && rval == math.MinInt64) || (rval == -1 && lval == math.MinInt64) {
+			return true
+		}
+		return (lval*rval)/rval != lval
+	case "/":
+		return lval == math.MinInt64 && rval == -1
+	}
+	return false
+}
@@ -1,9 +1,74 @@
 package runtime
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ObjAny matches an argument of any type in a builtin signature.
+const ObjAny ObjectType = "Any"
+
+// Caller is handed to builtins by the backend running them, whichever it is.
+type Caller interface {
+	// Call calls the function value fn with args.
+	Call(fn Object, args ...Object) Object
+	// Step counts a step of the work of a builtin walking a collection,
+	// failing when the steps run out or the evaluation is canceled.
+	Step() *Error
+	// Alloc counts size bytes of the value a builtin is building, failing
+	// when more than the limit has been allocated.
+	Alloc(size int64) *Error
+}
 
 type Builtin struct {
 	Fn BuiltinFunction
+	// CallFn is set instead of Fn by builtins which call functions passed to them.
+	CallFn func(call Caller, args ...Object) Object
+}
+
+// Call runs the builtin with args, call runs functions passed as arguments
+// and keeps track of the work done.
+func (b *Builtin) Call(call Caller, args ...Object) Object {
+	if b.CallFn != nil {
+		return b.CallFn(call, args...)
+	}
+	return b.Fn(args...)
+}
+
+// NewBuiltin wraps fn so it is only called with exactly len(params) arguments,
+// each of the type listed in params.
+func NewBuiltin(name string, params []ObjectType, fn BuiltinFunction) *Builtin {
+	return &Builtin{
+		Fn: func(args ...Object) Object {
+			if err := checkArgs(name, args, len(params), params...); err != nil {
+				return err
+			}
+			return fn(args...)
+		},
+	}
+}
+
+// checkArgs validates args against params, of which the first min are required.
+func checkArgs(name string, args []Object, min int, params ...ObjectType) *Error {
+	if len(args) < min || len(args) > len(params) {
+		want := strconv.Itoa(len(params))
+		if min != len(params) {
+			want = fmt.Sprintf("%d to %d", min, len(params))
+		}
+		return NewArgumentError("wrong number of arguments to `%s`. got=%d, want=%s", name, len(args), want)
+	}
+	for i, arg := range args {
+		if params[i] != ObjAny && arg.Type() != params[i] {
+			return NewTypeError("argument %d to `%s` must be %s, got %s", i+1, name, params[i], arg.Type())
+		}
+	}
+	return nil
 }
 
 func (b *Builtin) Type() ObjectType { return ObjBuiltin }
@@ -15,26 +80,132 @@ func fnLen() *Builtin {
 	return &Builtin{
 		Fn: func(args ...Object) Object {
 			if len(args) != 1 {
-				return NewError("wrong number of arguments. got=%d, want=1", len(args))
+				return NewArgumentError("wrong number of arguments. got=%d, want=1", len(args))
 			}
 			switch arg := args[0].(type) {
 			case *String:
-				return &Integer{Value: int64(len(arg.Value))}
+				// characters, byte_len counts the bytes of the UTF-8 encoding
+				return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 			case *Array:
 				return &Integer{Value: arg.Len()}
+			case *Tuple:
+				return &Integer{Value: arg.Len()}
+			case *Range:
+				return arg.Len()
 			default:
-				return NewError("argument to `len` not supported, got %s",
+				return NewTypeError("argument to `len` not supported, got %s",
 					args[0].Type())
 			}
 		},
 	}
 }
 
-func fnPuts() *Builtin {
+// fnError makes error(message) and error(message, kind), an error value
+// which throw raises.
+func fnError() *Builtin {
+	return &Builtin{
+		Fn: func(args ...Object) Object {
+			if err := checkArgs("error", args, 1, ObjString, ObjString); err != nil {
+				return err
+			}
+			err := NewError("%s", args[0].(*String).Value)
+			if len(args) == 2 {
+				err.Kind = args[1].(*String).Value
+			}
+			return &ErrorValue{Err: err}
+		},
+	}
+}
+
+// fnRange makes range(stop), range(start, stop) and range(start, stop, step).
+func fnRange() *Builtin {
+	return &Builtin{
+		Fn: func(args ...Object) Object {
+			if err := checkArgs("range", args, 1, ObjInteger, ObjInteger, ObjInteger); err != nil {
+				return err
+			}
+			bounds := make([]int64, len(args))
+			for i, arg := range args {
+				bounds[i] = arg.(*Integer).Value
+			}
+
+			r := &Range{Step: 1}
+			switch len(bounds) {
+			case 1:
+				r.Stop = bounds[0]
+			case 2:
+				r.Start, r.Stop = bounds[0], bounds[1]
+			case 3:
+				r.Start, r.Stop, r.Step = bounds[0], bounds[1], bounds[2]
+			}
+			if r.Step == 0 {
+				return NewError("range step must not be zero")
+			}
+			return r
+		},
+	}
+}
+
+func fnByteLen() *Builtin {
+	return NewBuiltin("byte_len", []ObjectType{ObjString}, func(args ...Object) Object {
+		return &Integer{Value: int64(len(args[0].(*String).Value))}
+	})
+}
+
+func fnInt() *Builtin {
+	return NewBuiltin("int", []ObjectType{ObjAny}, func(args ...Object) Object {
+		switch arg := args[0].(type) {
+		case *Integer, *BigInt:
+			return arg
+		case *Float:
+			// truncates towards zero
+			i, ok := floatToInteger(arg.Value)
+			if !ok {
+				return NewError("cannot convert %s to Integer", arg.Inspect())
+			}
+			return i
+		case *String:
+			i, ok := new(big.Int).SetString(strings.TrimSpace(arg.Value), 0)
+			if !ok {
+				return NewError("cannot convert %q to Integer", arg.Value)
+			}
+			return NewInteger(i)
+		default:
+			return NewTypeError("argument to `int` not supported, got %s", arg.Type())
+		}
+	})
+}
+
+func fnFloat() *Builtin {
+	return NewBuiltin("float", []ObjectType{ObjAny}, func(args ...Object) Object {
+		switch arg := args[0].(type) {
+		case *Integer, *BigInt:
+			f, _ := ToFloat(arg)
+			return &Float{Value: f}
+		case *Float:
+			return arg
+		case *String:
+			f, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+			if err != nil {
+				return NewError("cannot convert %q to Float", arg.Value)
+			}
+			return &Float{Value: f}
+		default:
+			return NewTypeError("argument to `float` not supported, got %s", arg.Type())
+		}
+	})
+}
+
+// PutsTo returns the puts builtin writing to w, a nil w writes to the standard output.
+func PutsTo(w io.Writer) *Builtin {
 	return &Builtin{
 		Fn: func(args ...Object) Object {
+			out := w
+			if out == nil {
+				out = os.Stdout
+			}
 			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+				fmt.Fprintln(out, arg.Inspect())
 			}
 			return Nil
 		},
@@ -43,11 +214,62 @@ func fnPuts() *Builtin {
 }
 
 var builtins = map[string]*Builtin{
-	"len":  fnLen(),
-	"puts": fnPuts(),
+	"len":      fnLen(),
+	"byte_len": fnByteLen(),
+	"puts":     PutsTo(nil),
+	"range":    fnRange(),
+	"int":      fnInt(),
+	"float":    fnFloat(),
+
+	"push":     fnPush(),
+	"pop":      fnPop(),
+	"first":    fnFirst(),
+	"last":     fnLast(),
+	"rest":     fnRest(),
+	"slice":    fnSlice(),
+	"concat":   fnConcat(),
+	"keys":     fnKeys(),
+	"values":   fnValues(),
+	"has":      fnHas(),
+	"delete":   fnDelete(),
+	"merge":    fnMerge(),
+	"update":   fnUpdate(),
+	"contains": fnContains(),
+	"index_of": fnIndexOf(),
+	"reverse":  fnReverse(),
+	"reversed": fnReversed(),
+	"sort":     fnSort("sort", true),
+	"sorted":   fnSort("sorted", false),
+
+	"map":      fnMap(),
+	"filter":   fnFilter(),
+	"reduce":   fnReduce(),
+	"each":     fnEach(),
+	"find":     fnFind(),
+	"any":      fnAny(),
+	"all":      fnAll(),
+	"zip":      fnZip(),
+	"flat_map": fnFlatMap(),
+	"group_by": fnGroupBy(),
+	"sort_by":  fnSortBy(),
+
+	"tuple": fnTuple(),
+	"array": fnArray(),
+
+	"error": fnError(),
 }
 
 func GetBuiltin(name string) (*Builtin, bool) {
 	b, ok := builtins[name]
 	return b, ok
 }
+
+// BuiltinNames returns the names of the builtin functions, sorted.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,443 @@
+package runtime
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// builtins working on arrays and hashes. push, pop, delete, update, reverse
+// and sort change their first argument, the other builtins leave their
+// arguments alone and return new values.
+
+// checkVariadicArgs validates at least min args against params, the last
+// param type applies to all the remaining args.
+func checkVariadicArgs(name string, args []Object, min int, params ...ObjectType) *Error {
+	if len(args) < min {
+		return NewArgumentError("wrong number of arguments to `%s`. got=%d, want=at least %d", name, len(args), min)
+	}
+	for i, arg := range args {
+		want := params[len(params)-1]
+		if i < len(params) {
+			want = params[i]
+		}
+		if want != ObjAny && arg.Type() != want {
+			return NewTypeError("argument %d to `%s` must be %s, got %s", i+1, name, want, arg.Type())
+		}
+	}
+	return nil
+}
+
+func copyElements(elements []Object) []Object {
+	return append([]Object{}, elements...)
+}
+
+// fnPush appends values to an array in place and returns the array.
+func fnPush() *Builtin {
+	return &Builtin{
+		Fn: func(args ...Object) Object {
+			if err := checkVariadicArgs("push", args, 1, ObjArray, ObjAny); err != nil {
+				return err
+			}
+			arr := args[0].(*Array)
+			arr.Elements = append(arr.Elements, args[1:]...)
+			return arr
+		},
+	}
+}
+
+// fnPop removes the last element of an array and returns it, nil when the array is empty.
+func fnPop() *Builtin {
+	return NewBuiltin("pop", []ObjectType{ObjArray}, func(args ...Object) Object {
+		arr := args[0].(*Array)
+		if len(arr.Elements) == 0 {
+			return Nil
+		}
+		last := arr.Elements[len(arr.Elements)-1]
+		arr.Elements = arr.Elements[:len(arr.Elements)-1]
+		return last
+	})
+}
+
+func fnFirst() *Builtin {
+	return NewBuiltin("first", []ObjectType{ObjAny}, func(args ...Object) Object {
+		elements, ok := elementsOf(args[0])
+		if !ok {
+			return NewTypeError("argument 1 to `first` must be Array or Tuple, got %s", args[0].Type())
+		}
+		if len(elements) == 0 {
+			return Nil
+		}
+		return elements[0]
+	})
+}
+
+func fnLast() *Builtin {
+	return NewBuiltin("last", []ObjectType{ObjAny}, func(args ...Object) Object {
+		elements, ok := elementsOf(args[0])
+		if !ok {
+			return NewTypeError("argument 1 to `last` must be Array or Tuple, got %s", args[0].Type())
+		}
+		if len(elements) == 0 {
+			return Nil
+		}
+		return elements[len(elements)-1]
+	})
+}
+
+// fnRest returns a new array of all elements but the first.
+func fnRest() *Builtin {
+	return NewBuiltin("rest", []ObjectType{ObjArray}, func(args ...Object) Object {
+		arr := args[0].(*Array)
+		if len(arr.Elements) == 0 {
+			return &Array{Elements: []Object{}}
+		}
+		return &Array{Elements: copyElements(arr.Elements[1:])}
+	})
+}
+
+// fnSlice makes slice(x, start) and slice(x, start, end) of an array or a
+// string. Negative bounds count from the end and bounds are clamped to the
+// length, like the index expression out of range is not an error.
+func fnSlice() *Builtin {
+	return &Builtin{
+		Fn: func(args ...Object) Object {
+			if err := checkArgs("slice", args, 2, ObjAny, ObjInteger, ObjInteger); err != nil {
+				return err
+			}
+
+			var length int64
+			switch x := args[0].(type) {
+			case *Array:
+				length = x.Len()
+			case *String:
+				length = int64(utf8.RuneCountInString(x.Value))
+			default:
+				return NewTypeError("argument 1 to `slice` must be Array or String, got %s", x.Type())
+			}
+
+			start := sliceBound(args[1].(*Integer).Value, length)
+			end := length
+			if len(args) == 3 {
+				end = sliceBound(args[2].(*Integer).Value, length)
+			}
+			if end < start {
+				end = start
+			}
+
+			if arr, ok := args[0].(*Array); ok {
+				return &Array{Elements: copyElements(arr.Elements[start:end])}
+			}
+			runes := []rune(args[0].(*String).Value)
+			return &String{Value: string(runes[start:end])}
+		},
+	}
+}
+
+func sliceBound(i, length int64) int64 {
+	if i < 0 {
+		i += length
+	}
+	switch {
+	case i < 0:
+		return 0
+	case i > length:
+		return length
+	}
+	return i
+}
+
+// fnConcat returns a new array with the elements of all its arguments.
+func fnConcat() *Builtin {
+	return &Builtin{
+		CallFn: func(call Caller, args ...Object) Object {
+			if err := checkVariadicArgs("concat", args, 1, ObjArray); err != nil {
+				return err
+			}
+			elements := []Object{}
+			for _, arg := range args {
+				// charged before copying, an array may be passed many times
+				more := arg.(*Array).Elements
+				if err := call.Alloc(elementSize * int64(len(more))); err != nil {
+					return err
+				}
+				elements = append(elements, more...)
+			}
+			return &Array{Elements: elements}
+		},
+	}
+}
+
+func fnKeys() *Builtin {
+	return NewBuiltin("keys", []ObjectType{ObjHash}, func(args ...Object) Object {
+		keys := []Object{}
+		for _, pair := range args[0].(*Hash).Pairs() {
+			keys = append(keys, pair.Key)
+		}
+		return &Array{Elements: keys}
+	})
+}
+
+func fnValues() *Builtin {
+	return NewBuiltin("values", []ObjectType{ObjHash}, func(args ...Object) Object {
+		values := []Object{}
+		for _, pair := range args[0].(*Hash).Pairs() {
+			values = append(values, pair.Value)
+		}
+		return &Array{Elements: values}
+	})
+}
+
+func fnHas() *Builtin {
+	return NewBuiltin("has", []ObjectType{ObjHash, ObjAny}, func(args ...Object) Object {
+		_, ok, err := args[0].(*Hash).Get(args[1])
+		if err != nil {
+			return err
+		}
+		return NativeBool(ok)
+	})
+}
+
+// fnDelete removes a key from a hash in place and returns its value, nil when it was missing.
+func fnDelete() *Builtin {
+	return NewBuiltin("delete", []ObjectType{ObjHash, ObjAny}, func(args ...Object) Object {
+		value, ok, err := args[0].(*Hash).Delete(args[1])
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return Nil
+		}
+		return value
+	})
+}
+
+// fnMerge returns a new hash with the pairs of all its arguments, later
+// hashes win when a key is in several of them.
+func fnMerge() *Builtin {
+	return &Builtin{
+		Fn: func(args ...Object) Object {
+			if err := checkVariadicArgs("merge", args, 1, ObjHash); err != nil {
+				return err
+			}
+			merged := NewHash()
+			mergeInto(merged, args)
+			return merged
+		},
+	}
+}
+
+// fnUpdate is merge in place, it adds the pairs of the other hashes to the first one.
+func fnUpdate() *Builtin {
+	return &Builtin{
+		Fn: func(args ...Object) Object {
+			if err := checkVariadicArgs("update", args, 1, ObjHash); err != nil {
+				return err
+			}
+			h := args[0].(*Hash)
+			mergeInto(h, args[1:])
+			return h
+		},
+	}
+}
+
+func mergeInto(dst *Hash, hashes []Object) {
+	for _, h := range hashes {
+		for _, pair := range h.(*Hash).Pairs() {
+			// the key was hashed when it was stored
+			dst.Set(pair.Key, pair.Value)
+		}
+	}
+}
+
+// fnContains reports whether an array or a tuple holds a value or a string holds a substring.
+func fnContains() *Builtin {
+	return NewBuiltin("contains", []ObjectType{ObjAny, ObjAny}, func(args ...Object) Object {
+		i := indexOf("contains", args[0], args[1])
+		if err, ok := i.(*Error); ok {
+			return err
+		}
+		return NativeBool(i.(*Integer).Value >= 0)
+	})
+}
+
+// fnIndexOf returns the position of a value in an array or a tuple or of a substring,
+// counted in characters, in a string, -1 when it is not found.
+func fnIndexOf() *Builtin {
+	return NewBuiltin("index_of", []ObjectType{ObjAny, ObjAny}, func(args ...Object) Object {
+		return indexOf("index_of", args[0], args[1])
+	})
+}
+
+func indexOf(name string, x, v Object) Object {
+	if elements, ok := elementsOf(x); ok {
+		for i, e := range elements {
+			if Equal(e, v) {
+				return &Integer{Value: int64(i)}
+			}
+		}
+		return &Integer{Value: -1}
+	}
+	switch x := x.(type) {
+	case *String:
+		sub, ok := v.(*String)
+		if !ok {
+			return NewTypeError("argument 2 to `%s` must be String, got %s", name, v.Type())
+		}
+		i := strings.Index(x.Value, sub.Value)
+		if i < 0 {
+			return &Integer{Value: -1}
+		}
+		return &Integer{Value: int64(utf8.RuneCountInString(x.Value[:i]))}
+	default:
+		return NewTypeError("argument 1 to `%s` must be Array, Tuple or String, got %s", name, x.Type())
+	}
+}
+
+// fnTuple makes a tuple of the elements of an iterable, tuple() is the empty
+// tuple. Tuples cannot be changed, so unlike arrays they can be hash keys.
+func fnTuple() *Builtin {
+	return &Builtin{
+		CallFn: func(call Caller, args ...Object) Object {
+			if err := checkArgs("tuple", args, 0, ObjAny); err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				return &Tuple{Elements: []Object{}}
+			}
+			elements, err := collect(call, "tuple", args[0])
+			if err != nil {
+				return err
+			}
+			return &Tuple{Elements: elements}
+		},
+	}
+}
+
+// fnArray returns a new array of the elements of an iterable.
+func fnArray() *Builtin {
+	return &Builtin{
+		CallFn: func(call Caller, args ...Object) Object {
+			if err := checkArgs("array", args, 1, ObjAny); err != nil {
+				return err
+			}
+			elements, err := collect(call, "array", args[0])
+			if err != nil {
+				return err
+			}
+			return &Array{Elements: elements}
+		},
+	}
+}
+
+// collect returns the elements of x, charging each to call as it goes.
+func collect(call Caller, name string, x Object) ([]Object, *Error) {
+	elements := []Object{}
+	err := iterate(call, name, x, func(v Object) (bool, *Error) {
+		elements = append(elements, v)
+		return false, call.Alloc(elementSize)
+	})
+	return elements, err
+}
+
+// fnReverse reverses an array in place and returns it.
+func fnReverse() *Builtin {
+	return NewBuiltin("reverse", []ObjectType{ObjArray}, func(args ...Object) Object {
+		arr := args[0].(*Array)
+		reverseElements(arr.Elements)
+		return arr
+	})
+}
+
+// fnReversed returns a reversed copy of an array or a string.
+func fnReversed() *Builtin {
+	return NewBuiltin("reversed", []ObjectType{ObjAny}, func(args ...Object) Object {
+		switch x := args[0].(type) {
+		case *Array:
+			elements := copyElements(x.Elements)
+			reverseElements(elements)
+			return &Array{Elements: elements}
+		case *String:
+			runes := []rune(x.Value)
+			for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+				runes[i], runes[j] = runes[j], runes[i]
+			}
+			return &String{Value: string(runes)}
+		default:
+			return NewTypeError("argument 1 to `reversed` must be Array or String, got %s", x.Type())
+		}
+	})
+}
+
+func reverseElements(elements []Object) {
+	for i, j := 0, len(elements)-1; i < j; i, j = i+1, j-1 {
+		elements[i], elements[j] = elements[j], elements[i]
+	}
+}
+
+// fnSort sorts an array in place and returns it, fnSorted returns a sorted
+// copy. Without a comparator numbers and strings are sorted ascending, a
+// comparator fn(a, b) returns true, or a negative integer, when a goes before b.
+func fnSort(name string, inPlace bool) *Builtin {
+	return &Builtin{
+		CallFn: func(call Caller, args ...Object) Object {
+			if err := checkArgs(name, args, 1, ObjArray, ObjAny); err != nil {
+				return err
+			}
+			var cmp Object
+			if len(args) == 2 {
+				cmp = args[1]
+				if err := checkFunction(name, 2, cmp); err != nil {
+					return err
+				}
+			}
+
+			arr := args[0].(*Array)
+			// sort a copy so an error leaves the array as it was
+			elements := copyElements(arr.Elements)
+			if err := sortElements(call, elements, cmp); err != nil {
+				return err
+			}
+			if !inPlace {
+				return &Array{Elements: elements}
+			}
+			copy(arr.Elements, elements)
+			return arr
+		},
+	}
+}
+
+// sortElements sorts elements stably, ordered by the comparator cmp unless it is nil.
+func sortElements(call Caller, elements []Object, cmp Object) *Error {
+	var err *Error
+	sort.SliceStable(elements, func(i, j int) bool {
+		if err == nil {
+			// every comparison is a step, a sort without a comparator takes none otherwise
+			err = call.Step()
+		}
+		if err != nil {
+			return false
+		}
+		var less bool
+		less, err = lessThan(call, cmp, elements[i], elements[j])
+		return less
+	})
+	return err
+}
+
+func lessThan(call Caller, cmp Object, a, b Object) (bool, *Error) {
+	if cmp == nil {
+		c, err := Compare(a, b)
+		return c < 0, err
+	}
+	switch res := call.Call(cmp, a, b).(type) {
+	case *Error:
+		return false, res
+	case *Boolean:
+		return res.Value, nil
+	case *Integer:
+		return res.Value < 0, nil
+	default:
+		return false, NewTypeError("comparator must return Boolean or Integer, got %s", res.Type())
+	}
+}
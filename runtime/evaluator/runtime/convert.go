@@ -0,0 +1,584 @@
+package runtime
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// conversion between Go values and runtime objects, struct fields are matched
+// to hash keys by their `monkey:"name"` tag or, without one, by the field name
+
+var (
+	objectType    = reflect.TypeOf((*Object)(nil)).Elem()
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+	bigIntType    = reflect.TypeOf(big.Int{})
+	bigIntPtrType = reflect.TypeOf((*big.Int)(nil))
+)
+
+// ToObject converts a Go value into a runtime object. Integers, big.Int, floats,
+// booleans, strings, slices, arrays, maps, structs and pointers to them are
+// converted recursively and functions are wrapped as builtins. Objects are returned as
+// they are and nil becomes Nil.
+func ToObject(v interface{}) (Object, error) {
+	if v == nil {
+		return Nil, nil
+	}
+	return toObject(reflect.ValueOf(v), map[visit]bool{})
+}
+
+// visit is a pointer, map or slice being converted. A value met again while
+// converting what it holds refers to itself and would be converted forever.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	// len tells apart slices sharing their start
+	len int
+}
+
+// enter marks v as being converted, it fails when v already is.
+func enter(v reflect.Value, seen map[visit]bool) (visit, error) {
+	key := visit{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if seen[key] {
+		return key, fmt.Errorf("cannot convert %s, it refers to itself", v.Type())
+	}
+	seen[key] = true
+	return key, nil
+}
+
+func toObject(v reflect.Value, seen map[visit]bool) (Object, error) {
+	if v.Type().Implements(objectType) {
+		if v.IsNil() {
+			return Nil, nil
+		}
+		return v.Interface().(Object), nil
+	}
+
+	switch v.Type() {
+	case bigIntType:
+		i := v.Interface().(big.Int)
+		return NewInteger(new(big.Int).Set(&i)), nil
+	case bigIntPtrType:
+		if v.IsNil() {
+			return Nil, nil
+		}
+		return NewInteger(new(big.Int).Set(v.Interface().(*big.Int))), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return NativeBool(v.Bool()), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: v.Int()}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return NewInteger(new(big.Int).SetUint64(v.Uint())), nil
+
+	case reflect.Float32, reflect.Float64:
+		return &Float{Value: v.Float()}, nil
+
+	case reflect.String:
+		return &String{Value: v.String()}, nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return Nil, nil
+			}
+			key, err := enter(v, seen)
+			if err != nil {
+				return nil, err
+			}
+			defer delete(seen, key)
+		}
+		arr := &Array{Elements: make([]Object, v.Len())}
+		for i := 0; i < v.Len(); i++ {
+			el, err := toObject(v.Index(i), seen)
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			arr.Elements[i] = el
+		}
+		return arr, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return Nil, nil
+		}
+		key, err := enter(v, seen)
+		if err != nil {
+			return nil, err
+		}
+		defer delete(seen, key)
+		pairs := []HashPair{}
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := toObject(iter.Key(), seen)
+			if err != nil {
+				return nil, fmt.Errorf("key %v: %w", iter.Key(), err)
+			}
+			val, err := toObject(iter.Value(), seen)
+			if err != nil {
+				return nil, fmt.Errorf("key %v: %w", iter.Key(), err)
+			}
+			pairs = append(pairs, HashPair{Key: key, Value: val})
+		}
+
+		// Go maps have no order, sort the keys so the hash is the same every time
+		sort.Slice(pairs, func(i, j int) bool {
+			if c, err := Compare(pairs[i].Key, pairs[j].Key); err == nil {
+				return c < 0
+			}
+			return pairs[i].Key.Inspect() < pairs[j].Key.Inspect()
+		})
+		h := NewHash()
+		for _, pair := range pairs {
+			if err := h.Set(pair.Key, pair.Value); err != nil {
+				return nil, fmt.Errorf("key %s: %s", pair.Key.Inspect(), err.Message)
+			}
+		}
+		return h, nil
+
+	case reflect.Struct:
+		return structToObject(v, seen)
+
+	case reflect.Pointer:
+		if v.IsNil() {
+			return Nil, nil
+		}
+		key, err := enter(v, seen)
+		if err != nil {
+			return nil, err
+		}
+		defer delete(seen, key)
+		return toObject(v.Elem(), seen)
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return Nil, nil
+		}
+		return toObject(v.Elem(), seen)
+
+	case reflect.Func:
+		if v.IsNil() {
+			return Nil, nil
+		}
+		return funcBuiltin("", v), nil
+	}
+
+	return nil, fmt.Errorf("cannot convert %s to a monkey object", v.Type())
+}
+
+func structToObject(v reflect.Value, seen map[visit]bool) (Object, error) {
+	h := NewHash()
+	for _, f := range structFields(v.Type()) {
+		fv, err := v.FieldByIndexErr(f.index)
+		if err != nil {
+			// promoted through a nil embedded pointer, there is no such field
+			continue
+		}
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+		val, err := toObject(fv, seen)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.goName, err)
+		}
+		h.Set(&String{Value: f.name}, val)
+	}
+	return h, nil
+}
+
+type field struct {
+	name      string
+	goName    string
+	index     []int
+	omitEmpty bool
+}
+
+// structFields lists the exported fields of t which are not tagged `monkey:"-"`.
+func structFields(t reflect.Type) []field {
+	fields := []field{}
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("monkey")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, field{
+			name:      name,
+			goName:    f.Name,
+			index:     f.Index,
+			omitEmpty: opts == "omitempty",
+		})
+	}
+	return fields
+}
+
+// FuncBuiltin wraps the Go function fn as a builtin called name. Arguments are
+// converted with FromObject into the parameter types of fn and its result with
+// ToObject, a non-nil error result is returned as an Error.
+func FuncBuiltin(name string, fn interface{}) (*Builtin, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("cannot wrap %T as a builtin, want a function", fn)
+	}
+	return funcBuiltin(name, v), nil
+}
+
+func funcBuiltin(name string, fn reflect.Value) *Builtin {
+	t := fn.Type()
+	label := "function"
+	if name != "" {
+		label = "`" + name + "`"
+	}
+
+	return &Builtin{
+		Fn: func(args ...Object) Object {
+			fixed := t.NumIn()
+			if t.IsVariadic() {
+				fixed--
+			}
+			if len(args) < fixed || (!t.IsVariadic() && len(args) > fixed) {
+				return NewArgumentError("wrong number of arguments to %s. got=%d, want=%d", label, len(args), t.NumIn())
+			}
+
+			in := make([]reflect.Value, len(args))
+			for i, arg := range args {
+				var pt reflect.Type
+				if i < fixed {
+					pt = t.In(i)
+				} else {
+					pt = t.In(fixed).Elem()
+				}
+				pv := reflect.New(pt)
+				if err := fromObject(arg, pv.Elem()); err != nil {
+					return NewTypeError("argument %d to %s: %s", i+1, label, err)
+				}
+				in[i] = pv.Elem()
+			}
+
+			return funcResult(label, fn.Call(in))
+		},
+	}
+}
+
+// funcResult converts the results of a wrapped function call, which may end in an error.
+func funcResult(label string, out []reflect.Value) Object {
+	if n := len(out); n > 0 && out[n-1].Type() == errorType {
+		if err := out[n-1]; !err.IsNil() {
+			return NewError("%s", err.Interface().(error))
+		}
+		out = out[:n-1]
+	}
+
+	switch len(out) {
+	case 0:
+		return Nil
+	case 1:
+		obj, err := toObject(out[0], map[visit]bool{})
+		if err != nil {
+			return NewError("result of %s: %s", label, err)
+		}
+		return obj
+	}
+
+	arr := &Array{}
+	for _, o := range out {
+		obj, err := toObject(o, map[visit]bool{})
+		if err != nil {
+			return NewError("result of %s: %s", label, err)
+		}
+		arr.Elements = append(arr.Elements, obj)
+	}
+	return arr
+}
+
+// FromObject stores obj into the value target points to, converting it to the
+// Go type of the target. A target of type interface{} receives the Go value
+// closest to obj: int64, *big.Int, float64, string, bool, nil, []interface{}
+// or map[interface{}]interface{}.
+func FromObject(obj Object, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("cannot convert into %T, want a non-nil pointer", target)
+	}
+	return fromObject(obj, v.Elem())
+}
+
+func fromObject(obj Object, v reflect.Value) error {
+	if obj == nil {
+		obj = Nil
+	}
+
+	// objects can be stored as they are
+	if v.Type() == objectType || reflect.TypeOf(obj).AssignableTo(v.Type()) && v.Kind() != reflect.Interface {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+
+	if obj == Nil {
+		switch v.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		return cannotConvert(obj, v.Type())
+	}
+
+	if v.Type() == bigIntType || v.Type() == bigIntPtrType {
+		i, ok := ToBig(obj)
+		if !ok {
+			return cannotConvert(obj, v.Type())
+		}
+		i = new(big.Int).Set(i)
+		if v.Type() == bigIntType {
+			v.Set(reflect.ValueOf(*i))
+		} else {
+			v.Set(reflect.ValueOf(i))
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return cannotConvert(obj, v.Type())
+		}
+		natural, err := goValue(obj, map[Object]bool{})
+		if err != nil {
+			return err
+		}
+		if natural == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(natural))
+		}
+		return nil
+
+	case reflect.Pointer:
+		pv := reflect.New(v.Type().Elem())
+		if err := fromObject(obj, pv.Elem()); err != nil {
+			return err
+		}
+		v.Set(pv)
+		return nil
+
+	case reflect.Bool:
+		b, ok := obj.(*Boolean)
+		if !ok {
+			return cannotConvert(obj, v.Type())
+		}
+		v.SetBool(b.Value)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if b, ok := obj.(*BigInt); ok {
+			return fmt.Errorf("cannot convert %s to %s: out of range", b.Inspect(), v.Type())
+		}
+		i, ok := obj.(*Integer)
+		if !ok {
+			return cannotConvert(obj, v.Type())
+		}
+		if v.OverflowInt(i.Value) {
+			return fmt.Errorf("cannot convert %d to %s: out of range", i.Value, v.Type())
+		}
+		v.SetInt(i.Value)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := ToBig(obj)
+		if !ok {
+			return cannotConvert(obj, v.Type())
+		}
+		if i.Sign() < 0 || !i.IsUint64() || v.OverflowUint(i.Uint64()) {
+			return fmt.Errorf("cannot convert %s to %s: out of range", i, v.Type())
+		}
+		v.SetUint(i.Uint64())
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		// integers are accepted where a float is wanted
+		f, ok := ToFloat(obj)
+		if !ok {
+			return cannotConvert(obj, v.Type())
+		}
+		v.SetFloat(f)
+		return nil
+
+	case reflect.String:
+		s, ok := obj.(*String)
+		if !ok {
+			return cannotConvert(obj, v.Type())
+		}
+		v.SetString(s.Value)
+		return nil
+
+	case reflect.Slice:
+		elements, ok := elementsOf(obj)
+		if !ok {
+			return cannotConvert(obj, v.Type())
+		}
+		slice := reflect.MakeSlice(v.Type(), len(elements), len(elements))
+		for i, el := range elements {
+			if err := fromObject(el, slice.Index(i)); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+		v.Set(slice)
+		return nil
+
+	case reflect.Array:
+		elements, ok := elementsOf(obj)
+		if !ok {
+			return cannotConvert(obj, v.Type())
+		}
+		if len(elements) != v.Len() {
+			return fmt.Errorf("cannot convert %s of %d elements to %s", obj.Type(), len(elements), v.Type())
+		}
+		for i, el := range elements {
+			if err := fromObject(el, v.Index(i)); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		h, ok := obj.(*Hash)
+		if !ok {
+			return cannotConvert(obj, v.Type())
+		}
+		m := reflect.MakeMapWithSize(v.Type(), h.Len())
+		for _, pair := range h.Pairs() {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := fromObject(pair.Key, key); err != nil {
+				return fmt.Errorf("key %s: %w", pair.Key.Inspect(), err)
+			}
+			val := reflect.New(v.Type().Elem()).Elem()
+			if err := fromObject(pair.Value, val); err != nil {
+				return fmt.Errorf("key %s: %w", pair.Key.Inspect(), err)
+			}
+			m.SetMapIndex(key, val)
+		}
+		v.Set(m)
+		return nil
+
+	case reflect.Struct:
+		h, ok := obj.(*Hash)
+		if !ok {
+			return cannotConvert(obj, v.Type())
+		}
+		for _, f := range structFields(v.Type()) {
+			value, ok, _ := h.Get(&String{Value: f.name})
+			if !ok {
+				continue
+			}
+			fv, err := allocField(v, f.index)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", f.goName, err)
+			}
+			if err := fromObject(value, fv); err != nil {
+				return fmt.Errorf("field %s: %w", f.goName, err)
+			}
+		}
+		return nil
+	}
+
+	return cannotConvert(obj, v.Type())
+}
+
+// allocField returns the field of the struct v at index like FieldByIndex,
+// allocating the nil embedded pointers the field is promoted through.
+func allocField(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot allocate the unexported embedded %s", v.Type())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+func cannotConvert(obj Object, t reflect.Type) error {
+	return fmt.Errorf("cannot convert %s to %s", obj.Type(), t)
+}
+
+// goValue returns the Go value closest to obj, objects without one are
+// returned as they are. seen holds the arrays, tuples and hashes being
+// converted, one holding itself has no Go value.
+func goValue(obj Object, seen map[Object]bool) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return obj.Value, nil
+	case *BigInt:
+		return new(big.Int).Set(obj.Value), nil
+	case *Float:
+		return obj.Value, nil
+	case *String:
+		return obj.Value, nil
+	case *Boolean:
+		return obj.Value, nil
+	case *NilType:
+		return nil, nil
+	case *Array, *Tuple, *Hash:
+		if seen[obj] {
+			return nil, fmt.Errorf("cannot convert %s, it holds itself", obj.Type())
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+	default:
+		return obj, nil
+	}
+
+	switch obj := obj.(type) {
+	case *Array:
+		return goValues(obj.Elements, seen)
+	case *Tuple:
+		return goValues(obj.Elements, seen)
+	}
+	h := obj.(*Hash)
+	values := make(map[interface{}]interface{}, h.Len())
+	for _, pair := range h.Pairs() {
+		var key interface{} = pair.Key
+		if _, ok := pair.Key.(*Tuple); !ok {
+			// a slice cannot be a map key, a tuple itself is kept
+			key, _ = goValue(pair.Key, seen)
+		}
+		value, err := goValue(pair.Value, seen)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+func goValues(elements []Object, seen map[Object]bool) ([]interface{}, error) {
+	values := make([]interface{}, len(elements))
+	for i, el := range elements {
+		value, err := goValue(el, seen)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
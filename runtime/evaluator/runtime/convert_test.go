@@ -0,0 +1,294 @@
+package runtime
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type address struct {
+	City string `monkey:"city"`
+	Zip  string `monkey:"zip,omitempty"`
+}
+
+type person struct {
+	Name    string   `monkey:"name"`
+	Age     uint8    `monkey:"age"`
+	Tags    []string `monkey:"tags"`
+	Address *address `monkey:"address"`
+	Secret  string   `monkey:"-"`
+	Plain   bool
+	hidden  int
+}
+
+func TestToObject(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{nil, "Nil"},
+		{42, "42"},
+		{int8(-3), "-3"},
+		{uint32(7), "7"},
+		{true, "true"},
+		{uint64(1 << 63), "9223372036854775808"},
+		{big.NewInt(7), "7"},
+		{*new(big.Int).Lsh(big.NewInt(1), 70), "1180591620717411303424"},
+		{2.5, "2.5"},
+		{float32(1), "1.0"},
+		{"hi", "hi"},
+		{[]int{1, 2, 3}, "[1, 2, 3]"},
+		{[2]bool{true, false}, "[true, false]"},
+		{[]string(nil), "Nil"},
+		{map[string]int{"b": 2, "a": 1, "c": 3}, "{a:1, b:2, c:3}"},
+		{(*int)(nil), "Nil"},
+		{&address{City: "Udupi"}, "{city:Udupi}"},
+		{&Integer{Value: 5}, "5"},
+	}
+
+	for _, tt := range tests {
+		obj, err := ToObject(tt.input)
+		assert.NoError(t, err, "%#v", tt.input)
+		assert.Equal(t, tt.expected, obj.Inspect(), "%#v", tt.input)
+	}
+}
+
+func TestToObjectStruct(t *testing.T) {
+	obj, err := ToObject(person{Name: "Ann", Age: 30, Tags: []string{"a"}, Secret: "x", Plain: true, hidden: 1})
+	assert.NoError(t, err)
+
+	h, ok := obj.(*Hash)
+	assert.True(t, ok, "struct must convert to a hash")
+	assert.Equal(t, 5, h.Len())
+
+	get := func(key string) Object {
+		value, _, _ := h.Get(&String{Value: key})
+		return value
+	}
+	assert.Equal(t, "Ann", get("name").Inspect())
+	assert.Equal(t, "30", get("age").Inspect())
+	assert.Equal(t, "[a]", get("tags").Inspect())
+	assert.Equal(t, Nil, get("address"))
+	assert.Equal(t, True, get("Plain"))
+}
+
+func TestToObjectErrors(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{make(chan int), "cannot convert chan int to a monkey object"},
+		{[]complex64{1}, "index 0: cannot convert complex64 to a monkey object"},
+		{struct{ C chan int }{}, "field C: cannot convert chan int to a monkey object"},
+		{map[[1]int]int{{1}: 1}, "key [1]: unusable as hash key: Array"},
+	}
+
+	for _, tt := range tests {
+		_, err := ToObject(tt.input)
+		assert.EqualError(t, err, tt.expected)
+	}
+}
+
+type node struct {
+	Value int
+	Next  *node
+}
+
+func TestToObjectCycles(t *testing.T) {
+	n := &node{Value: 1}
+	n.Next = &node{Value: 2, Next: n}
+	_, err := ToObject(n)
+	assert.EqualError(t, err, "field Next: field Next: cannot convert *runtime.node, it refers to itself")
+
+	m := map[string]interface{}{}
+	m["self"] = m
+	_, err = ToObject(m)
+	assert.EqualError(t, err, "key self: cannot convert map[string]interface {}, it refers to itself")
+
+	s := []interface{}{nil}
+	s[0] = s
+	_, err = ToObject(s)
+	assert.EqualError(t, err, "index 0: cannot convert []interface {}, it refers to itself")
+
+	// a value shared without a cycle converts
+	shared := &node{Value: 3}
+	obj, err := ToObject([]*node{shared, shared})
+	assert.NoError(t, err)
+	assert.Equal(t, "[{Value:3, Next:Nil}, {Value:3, Next:Nil}]", obj.Inspect())
+
+	// a pointer to the first field is not the struct holding it
+	type first struct {
+		X int
+		P *int
+	}
+	f := &first{X: 4}
+	f.P = &f.X
+	obj, err = ToObject(f)
+	assert.NoError(t, err)
+	assert.Equal(t, "{X:4, P:4}", obj.Inspect())
+}
+
+func TestFromObject(t *testing.T) {
+	var i int
+	assert.NoError(t, FromObject(&Integer{Value: 12}, &i))
+	assert.Equal(t, 12, i)
+
+	var b *big.Int
+	assert.NoError(t, FromObject(&BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 70)}, &b))
+	assert.Equal(t, "1180591620717411303424", b.String())
+
+	var u uint64
+	assert.NoError(t, FromObject(&BigInt{Value: new(big.Int).SetUint64(1 << 63)}, &u))
+	assert.Equal(t, uint64(1<<63), u)
+
+	var f float64
+	assert.NoError(t, FromObject(&Integer{Value: 2}, &f))
+	assert.Equal(t, 2.0, f)
+	assert.NoError(t, FromObject(&Float{Value: 0.5}, &f))
+	assert.Equal(t, 0.5, f)
+
+	var s string
+	assert.NoError(t, FromObject(&String{Value: "x"}, &s))
+	assert.Equal(t, "x", s)
+
+	var ints []int
+	assert.NoError(t, FromObject(&Array{Elements: []Object{&Integer{Value: 1}, &Integer{Value: 2}}}, &ints))
+	assert.Equal(t, []int{1, 2}, ints)
+
+	var p *int
+	assert.NoError(t, FromObject(&Integer{Value: 3}, &p))
+	assert.Equal(t, 3, *p)
+	assert.NoError(t, FromObject(Nil, &p))
+	assert.Nil(t, p)
+
+	h := NewHash()
+	h.Set(&String{Value: "a"}, &Integer{Value: 1})
+	h.Set(&String{Value: "b"}, &Integer{Value: 2})
+	var m map[string]int
+	assert.NoError(t, FromObject(h, &m))
+	assert.Equal(t, map[string]int{"a": 1, "b": 2}, m)
+
+	var any interface{}
+	assert.NoError(t, FromObject(h, &any))
+	assert.Equal(t, map[interface{}]interface{}{"a": int64(1), "b": int64(2)}, any)
+
+	var obj Object
+	assert.NoError(t, FromObject(h, &obj))
+	assert.Equal(t, h, obj)
+}
+
+func TestFromObjectStruct(t *testing.T) {
+	src, err := ToObject(person{Name: "Ann", Age: 30, Tags: []string{"a", "b"}, Address: &address{City: "Udupi", Zip: "576101"}, Plain: true})
+	assert.NoError(t, err)
+
+	var p person
+	assert.NoError(t, FromObject(src, &p))
+	assert.Equal(t, person{Name: "Ann", Age: 30, Tags: []string{"a", "b"}, Address: &address{City: "Udupi", Zip: "576101"}, Plain: true}, p)
+}
+
+type base struct {
+	ID int
+}
+
+type derived struct {
+	*base
+	*Extra
+	Z int
+}
+
+type Extra struct {
+	Note string
+}
+
+func TestEmbeddedNilPointer(t *testing.T) {
+	// the fields promoted through a nil pointer are left out
+	obj, err := ToObject(derived{Z: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "{Z:1}", obj.Inspect())
+
+	obj, err = ToObject(derived{base: &base{ID: 2}, Extra: &Extra{Note: "x"}, Z: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "{ID:2, Note:x, Z:1}", obj.Inspect())
+
+	// and allocated when there is a value for them
+	h := NewHash()
+	h.Set(&String{Value: "Note"}, &String{Value: "y"})
+	h.Set(&String{Value: "Z"}, &Integer{Value: 3})
+	var d derived
+	assert.NoError(t, FromObject(h, &d))
+	assert.Equal(t, derived{Extra: &Extra{Note: "y"}, Z: 3}, d)
+
+	// an unexported embedded pointer cannot be allocated
+	h.Set(&String{Value: "ID"}, &Integer{Value: 4})
+	assert.EqualError(t, FromObject(h, &derived{}), "field ID: cannot allocate the unexported embedded *runtime.base")
+}
+
+func TestFromObjectCycles(t *testing.T) {
+	arr := &Array{Elements: []Object{&Integer{Value: 1}}}
+	arr.Elements = append(arr.Elements, arr)
+	var v interface{}
+	assert.EqualError(t, FromObject(arr, &v), "cannot convert Array, it holds itself")
+
+	h := NewHash()
+	h.Set(&String{Value: "self"}, h)
+	assert.EqualError(t, FromObject(h, &v), "cannot convert Hash, it holds itself")
+
+	// a value shared without a cycle converts
+	shared := &Array{Elements: []Object{&Integer{Value: 2}}}
+	assert.NoError(t, FromObject(&Array{Elements: []Object{shared, shared}}, &v))
+	assert.Equal(t, []interface{}{[]interface{}{int64(2)}, []interface{}{int64(2)}}, v)
+}
+
+func TestFromObjectErrors(t *testing.T) {
+	h := NewHash()
+	h.Set(&String{Value: "age"}, &Integer{Value: 300})
+
+	tests := []struct {
+		obj      Object
+		target   interface{}
+		expected string
+	}{
+		{&String{Value: "x"}, new(int), "cannot convert String to int"},
+		{&Float{Value: 1.5}, new(int), "cannot convert Float to int"},
+		{&Integer{Value: -1}, new(uint), "cannot convert -1 to uint: out of range"},
+		{&Integer{Value: 128}, new(int8), "cannot convert 128 to int8: out of range"},
+		{&BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 70)}, new(int64), "cannot convert 1180591620717411303424 to int64: out of range"},
+		{Nil, new(string), "cannot convert Nil to string"},
+		{&Array{Elements: []Object{True}}, new([]int), "index 0: cannot convert Boolean to int"},
+		{&Array{}, new([2]int), "cannot convert Array of 0 elements to [2]int"},
+		{h, new(person), "field Age: cannot convert 300 to uint8: out of range"},
+		{True, 1, "cannot convert into int, want a non-nil pointer"},
+	}
+
+	for _, tt := range tests {
+		assert.EqualError(t, FromObject(tt.obj, tt.target), tt.expected)
+	}
+}
+
+func TestFuncBuiltin(t *testing.T) {
+	b, err := FuncBuiltin("join", func(sep string, parts ...string) string {
+		return strings.Join(parts, sep)
+	})
+	assert.NoError(t, err)
+
+	result := b.Fn(&String{Value: "-"}, &String{Value: "a"}, &String{Value: "b"})
+	assert.Equal(t, "a-b", result.Inspect())
+
+	result = b.Fn()
+	assert.Equal(t, "wrong number of arguments to `join`. got=0, want=2", result.(*Error).Message)
+
+	result = b.Fn(&String{Value: "-"}, &Integer{Value: 1})
+	assert.Equal(t, "argument 2 to `join`: cannot convert Integer to string", result.(*Error).Message)
+
+	fail, _ := FuncBuiltin("fail", func() (int, error) { return 0, errors.New("boom") })
+	assert.Equal(t, "boom", fail.Fn().(*Error).Message)
+
+	none, _ := FuncBuiltin("none", func() {})
+	assert.Equal(t, Nil, none.Fn())
+
+	_, err = FuncBuiltin("bad", 3)
+	assert.EqualError(t, err, "cannot wrap int as a builtin, want a function")
+}
@@ -2,6 +2,24 @@ package runtime
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/NishanthSpShetty/monkey/token"
+)
+
+// kinds of errors, scripts catching an error get its kind
+const (
+	KindError = "Error"
+	// KindType is for values of the wrong type for an operation
+	KindType = "TypeError"
+	// KindName is for names which are not declared
+	KindName = "NameError"
+	// KindIndex is for indexes out of range
+	KindIndex = "IndexError"
+	// KindArgument is for calls with the wrong number of arguments
+	KindArgument = "ArgumentError"
 )
 
 func NewError(format string, a ...interface{}) *Error {
@@ -10,6 +28,217 @@ func NewError(format string, a ...interface{}) *Error {
 	}
 }
 
+func NewTypeError(format string, a ...interface{}) *Error {
+	return newKindError(KindType, format, a...)
+}
+
+func NewNameError(format string, a ...interface{}) *Error {
+	return newKindError(KindName, format, a...)
+}
+
+func NewIndexError(format string, a ...interface{}) *Error {
+	return newKindError(KindIndex, format, a...)
+}
+
+func NewArgumentError(format string, a ...interface{}) *Error {
+	return newKindError(KindArgument, format, a...)
+}
+
+func newKindError(kind, format string, a ...interface{}) *Error {
+	err := NewError(format, a...)
+	err.Kind = kind
+	return err
+}
+
+// KindName returns the kind of the error, KindError when it has none.
+func (e *Error) KindName() string {
+	if e.Kind == "" {
+		return KindError
+	}
+	return e.Kind
+}
+
+// Catchable reports whether a script may catch the error, errors stopping
+// an evaluation at one of its limits cannot be.
+func (e *Error) Catchable() bool {
+	return e.Limit == NoLimit
+}
+
 func IsError(obj Object) bool {
 	return obj != nil && obj.Type() == ObjError
 }
+
+// ErrorValue is an error caught by a script, or made by the error builtin.
+// Indexing it with "message", "kind", "stack", "value", "line" or "column"
+// gives the details of the error and throwing it raises the error again.
+type ErrorValue struct {
+	Err *Error
+}
+
+func (ev *ErrorValue) Type() ObjectType { return ObjErrorValue }
+func (ev *ErrorValue) Inspect() string  { return ev.Err.KindName() + ": " + ev.Err.Message }
+
+// Field returns the detail of the error called name, nil when there is no such detail.
+func (ev *ErrorValue) Field(name string) Object {
+	err := ev.Err
+	switch name {
+	case "message":
+		return &String{Value: err.Message}
+	case "kind":
+		return &String{Value: err.KindName()}
+	case "stack":
+		stack := make([]Object, len(err.Stack))
+		for i, sf := range err.Stack {
+			stack[i] = &String{Value: sf.Call() + " at " + sf.Pos.String()}
+		}
+		return &Array{Elements: stack}
+	case "value":
+		if err.Value == nil {
+			return Nil
+		}
+		return err.Value
+	case "line":
+		return &Integer{Value: int64(err.Pos.Line)}
+	case "column":
+		return &Integer{Value: int64(err.Pos.Column)}
+	}
+	return nil
+}
+
+// Throw returns the error raised by throwing value. An ErrorValue is raised
+// as the error it holds, any other value becomes the Value of a new error
+// whose message is the value printed.
+func Throw(value Object) *Error {
+	if ev, ok := value.(*ErrorValue); ok {
+		return ev.Err
+	}
+	err := NewError("%s", value.Inspect())
+	err.Value = value
+	return err
+}
+
+// StackFrame is a call of a Monkey function an error unwound through.
+type StackFrame struct {
+	// Function is the name the function was bound to with let, empty when it has none
+	Function string
+	// Pos is where the function was called, a function called by a builtin
+	// has the position of the call of the builtin.
+	Pos token.Position
+	// File is the source Pos is in, as for Error.File
+	File *token.File
+	// Args summarizes the arguments of the call
+	Args []string
+}
+
+// NewStackFrame describes the call of the function called name with args at pos.
+func NewStackFrame(name string, pos token.Position, args []Object) StackFrame {
+	summary := make([]string, len(args))
+	for i, arg := range args {
+		summary[i] = summarize(arg)
+	}
+	return StackFrame{Function: name, Pos: pos, Args: summary}
+}
+
+// Call renders the call, f(1, "a") or fn(2) for a function without a name.
+func (sf StackFrame) Call() string {
+	name := sf.Function
+	if name == "" {
+		name = "fn"
+	}
+	return name + "(" + strings.Join(sf.Args, ", ") + ")"
+}
+
+// maxSummary is the number of characters an argument is shortened to in a stack frame.
+const maxSummary = 24
+
+// summarize renders obj shortened, only printing the start of long values
+// as it runs for every frame an error unwinds through.
+func summarize(obj Object) string {
+	var s string
+	switch obj := obj.(type) {
+	case *String:
+		value := obj.Value
+		for i := range value {
+			if i >= maxSummary*utf8.UTFMax {
+				value = value[:i]
+				break
+			}
+		}
+		s = strconv.Quote(value)
+	case *Function:
+		// the source of the body is too much, render it like a compiled closure
+		s = fmt.Sprintf("fn/%d", len(obj.Params))
+		if obj.Name != "" {
+			s = fmt.Sprintf("fn %s/%d", obj.Name, len(obj.Params))
+		}
+	case *Array, *Tuple, *Hash:
+		p := &printer{limit: maxSummary * utf8.UTFMax}
+		p.print(obj)
+		s = p.String()
+	default:
+		s = obj.Inspect()
+	}
+	if utf8.RuneCountInString(s) > maxSummary {
+		s = string([]rune(s)[:maxSummary-3]) + "..."
+	}
+	return s
+}
+
+// Unwind records that the error leaves a function from file: the positions
+// not yet known to be in another source are in its body.
+func (e *Error) Unwind(file *token.File) {
+	if e.File == nil && e.Pos.IsValid() {
+		e.File = file
+	}
+	for i := range e.Stack {
+		if e.Stack[i].File == nil && e.Stack[i].Pos.IsValid() {
+			e.Stack[i].File = file
+		}
+	}
+}
+
+// orFile returns file, or f when file is not known.
+func orFile(file, f *token.File) *token.File {
+	if file == nil {
+		return f
+	}
+	return file
+}
+
+// stack traces longer than this are shown as their innermost and outermost frames
+const (
+	traceHead = 20
+	traceTail = 5
+)
+
+// Render formats the error with its location in f and the offending source line,
+// falling back to the plain message when the position is unknown. Positions
+// known to be in another source, such as the body of a function evaluated
+// earlier, are rendered against it. The calls the error unwound through
+// follow, most recent first.
+func (e *Error) Render(f *token.File) string {
+	out := e.Inspect()
+	if e.Pos.IsValid() {
+		out = orFile(e.File, f).Format(e.Pos, out)
+	}
+	if len(e.Stack) == 0 {
+		return out
+	}
+
+	var b strings.Builder
+	b.WriteString(out)
+	b.WriteString("\nstack trace (most recent call first):")
+	for i, sf := range e.Stack {
+		if len(e.Stack) > traceHead+traceTail && i == traceHead {
+			fmt.Fprintf(&b, "\n    ... %d more calls", len(e.Stack)-traceHead-traceTail)
+		}
+		if len(e.Stack) > traceHead+traceTail && i >= traceHead && i < len(e.Stack)-traceTail {
+			continue
+		}
+		b.WriteString("\n    " + sf.Call())
+		if sf.Pos.IsValid() {
+			b.WriteString(" at " + orFile(sf.File, f).Location(sf.Pos))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,335 @@
+package runtime
+
+import "sort"
+
+// builtins taking a function, they walk arrays, strings, ranges and hashes
+// like a for-in loop with a single variable does.
+
+// checkFunction fails unless argument i to name can be called.
+func checkFunction(name string, i int, fn Object) *Error {
+	if t := fn.Type(); t != ObjFunction && t != ObjBuiltin {
+		return NewTypeError("argument %d to `%s` must be Function, got %s", i, name, t)
+	}
+	return nil
+}
+
+// iterate calls f with each element of x until f returns stop or an error.
+// Every element is a step, a range may be longer than the time allowed.
+func iterate(call Caller, name string, x Object, f func(v Object) (stop bool, err *Error)) *Error {
+	it, err := NewIterator(x)
+	if err != nil {
+		return NewTypeError("argument 1 to `%s` must be iterable, got %s", name, x.Type())
+	}
+	for {
+		key, value, ok := it.Next()
+		if !ok {
+			return nil
+		}
+		if err := call.Step(); err != nil {
+			return err
+		}
+		stop, err := f(it.Single(key, value))
+		if err != nil || stop {
+			return err
+		}
+	}
+}
+
+// newHigherOrder makes a builtin called with a collection and a function,
+// and up to optional more arguments after them.
+func newHigherOrder(name string, optional int, fn func(call Caller, args ...Object) Object) *Builtin {
+	params := []ObjectType{ObjAny, ObjAny}
+	for i := 0; i < optional; i++ {
+		params = append(params, ObjAny)
+	}
+	return &Builtin{
+		CallFn: func(call Caller, args ...Object) Object {
+			if err := checkArgs(name, args, 2, params...); err != nil {
+				return err
+			}
+			if err := checkFunction(name, 2, args[1]); err != nil {
+				return err
+			}
+			return fn(call, args...)
+		},
+	}
+}
+
+// callError returns res when it is an error.
+func callError(res Object) *Error {
+	if err, ok := res.(*Error); ok {
+		return err
+	}
+	return nil
+}
+
+func fnMap() *Builtin {
+	return newHigherOrder("map", 0, func(call Caller, args ...Object) Object {
+		mapped := []Object{}
+		err := iterate(call, "map", args[0], func(v Object) (bool, *Error) {
+			res := call.Call(args[1], v)
+			if err := callError(res); err != nil {
+				return true, err
+			}
+			mapped = append(mapped, res)
+			return false, call.Alloc(elementSize)
+		})
+		if err != nil {
+			return err
+		}
+		return &Array{Elements: mapped}
+	})
+}
+
+func fnFilter() *Builtin {
+	return newHigherOrder("filter", 0, func(call Caller, args ...Object) Object {
+		kept := []Object{}
+		err := iterate(call, "filter", args[0], func(v Object) (bool, *Error) {
+			res := call.Call(args[1], v)
+			if err := callError(res); err != nil || !IsTruthy(res) {
+				return false, err
+			}
+			kept = append(kept, v)
+			return false, call.Alloc(elementSize)
+		})
+		if err != nil {
+			return err
+		}
+		return &Array{Elements: kept}
+	})
+}
+
+// fnReduce folds the elements with fn(acc, x), starting from the initial
+// value or, when there is none, from the first element.
+func fnReduce() *Builtin {
+	return newHigherOrder("reduce", 1, func(call Caller, args ...Object) Object {
+		var acc Object
+		if len(args) == 3 {
+			acc = args[2]
+		}
+		err := iterate(call, "reduce", args[0], func(v Object) (bool, *Error) {
+			if acc == nil {
+				acc = v
+				return false, nil
+			}
+			acc = call.Call(args[1], acc, v)
+			return false, callError(acc)
+		})
+		if err != nil {
+			return err
+		}
+		if acc == nil {
+			return NewError("`reduce` of an empty %s needs an initial value", args[0].Type())
+		}
+		return acc
+	})
+}
+
+func fnEach() *Builtin {
+	return newHigherOrder("each", 0, func(call Caller, args ...Object) Object {
+		err := iterate(call, "each", args[0], func(v Object) (bool, *Error) {
+			return false, callError(call.Call(args[1], v))
+		})
+		if err != nil {
+			return err
+		}
+		return Nil
+	})
+}
+
+// fnFind returns the first element fn is truthy for, nil when there is none.
+func fnFind() *Builtin {
+	return newHigherOrder("find", 0, func(call Caller, args ...Object) Object {
+		var found Object = Nil
+		err := iterate(call, "find", args[0], func(v Object) (bool, *Error) {
+			res := call.Call(args[1], v)
+			if err := callError(res); err != nil {
+				return true, err
+			}
+			if IsTruthy(res) {
+				found = v
+				return true, nil
+			}
+			return false, nil
+		})
+		if err != nil {
+			return err
+		}
+		return found
+	})
+}
+
+// fnAny and fnAll test the elements with fn, or their own truthiness when
+// called without one, stopping as soon as the answer is known.
+func fnAny() *Builtin { return quantifier("any", true) }
+func fnAll() *Builtin { return quantifier("all", false) }
+
+func quantifier(name string, any bool) *Builtin {
+	return &Builtin{
+		CallFn: func(call Caller, args ...Object) Object {
+			if err := checkArgs(name, args, 1, ObjAny, ObjAny); err != nil {
+				return err
+			}
+			if len(args) == 2 {
+				if err := checkFunction(name, 2, args[1]); err != nil {
+					return err
+				}
+			}
+
+			// any stops at the first truthy element, all at the first falsy one
+			result := !any
+			err := iterate(call, name, args[0], func(v Object) (bool, *Error) {
+				res := v
+				if len(args) == 2 {
+					res = call.Call(args[1], v)
+					if err := callError(res); err != nil {
+						return true, err
+					}
+				}
+				if IsTruthy(res) == any {
+					result = any
+					return true, nil
+				}
+				return false, nil
+			})
+			if err != nil {
+				return err
+			}
+			return NativeBool(result)
+		},
+	}
+}
+
+// fnZip pairs up the elements of its arguments, zip([1, 2], ["a", "b"]) is
+// [[1, "a"], [2, "b"]], stopping at the end of the shortest one.
+func fnZip() *Builtin {
+	return &Builtin{
+		CallFn: func(call Caller, args ...Object) Object {
+			if err := checkVariadicArgs("zip", args, 1, ObjAny); err != nil {
+				return err
+			}
+			iters := make([]*Iterator, len(args))
+			for i, arg := range args {
+				it, err := NewIterator(arg)
+				if err != nil {
+					return NewTypeError("argument %d to `zip` must be iterable, got %s", i+1, arg.Type())
+				}
+				iters[i] = it
+			}
+
+			zipped := []Object{}
+			for {
+				tuple := make([]Object, len(iters))
+				for i, it := range iters {
+					key, value, ok := it.Next()
+					if !ok {
+						return &Array{Elements: zipped}
+					}
+					tuple[i] = it.Single(key, value)
+				}
+				pair := &Array{Elements: tuple}
+				zipped = append(zipped, pair)
+				if err := call.Step(); err != nil {
+					return err
+				}
+				if err := call.Alloc(elementSize + SizeOf(pair)); err != nil {
+					return err
+				}
+			}
+		},
+	}
+}
+
+// fnFlatMap concatenates the arrays fn returns, other results are added as they are.
+func fnFlatMap() *Builtin {
+	return newHigherOrder("flat_map", 0, func(call Caller, args ...Object) Object {
+		flat := []Object{}
+		err := iterate(call, "flat_map", args[0], func(v Object) (bool, *Error) {
+			res := call.Call(args[1], v)
+			if arr, ok := res.(*Array); ok {
+				flat = append(flat, arr.Elements...)
+				return false, call.Alloc(elementSize * int64(len(arr.Elements)))
+			}
+			if err := callError(res); err != nil {
+				return true, err
+			}
+			flat = append(flat, res)
+			return false, call.Alloc(elementSize)
+		})
+		if err != nil {
+			return err
+		}
+		return &Array{Elements: flat}
+	})
+}
+
+// fnGroupBy returns a hash from each key fn returns to the array of elements with that key.
+func fnGroupBy() *Builtin {
+	return newHigherOrder("group_by", 0, func(call Caller, args ...Object) Object {
+		groups := NewHash()
+		err := iterate(call, "group_by", args[0], func(v Object) (bool, *Error) {
+			key := call.Call(args[1], v)
+			if err := callError(key); err != nil {
+				return true, err
+			}
+			group, ok, err := groups.Get(key)
+			if err != nil {
+				return true, err
+			}
+			if !ok {
+				group = &Array{Elements: []Object{}}
+				if err := groups.Set(key, group); err != nil {
+					return true, err
+				}
+			}
+			arr := group.(*Array)
+			arr.Elements = append(arr.Elements, v)
+			return false, call.Alloc(elementSize)
+		})
+		if err != nil {
+			return err
+		}
+		return groups
+	})
+}
+
+// fnSortBy returns the elements sorted by the key fn returns for them,
+// fn is called once per element and equal keys keep their order.
+func fnSortBy() *Builtin {
+	return newHigherOrder("sort_by", 0, func(call Caller, args ...Object) Object {
+		type keyed struct{ key, value Object }
+		items := []keyed{}
+		err := iterate(call, "sort_by", args[0], func(v Object) (bool, *Error) {
+			key := call.Call(args[1], v)
+			if err := callError(key); err != nil {
+				return true, err
+			}
+			items = append(items, keyed{key, v})
+			return false, call.Alloc(2 * elementSize)
+		})
+		if err != nil {
+			return err
+		}
+
+		sort.SliceStable(items, func(i, j int) bool {
+			if err == nil {
+				err = call.Step()
+			}
+			if err != nil {
+				return false
+			}
+			var c int
+			c, err = Compare(items[i].key, items[j].key)
+			return c < 0
+		})
+		if err != nil {
+			return err
+		}
+
+		sorted := make([]Object, len(items))
+		for i, item := range items {
+			sorted[i] = item.value
+		}
+		return &Array{Elements: sorted}
+	})
+}
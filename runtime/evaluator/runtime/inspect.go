@@ -0,0 +1,96 @@
+package runtime
+
+import "strings"
+
+// printer writes the text Inspect returns for arrays, tuples and hashes.
+// A value holding itself is written [...], (...) or {...} where it is met
+// again inside itself.
+type printer struct {
+	strings.Builder
+	// open are the values being written
+	open map[Object]bool
+	// limit stops the printing once that many bytes are written, when set
+	limit int
+}
+
+func inspect(obj Object) string {
+	p := &printer{}
+	p.print(obj)
+	return p.String()
+}
+
+func (p *printer) print(obj Object) {
+	if p.done() {
+		return
+	}
+	switch obj := obj.(type) {
+	case *Array:
+		if p.enter(obj, "[...]") {
+			defer delete(p.open, obj)
+			p.WriteString("[")
+			p.elements(obj.Elements)
+			p.WriteString("]")
+		}
+	case *Tuple:
+		if p.enter(obj, "(...)") {
+			defer delete(p.open, obj)
+			p.WriteString("(")
+			p.elements(obj.Elements)
+			if len(obj.Elements) == 1 {
+				// (1) would read as a number
+				p.WriteString(",")
+			}
+			p.WriteString(")")
+		}
+	case *Hash:
+		if p.enter(obj, "{...}") {
+			defer delete(p.open, obj)
+			p.WriteString("{")
+			for i, pair := range obj.pairs {
+				if p.done() {
+					return
+				}
+				if i > 0 {
+					p.WriteString(", ")
+				}
+				p.print(pair.Key)
+				p.WriteString(":")
+				p.print(pair.Value)
+			}
+			p.WriteString("}")
+		}
+	default:
+		p.WriteString(obj.Inspect())
+	}
+}
+
+func (p *printer) elements(elements []Object) {
+	for i, e := range elements {
+		if p.done() {
+			return
+		}
+		if i > 0 {
+			p.WriteString(", ")
+		}
+		p.print(e)
+	}
+}
+
+// enter marks obj as being written, when it already is it writes cycle
+// in its place and returns false.
+func (p *printer) enter(obj Object, cycle string) bool {
+	if p.open[obj] {
+		p.WriteString(cycle)
+		return false
+	}
+	if p.open == nil {
+		p.open = map[Object]bool{}
+	}
+	p.open[obj] = true
+	return true
+}
+
+// done reports whether the limit is reached, what follows would be cut.
+func (p *printer) done() bool {
+	return p.limit > 0 && p.Len() >= p.limit
+}
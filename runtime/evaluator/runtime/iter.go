@@ -0,0 +1,183 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"unicode/utf8"
+)
+
+// Range is the sequence of integers from Start up to, not including, Stop
+// taking Step at a time, as made by the range builtin.
+type Range struct {
+	Start, Stop, Step int64
+}
+
+func (r *Range) Type() ObjectType { return ObjRange }
+func (r *Range) Inspect() string {
+	if r.Step == 1 {
+		return fmt.Sprintf("range(%d, %d)", r.Start, r.Stop)
+	}
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.Stop, r.Step)
+}
+
+// Len returns the number of integers in the range, an Integer or a BigInt
+// when there are more than an int64 holds.
+func (r *Range) Len() Object {
+	n := r.count()
+	if n > math.MaxInt64 {
+		return &BigInt{Value: new(big.Int).SetUint64(n)}
+	}
+	return &Integer{Value: int64(n)}
+}
+
+// count returns the number of integers in the range.
+func (r *Range) count() uint64 {
+	// the distance and the step may not fit in an int64, they do in a uint64
+	var span, step uint64
+	switch {
+	case r.Step > 0 && r.Start < r.Stop:
+		span, step = uint64(r.Stop)-uint64(r.Start), uint64(r.Step)
+	case r.Step < 0 && r.Start > r.Stop:
+		span, step = uint64(r.Start)-uint64(r.Stop), -uint64(r.Step)
+	default:
+		return 0
+	}
+	n := span / step
+	if span%step != 0 {
+		n++
+	}
+	return n
+}
+
+// Iterator walks the elements of an array, string, range or hash for a for-in loop.
+type Iterator struct {
+	next func() (key, value Object, ok bool)
+	// keys is set when a loop with a single variable gets the key rather than the value
+	keys bool
+}
+
+func (it *Iterator) Type() ObjectType { return ObjIterator }
+func (it *Iterator) Inspect() string  { return "iterator" }
+
+// NewIterator returns an iterator over obj, or an error when obj cannot be iterated.
+func NewIterator(obj Object) (*Iterator, *Error) {
+	switch obj := obj.(type) {
+	case *Array:
+		i := 0
+		return &Iterator{next: func() (Object, Object, bool) {
+			// the length is checked every step, the array may change while looping
+			if i >= len(obj.Elements) {
+				return nil, nil, false
+			}
+			i++
+			return &Integer{Value: int64(i - 1)}, obj.Elements[i-1], true
+		}}, nil
+
+	case *Tuple:
+		i := 0
+		return &Iterator{next: func() (Object, Object, bool) {
+			if i >= len(obj.Elements) {
+				return nil, nil, false
+			}
+			i++
+			return &Integer{Value: int64(i - 1)}, obj.Elements[i-1], true
+		}}, nil
+
+	case *String:
+		offset, i := 0, 0
+		return &Iterator{next: func() (Object, Object, bool) {
+			if offset >= len(obj.Value) {
+				return nil, nil, false
+			}
+			r, width := utf8.DecodeRuneInString(obj.Value[offset:])
+			offset += width
+			i++
+			return &Integer{Value: int64(i - 1)}, &String{Value: string(r)}, true
+		}}, nil
+
+	case *Range:
+		i, n := uint64(0), obj.count()
+		return &Iterator{next: func() (Object, Object, bool) {
+			if i >= n {
+				return nil, nil, false
+			}
+			i++
+			// the product may wrap around, the element it lands on is within the range
+			return &Integer{Value: int64(i - 1)}, &Integer{Value: obj.Start + int64(i-1)*obj.Step}, true
+		}}, nil
+
+	case *Hash:
+		// iterate over the pairs present when the loop starts, in insertion order
+		pairs := obj.Pairs()
+		i := 0
+		return &Iterator{keys: true, next: func() (Object, Object, bool) {
+			if i >= len(pairs) {
+				return nil, nil, false
+			}
+			i++
+			return pairs[i-1].Key, pairs[i-1].Value, true
+		}}, nil
+
+	default:
+		return nil, NewTypeError("cannot iterate over %s", obj.Type())
+	}
+}
+
+// Next advances the iterator and returns the index, or hash key, and the
+// element it moved to. It returns false once the elements are exhausted.
+func (it *Iterator) Next() (key, value Object, ok bool) {
+	return it.next()
+}
+
+// Single picks what a loop with a single variable is bound to out of the
+// results of Next: the key when iterating a hash and the element otherwise.
+func (it *Iterator) Single(key, value Object) Object {
+	if it.keys {
+		return key
+	}
+	return value
+}
+
+// LoopControl is the signal break and continue statements send to the
+// enclosing loop, like ReturnValue does for return.
+type LoopControl struct {
+	Break bool
+}
+
+var (
+	BreakSignal    = &LoopControl{Break: true}
+	ContinueSignal = &LoopControl{Break: false}
+)
+
+func (lc *LoopControl) Type() ObjectType { return ObjLoopControl }
+func (lc *LoopControl) Inspect() string {
+	if lc.Break {
+		return "break"
+	}
+	return "continue"
+}
+
+// Spread returns the elements of obj spread into the arguments of a call,
+// f(...xs), anything a for loop walks can be spread. Each element is charged
+// to call as it is taken.
+func Spread(call Caller, obj Object) ([]Object, *Error) {
+	it, err := NewIterator(obj)
+	if err != nil {
+		return nil, NewTypeError("cannot spread %s", obj.Type())
+	}
+	elements := []Object{}
+	for {
+		key, value, ok := it.Next()
+		if !ok {
+			return elements, nil
+		}
+		if err := call.Step(); err != nil {
+			return nil, err
+		}
+		if err := call.Alloc(elementSize); err != nil {
+			return nil, err
+		}
+		elements = append(elements, it.Single(key, value))
+	}
+}
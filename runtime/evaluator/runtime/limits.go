@@ -0,0 +1,144 @@
+package runtime
+
+import "context"
+
+// Limit tells which execution limit stopped an evaluation.
+type Limit int
+
+const (
+	// NoLimit is the Limit of ordinary errors raised by the program.
+	NoLimit Limit = iota
+	DepthLimit
+	StepLimit
+	AllocLimit
+	// Canceled is the Limit of an evaluation stopped by its context.
+	Canceled
+)
+
+func (l Limit) String() string {
+	switch l {
+	case DepthLimit:
+		return "depth limit"
+	case StepLimit:
+		return "step limit"
+	case AllocLimit:
+		return "allocation limit"
+	case Canceled:
+		return "canceled"
+	}
+	return "no limit"
+}
+
+// Limits bound the resources an evaluation may use, a zero field is no limit.
+type Limits struct {
+	// MaxDepth is the deepest nesting of function calls.
+	MaxDepth int
+	// MaxSteps is the number of nodes evaluated, or instructions run by the vm.
+	MaxSteps int64
+	// MaxAlloc is the approximate number of bytes of the values created.
+	// It counts what is created, not what is still in use.
+	MaxAlloc int64
+}
+
+// DefaultLimits only bound the call depth, which keeps a runaway recursion
+// from overflowing the Go stack.
+var DefaultLimits = Limits{MaxDepth: 10000}
+
+// NewBudget returns the budget of evaluations bounded by limits.
+func NewBudget(limits Limits) *Budget {
+	return &Budget{limits: limits}
+}
+
+// contextCheckInterval is how many steps are taken between looking at the context.
+const contextCheckInterval = 1024
+
+// Budget keeps track of the resources used by an evaluation. All the scopes
+// of a runtime share one budget.
+type Budget struct {
+	limits Limits
+	ctx    context.Context
+
+	depth int
+	steps int64
+	alloc int64
+}
+
+// Start begins an evaluation which stops when ctx is done, the steps and
+// allocations counted so far are forgotten.
+func (b *Budget) Start(ctx context.Context) {
+	b.ctx = ctx
+	b.depth = 0
+	b.steps = 0
+	b.alloc = 0
+}
+
+// Step counts an evaluation step, failing when the steps run out or the
+// context is done.
+func (b *Budget) Step() *Error {
+	b.steps++
+	if b.limits.MaxSteps > 0 && b.steps > b.limits.MaxSteps {
+		return newLimitError(StepLimit, "step limit exceeded: %d steps", b.limits.MaxSteps)
+	}
+	if b.ctx != nil && b.steps%contextCheckInterval == 0 {
+		if err := b.ctx.Err(); err != nil {
+			return newLimitError(Canceled, "evaluation canceled: %s", err)
+		}
+	}
+	return nil
+}
+
+// Enter counts a function call, failing when the calls nest too deep. Every
+// successful Enter must be followed by a Leave.
+func (b *Budget) Enter() *Error {
+	if b.limits.MaxDepth > 0 && b.depth >= b.limits.MaxDepth {
+		return newLimitError(DepthLimit, "maximum call depth exceeded: %d", b.limits.MaxDepth)
+	}
+	b.depth++
+	return nil
+}
+
+// Leave ends a call counted by Enter.
+func (b *Budget) Leave() {
+	b.depth--
+}
+
+// Alloc counts size bytes allocated, failing when more than the limit has been.
+func (b *Budget) Alloc(size int64) *Error {
+	b.alloc += size
+	if b.limits.MaxAlloc > 0 && b.alloc > b.limits.MaxAlloc {
+		return newLimitError(AllocLimit, "allocation limit exceeded: %d bytes", b.limits.MaxAlloc)
+	}
+	return nil
+}
+
+func newLimitError(limit Limit, format string, a ...interface{}) *Error {
+	err := NewError(format, a...)
+	err.Limit = limit
+	return err
+}
+
+// elementSize is what an element takes in an array or a tuple.
+const elementSize = 16
+
+// SizeOf approximates the bytes taken by obj itself, the elements of an
+// array or a hash are counted as references only. Shared values like
+// booleans and nil take none.
+func SizeOf(obj Object) int64 {
+	switch obj := obj.(type) {
+	case *Integer, *Float:
+		return 16
+	case *BigInt:
+		return 32 + 8*int64(len(obj.Value.Bits()))
+	case *String:
+		return 16 + int64(len(obj.Value))
+	case *Array:
+		return 24 + elementSize*int64(len(obj.Elements))
+	case *Tuple:
+		return 24 + elementSize*int64(len(obj.Elements))
+	case *Hash:
+		return 48 + 64*int64(obj.Len())
+	case *Function:
+		return 64
+	}
+	return 0
+}
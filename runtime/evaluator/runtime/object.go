@@ -2,11 +2,16 @@ package runtime
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"strconv"
 	"strings"
 
 	"github.com/NishanthSpShetty/monkey/ast"
+	"github.com/NishanthSpShetty/monkey/runtime/code"
+	"github.com/NishanthSpShetty/monkey/token"
 )
 
 type (
@@ -16,6 +21,8 @@ type (
 
 const (
 	ObjInteger  ObjectType = "Integer"
+	ObjBigInt   ObjectType = "BigInt"
+	ObjFloat    ObjectType = "Float"
 	ObjString   ObjectType = "String"
 	ObjBoolean  ObjectType = "Boolean"
 	ObjNull     ObjectType = "Nil"
@@ -24,7 +31,18 @@ const (
 	ObjFunction ObjectType = "Function"
 	ObjBuiltin  ObjectType = "Builtin"
 	ObjArray    ObjectType = "Array"
+	ObjTuple    ObjectType = "Tuple"
 	ObjHash     ObjectType = "Hash"
+	ObjRange    ObjectType = "Range"
+
+	// ObjErrorValue is an error caught by a script, unlike ObjError it does
+	// not stop the evaluation
+	ObjErrorValue ObjectType = "ErrorValue"
+
+	ObjIterator    ObjectType = "Iterator"
+	ObjLoopControl ObjectType = "LoopControl"
+
+	ObjCompiledFunction ObjectType = "CompiledFunction"
 )
 
 var (
@@ -65,6 +83,36 @@ func (i *Integer) HashKey() HashKey {
 	}
 }
 
+type Float struct {
+	Value float64
+}
+
+// Inspect prints whole floats with a trailing .0 so they read differently from integers.
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if strings.Trim(s, "-0123456789") == "" {
+		s += ".0"
+	}
+	return s
+}
+
+func (f *Float) Type() ObjectType {
+	return ObjFloat
+}
+
+// HashKey of a whole float is the key of the equal integer, so 1.0 and 1 find the same entry.
+func (f *Float) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) {
+		if i, ok := floatToInteger(f.Value); ok {
+			return i.(Hashtable).HashKey()
+		}
+	}
+	return HashKey{
+		Type:  f.Type(),
+		Value: math.Float64bits(f.Value),
+	}
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -114,6 +162,20 @@ func (rv *ReturnValue) Type() ObjectType {
 
 type Error struct {
 	Message string
+	// Kind classifies the error for the scripts catching it, empty is KindError.
+	Kind string
+	// Value is the value thrown by the script when it was not an error.
+	Value Object
+	// Pos is where in the source the error was raised, if known.
+	Pos token.Position
+	// File is the source Pos is in, nil while it is not known which, in which
+	// case it is the source the error is rendered with.
+	File *token.File
+	// Limit is set when the error stopped an evaluation which exceeded one
+	// of its Limits or was canceled.
+	Limit Limit
+	// Stack holds the calls the error unwound through, innermost first.
+	Stack []StackFrame
 }
 
 func (e *Error) Inspect() string {
@@ -125,9 +187,17 @@ func (e *Error) Type() ObjectType {
 }
 
 type Function struct {
-	Params  []*ast.Identifier
+	Params []*ast.Identifier
+	// Defaults are the default values of the parameters, see ast.FunctionLiteral
+	Defaults []ast.Expression
+	// Rest is the parameter collecting the arguments after Params, if any
+	Rest    *ast.Identifier
 	Body    *ast.BlockStatement
 	Runtime *Runtime
+	// Name is the name the function was bound to with let, if any
+	Name string
+	// File is the source the function was parsed from
+	File *token.File
 }
 
 func (f *Function) Type() ObjectType { return ObjFunction }
@@ -135,8 +205,15 @@ func (f *Function) Inspect() string {
 	var out bytes.Buffer
 
 	params := []string{}
-	for _, p := range f.Params {
-		params = append(params, p.String())
+	for i, p := range f.Params {
+		if f.Defaults != nil && f.Defaults[i] != nil {
+			params = append(params, p.String()+" = "+f.Defaults[i].String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
 	}
 	out.WriteString("fn")
 	out.WriteString("(")
@@ -148,6 +225,66 @@ func (f *Function) Inspect() string {
 	return out.String()
 }
 
+// Arity returns the least and the most arguments f takes, most is -1 when
+// it has a rest parameter.
+func (f *Function) Arity() (least, most int) {
+	least = len(f.Params)
+	if f.Defaults != nil {
+		least = 0
+		for least < len(f.Params) && f.Defaults[least] == nil {
+			least++
+		}
+	}
+	most = len(f.Params)
+	if f.Rest != nil {
+		most = -1
+	}
+	return least, most
+}
+
+// CheckArity fails unless got arguments are between least and most, or at
+// least least when most is -1.
+func CheckArity(least, most, got int) *Error {
+	switch {
+	case got >= least && (got <= most || most < 0):
+		return nil
+	case most < 0:
+		return NewArgumentError("wrong number of arguments: want=at least %d, got=%d", least, got)
+	case least != most:
+		return NewArgumentError("wrong number of arguments: want=%d to %d, got=%d", least, most, got)
+	}
+	return NewArgumentError("wrong number of arguments: want=%d, got=%d", least, got)
+}
+
+// FreeVar describes where a closure captures a variable from when it is created:
+// a local slot of the enclosing frame or a free variable of the enclosing closure.
+type FreeVar struct {
+	Local bool
+	Index int
+}
+
+// CompiledFunction is a function lowered to bytecode by the compiler.
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	Positions     code.PosTable
+	NumLocals     int
+	NumParameters int
+	// NumRequired is the number of parameters without a default
+	NumRequired int
+	// Variadic tells the local after the parameters collects the arguments past them
+	Variadic bool
+	Free     []FreeVar
+	// Name is the name the function was bound to with let, if any
+	Name string
+	// File is the source the function was compiled from, nil for the program
+	File *token.File
+}
+
+func (cf *CompiledFunction) Type() ObjectType { return ObjCompiledFunction }
+func (cf *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
 type String struct {
 	Value string
 }
@@ -168,37 +305,165 @@ type Array struct {
 func (a *Array) Len() int64 { return int64(len(a.Elements)) }
 
 func (a *Array) Type() ObjectType { return ObjArray }
-func (a *Array) Inspect() string {
-	var out bytes.Buffer
-	elements := []string{}
-	for _, e := range a.Elements {
-		elements = append(elements, e.Inspect())
+func (a *Array) Inspect() string  { return inspect(a) }
+
+// Tuple is an immutable array, a tuple of hashable values can be a hash key.
+type Tuple struct {
+	Elements []Object
+}
+
+func (t *Tuple) Len() int64 { return int64(len(t.Elements)) }
+
+func (t *Tuple) Type() ObjectType { return ObjTuple }
+func (t *Tuple) Inspect() string  { return inspect(t) }
+
+// HashKey combines the hash keys of the elements, which hashKeyOf has
+// checked to be hashable before a tuple is used as a key.
+func (t *Tuple) HashKey() HashKey {
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, e := range t.Elements {
+		if e, ok := e.(Hashtable); ok {
+			k := e.HashKey()
+			h.Write([]byte(k.Type))
+			binary.BigEndian.PutUint64(buf[:], k.Value)
+			h.Write(buf[:])
+		}
 	}
-	out.WriteString("[")
-	out.WriteString(strings.Join(elements, ", "))
-	out.WriteString("]")
-	return out.String()
+	return HashKey{Type: t.Type(), Value: h.Sum64()}
+}
+
+// elementsOf returns the elements of an array or a tuple.
+func elementsOf(obj Object) ([]Object, bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		return obj.Elements, true
+	case *Tuple:
+		return obj.Elements, true
+	}
+	return nil, false
 }
 
 type HashPair struct {
 	Key   Object
 	Value Object
 }
+
+// Hash keeps its pairs in insertion order, setting a key which is already
+// present replaces its value and keeps its place. Keys are found by their
+// HashKey and told apart with Equal, so keys whose hash keys collide are
+// kept side by side.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	pairs []HashPair
+	// buckets hold the indexes in pairs of the keys with the same hash key
+	buckets map[HashKey][]int
 }
 
-func (h *Hash) Type() ObjectType { return ObjHash }
-func (h *Hash) Inspect() string {
+func NewHash() *Hash {
+	return &Hash{
+		buckets: make(map[HashKey][]int),
+	}
+}
 
-	pairs := []string{}
+// Len is the number of pairs in the hash.
+func (h *Hash) Len() int { return len(h.pairs) }
 
-	for _, v := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s:%s, ", v.Key.Inspect(), v.Value.Inspect()))
+// Pairs returns the pairs in insertion order, the slice must not be modified.
+func (h *Hash) Pairs() []HashPair { return h.pairs }
+
+// find returns the hash key of key and the index of its pair, -1 when it is not present.
+func (h *Hash) find(key Object) (HashKey, int, *Error) {
+	hashKey, err := hashKeyOf(key)
+	if err != nil {
+		return hashKey, -1, err
 	}
-	var out bytes.Buffer
-	out.WriteString("{")
-	out.WriteString(strings.Join(pairs, ", "))
-	out.WriteString("}")
-	return out.String()
+	for _, i := range h.buckets[hashKey] {
+		if Equal(h.pairs[i].Key, key) {
+			return hashKey, i, nil
+		}
+	}
+	return hashKey, -1, nil
+}
+
+// Set stores value under key, failing when key cannot be hashed.
+func (h *Hash) Set(key, value Object) *Error {
+	hashKey, i, err := h.find(key)
+	if err != nil {
+		return err
+	}
+	if i >= 0 {
+		h.pairs[i].Value = value
+		return nil
+	}
+	h.buckets[hashKey] = append(h.buckets[hashKey], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: key, Value: value})
+	return nil
+}
+
+// Get returns the value stored under key, ok is false when there is none.
+func (h *Hash) Get(key Object) (value Object, ok bool, err *Error) {
+	_, i, err := h.find(key)
+	if err != nil || i < 0 {
+		return nil, false, err
+	}
+	return h.pairs[i].Value, true, nil
+}
+
+// Delete removes key and returns the value it had, ok is false when there was none.
+func (h *Hash) Delete(key Object) (value Object, ok bool, err *Error) {
+	_, i, err := h.find(key)
+	if err != nil || i < 0 {
+		return nil, false, err
+	}
+	value = h.pairs[i].Value
+
+	// a fresh slice, so iterations over the old pairs are not disturbed
+	pairs := make([]HashPair, 0, len(h.pairs)-1)
+	pairs = append(pairs, h.pairs[:i]...)
+	h.pairs = append(pairs, h.pairs[i+1:]...)
+
+	// drop i from its bucket and shift the indexes after it
+	for k, bucket := range h.buckets {
+		kept := bucket[:0]
+		for _, j := range bucket {
+			switch {
+			case j < i:
+				kept = append(kept, j)
+			case j > i:
+				kept = append(kept, j-1)
+			}
+		}
+		if len(kept) == 0 {
+			delete(h.buckets, k)
+		} else {
+			h.buckets[k] = kept
+		}
+	}
+	return value, true, nil
 }
+
+func hashKeyOf(key Object) (HashKey, *Error) {
+	if err := checkHashable(key); err != nil {
+		return HashKey{}, err
+	}
+	return key.(Hashtable).HashKey(), nil
+}
+
+// checkHashable fails unless key and, for tuples, all of its elements can be hashed.
+func checkHashable(key Object) *Error {
+	switch key := key.(type) {
+	case *Tuple:
+		for _, e := range key.Elements {
+			if err := checkHashable(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Hashtable:
+		return nil
+	}
+	return NewTypeError("unusable as hash key: %s", key.Type())
+}
+
+func (h *Hash) Type() ObjectType { return ObjHash }
+func (h *Hash) Inspect() string  { return inspect(h) }
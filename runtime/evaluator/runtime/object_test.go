@@ -1,6 +1,8 @@
 package runtime
 
 import (
+	"math"
+	"math/big"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -17,3 +19,161 @@ func TestStringHashKey(t *testing.T) {
 	assert.NotEqual(t, hello1.HashKey(), diff1.HashKey(), "strings with different content have same hash keys")
 
 }
+
+func TestBigIntHashKey(t *testing.T) {
+	big1 := &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 80)}
+	big2 := &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 80)}
+	neg := &BigInt{Value: new(big.Int).Neg(big1.Value)}
+
+	assert.Equal(t, big1.HashKey(), big2.HashKey(), "big ints with same value have different hash keys")
+	assert.NotEqual(t, big1.HashKey(), neg.HashKey(), "big ints with different sign have same hash keys")
+	assert.Equal(t, big1.HashKey(), (&Float{Value: 1 << 80}).HashKey(), "whole float must hash like the equal big int")
+}
+
+func TestHashOrder(t *testing.T) {
+	h := NewHash()
+	for _, k := range []string{"c", "a", "b", "d"} {
+		assert.Nil(t, h.Set(&String{Value: k}, &Integer{Value: int64(len(k))}))
+	}
+	assert.Equal(t, "{c:1, a:1, b:1, d:1}", h.Inspect())
+
+	// replacing a value keeps the place of the key
+	assert.Nil(t, h.Set(&String{Value: "a"}, &Integer{Value: 2}))
+	assert.Equal(t, "{c:1, a:2, b:1, d:1}", h.Inspect())
+
+	pairs := h.Pairs()
+	value, ok, err := h.Delete(&String{Value: "a"})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "2", value.Inspect())
+	assert.Equal(t, "{c:1, b:1, d:1}", h.Inspect())
+	assert.Len(t, pairs, 4, "pairs taken before a delete are left alone")
+
+	// the remaining keys are still found after the delete moved them
+	for _, k := range []string{"c", "b", "d"} {
+		_, ok, _ := h.Get(&String{Value: k})
+		assert.True(t, ok, k)
+	}
+	_, ok, _ = h.Delete(&String{Value: "a"})
+	assert.False(t, ok)
+
+	// a deleted key comes back at the end
+	assert.Nil(t, h.Set(&String{Value: "a"}, &Integer{Value: 3}))
+	assert.Equal(t, "{c:1, b:1, d:1, a:3}", h.Inspect())
+	assert.Equal(t, 4, h.Len())
+
+	assert.Equal(t, "{}", NewHash().Inspect())
+}
+
+// collider hashes every value to the same key, it is equal only to itself.
+type collider struct{ name string }
+
+func (c *collider) Type() ObjectType { return "Collider" }
+func (c *collider) Inspect() string  { return c.name }
+func (c *collider) HashKey() HashKey { return HashKey{Type: "Collider"} }
+
+func TestHashCollisions(t *testing.T) {
+	a, b, c := &collider{"a"}, &collider{"b"}, &collider{"c"}
+	h := NewHash()
+	for i, k := range []Object{a, b, c} {
+		assert.Nil(t, h.Set(k, &Integer{Value: int64(i)}))
+	}
+	assert.Equal(t, "{a:0, b:1, c:2}", h.Inspect())
+
+	value, ok, _ := h.Get(b)
+	assert.True(t, ok)
+	assert.Equal(t, "1", value.Inspect())
+
+	_, ok, _ = h.Delete(a)
+	assert.True(t, ok)
+	_, ok, _ = h.Get(a)
+	assert.False(t, ok)
+	for i, k := range []Object{b, c} {
+		value, ok, _ := h.Get(k)
+		assert.True(t, ok, k.Inspect())
+		assert.Equal(t, int64(i+1), value.(*Integer).Value, k.Inspect())
+	}
+	_, ok, _ = h.Get(&collider{"b"})
+	assert.False(t, ok, "a colliding key which is not equal must not be found")
+}
+
+func TestTupleHashKey(t *testing.T) {
+	tuple := func(elements ...Object) *Tuple { return &Tuple{Elements: elements} }
+	one, two := &Integer{Value: 1}, &String{Value: "2"}
+
+	assert.Equal(t, tuple(one, two).HashKey(), tuple(&Integer{Value: 1}, &String{Value: "2"}).HashKey())
+	assert.NotEqual(t, tuple(one, two).HashKey(), tuple(two, one).HashKey())
+	assert.Equal(t, tuple(one).HashKey(), tuple(&Float{Value: 1}).HashKey(), "whole float must hash like the equal integer")
+
+	_, err := hashKeyOf(tuple(one, &Array{}))
+	assert.Equal(t, "unusable as hash key: Array", err.Message)
+}
+
+func TestEqual(t *testing.T) {
+	arr := func(elements ...Object) *Array { return &Array{Elements: elements} }
+	one, two := &Integer{Value: 1}, &Integer{Value: 2}
+
+	h1, h2 := NewHash(), NewHash()
+	h1.Set(one, arr(two))
+	h1.Set(two, one)
+	h2.Set(two, &Float{Value: 1})
+	h2.Set(one, arr(&Integer{Value: 2}))
+
+	assert.True(t, Equal(arr(one, arr(two)), arr(one, arr(two))))
+	assert.False(t, Equal(arr(one, two), arr(two, one)))
+	assert.False(t, Equal(arr(one), &Tuple{Elements: []Object{one}}))
+	assert.True(t, Equal(h1, h2), "pairs in another order")
+	h2.Set(two, two)
+	assert.False(t, Equal(h1, h2))
+
+	c, err := Compare(arr(one, two), arr(one))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, c)
+}
+
+func TestRangeBounds(t *testing.T) {
+	tests := []struct {
+		r        *Range
+		expected []int64
+	}{
+		{&Range{Start: 0, Stop: math.MaxInt64, Step: 1 << 62}, []int64{0, 1 << 62}},
+		{&Range{Start: math.MinInt64, Stop: math.MaxInt64, Step: math.MaxInt64}, []int64{math.MinInt64, -1, math.MaxInt64 - 1}},
+		{&Range{Start: math.MaxInt64, Stop: math.MinInt64, Step: -(1 << 62)}, []int64{math.MaxInt64, math.MaxInt64 - 1<<62, math.MaxInt64 - 2<<62, math.MaxInt64 - 3<<62}},
+		{&Range{Start: math.MaxInt64, Stop: math.MinInt64, Step: math.MinInt64}, []int64{math.MaxInt64, -1}},
+		{&Range{Start: math.MaxInt64 - 1, Stop: math.MaxInt64, Step: math.MaxInt64}, []int64{math.MaxInt64 - 1}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, &Integer{Value: int64(len(tt.expected))}, tt.r.Len(), tt.r.Inspect())
+		it, err := NewIterator(tt.r)
+		assert.Nil(t, err)
+		got := []int64{}
+		for _, v, ok := it.Next(); ok; _, v, ok = it.Next() {
+			got = append(got, v.(*Integer).Value)
+		}
+		assert.Equal(t, tt.expected, got, tt.r.Inspect())
+	}
+
+	// more integers than an int64 counts
+	r := &Range{Start: math.MinInt64, Stop: math.MaxInt64, Step: 1}
+	assert.Equal(t, "18446744073709551615", r.Len().Inspect())
+	r = &Range{Start: -math.MaxInt64, Stop: math.MaxInt64, Step: 1}
+	assert.Equal(t, "18446744073709551614", r.Len().Inspect())
+	r = &Range{Start: 0, Stop: math.MaxInt64, Step: 1}
+	assert.Equal(t, &Integer{Value: math.MaxInt64}, r.Len())
+}
+
+func TestInspectCycles(t *testing.T) {
+	a := &Array{Elements: []Object{&Integer{Value: 1}}}
+	a.Elements = append(a.Elements, a)
+	assert.Equal(t, "[1, [...]]", a.Inspect())
+
+	h := NewHash()
+	h.Set(&String{Value: "me"}, h)
+	h.Set(&String{Value: "t"}, &Tuple{Elements: []Object{a}})
+	assert.Equal(t, "{me:{...}, t:([1, [...]],)}", h.Inspect())
+
+	// a value met twice without a cycle is written in full
+	b := &Array{Elements: []Object{a, a}}
+	assert.Equal(t, "[[1, [...]], [1, [...]]]", b.Inspect())
+}
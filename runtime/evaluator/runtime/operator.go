@@ -0,0 +1,486 @@
+package runtime
+
+import (
+	"math"
+	"math/big"
+	"strings"
+)
+
+// operator semantics shared by the tree-walking evaluator and the vm
+
+func NativeBool(b bool) *Boolean {
+	if b {
+		return True
+	}
+
+	return False
+}
+
+// IsTruthy reports whether obj counts as true in a condition,
+// everything except nil and false is truthy.
+func IsTruthy(obj Object) bool {
+	switch obj {
+	case Nil:
+		return false
+	case True:
+		return true
+	case False:
+		return false
+	}
+	return true
+}
+
+// EvalPrefix applies the prefix operator op to right.
+func EvalPrefix(op string, right Object) Object {
+	switch op {
+	case "!":
+		return evalBangOperatorExp(right)
+	case "-":
+		return evalMinusPrefixOperator(right)
+	default:
+		return NewTypeError("unknown operator: %s%s", op, right.Type())
+	}
+}
+
+func evalBangOperatorExp(right Object) Object {
+	switch right {
+	case True:
+		return False
+
+	case False:
+		return True
+	case Nil:
+		// not of null ? == True
+		return True
+	default:
+		return False
+	}
+}
+
+func evalMinusPrefixOperator(right Object) Object {
+	switch right := right.(type) {
+	case *Integer:
+		if right.Value == math.MinInt64 {
+			return NewInteger(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &Integer{Value: -right.Value}
+	case *BigInt:
+		return NewInteger(new(big.Int).Neg(right.Value))
+	case *Float:
+		return &Float{Value: -right.Value}
+	default:
+		return NewTypeError("unknown operator: -%s", right.Type())
+	}
+}
+
+// EvalInfix applies the infix operator op to left and right.
+func EvalInfix(op string, left, right Object) Object {
+	switch {
+	case left.Type() == ObjInteger && right.Type() == ObjInteger:
+		return evalIntegerInfixExpression(op, left, right)
+	case isInteger(left) && isInteger(right):
+		return evalBigIntInfixExpression(op, left, right)
+	case isNumber(left) && isNumber(right):
+		// one of them is a float, the integer is converted
+		return evalFloatInfixExpression(op, left, right)
+	case left.Type() == ObjString && right.Type() == ObjString:
+		return evalStringInfixExpression(op, left, right)
+
+	case left.Type() != right.Type():
+		return NewTypeError("type mismatch: %s %s %s", left.Type(), op, right.Type())
+	case op == "==":
+		return NativeBool(Equal(left, right))
+
+	case op == "!=":
+		return NativeBool(!Equal(left, right))
+	case (op == "<" || op == ">") && (left.Type() == ObjArray || left.Type() == ObjTuple):
+		return evalOrderExpression(op, left, right)
+	default:
+		return NewTypeError("unknown operator: %s %s %s", left.Type(), op, right.Type())
+	}
+}
+
+// Equal reports whether a and b hold the same value. Numbers and strings
+// compare by value, arrays, tuples and hashes by their contents and other
+// objects, functions among them, by identity. The order of the pairs does
+// not matter for hashes. Values holding themselves are equal when nothing
+// but the cycle tells them apart.
+func Equal(a, b Object) bool {
+	return equal(a, b, nil)
+}
+
+// pair is a pair of values being compared, seen again they form a cycle.
+type pair struct {
+	a, b Object
+}
+
+func equal(a, b Object, seen map[pair]bool) bool {
+	if a == b {
+		return true
+	}
+	switch {
+	case isNumber(a) && isNumber(b):
+		c, _ := Compare(a, b)
+		return c == 0
+	case a.Type() != b.Type():
+		return false
+	}
+
+	switch a.(type) {
+	case *Array, *Tuple, *Hash:
+		if seen == nil {
+			seen = map[pair]bool{}
+		}
+		// a pair met again is being compared already, any difference shows there
+		if seen[pair{a, b}] {
+			return true
+		}
+		seen[pair{a, b}] = true
+	}
+
+	switch a := a.(type) {
+	case *String:
+		return a.Value == b.(*String).Value
+	case *Array:
+		return equalElements(a.Elements, b.(*Array).Elements, seen)
+	case *Tuple:
+		return equalElements(a.Elements, b.(*Tuple).Elements, seen)
+	case *Hash:
+		h := b.(*Hash)
+		if a.Len() != h.Len() {
+			return false
+		}
+		for _, p := range a.Pairs() {
+			value, ok, _ := h.Get(p.Key)
+			if !ok || !equal(p.Value, value, seen) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func equalElements(a, b []Object, seen map[pair]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equal(a[i], b[i], seen) {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare orders two numbers or two strings, and arrays or tuples
+// lexicographically by their elements, returning -1, 0 or +1 as a is
+// less than, equal to or greater than b. Arrays holding themselves cannot
+// be ordered.
+func Compare(a, b Object) (int, *Error) {
+	return compare(a, b, nil)
+}
+
+func compare(a, b Object, seen map[pair]bool) (int, *Error) {
+	switch {
+	case a.Type() == ObjInteger && b.Type() == ObjInteger:
+		l, r := a.(*Integer).Value, b.(*Integer).Value
+		switch {
+		case l < r:
+			return -1, nil
+		case l > r:
+			return 1, nil
+		}
+		return 0, nil
+	case isInteger(a) && isInteger(b):
+		l, _ := ToBig(a)
+		r, _ := ToBig(b)
+		return l.Cmp(r), nil
+	case a.Type() == ObjFloat && b.Type() == ObjFloat:
+		l, r := a.(*Float).Value, b.(*Float).Value
+		switch {
+		case l < r:
+			return -1, nil
+		case l > r:
+			return 1, nil
+		}
+		return 0, nil
+	case isNumber(a) && isNumber(b):
+		return compareMixed(a, b), nil
+	case a.Type() == ObjString && b.Type() == ObjString:
+		return strings.Compare(a.(*String).Value, b.(*String).Value), nil
+	case a.Type() == b.Type() && (a.Type() == ObjArray || a.Type() == ObjTuple):
+		if seen == nil {
+			seen = map[pair]bool{}
+		}
+		if seen[pair{a, b}] {
+			return 0, NewTypeError("cannot compare %s holding itself", a.Type())
+		}
+		seen[pair{a, b}] = true
+		defer delete(seen, pair{a, b})
+
+		l, _ := elementsOf(a)
+		r, _ := elementsOf(b)
+		for i := 0; i < len(l) && i < len(r); i++ {
+			c, err := compare(l[i], r[i], seen)
+			if err != nil || c != 0 {
+				return c, err
+			}
+		}
+		// a prefix goes first
+		switch {
+		case len(l) < len(r):
+			return -1, nil
+		case len(l) > len(r):
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return 0, NewTypeError("cannot compare %s with %s", a.Type(), b.Type())
+	}
+}
+
+// evalOrderExpression evaluates < and > with Compare.
+func evalOrderExpression(op string, left, right Object) Object {
+	c, err := Compare(left, right)
+	if err != nil {
+		return err
+	}
+	if op == "<" {
+		return NativeBool(c < 0)
+	}
+	return NativeBool(c > 0)
+}
+
+func evalStringInfixExpression(op string, left, right Object) Object {
+	switch op {
+	case "==", "!=":
+		return NativeBool(Equal(left, right) == (op == "=="))
+	case "<", ">":
+		return evalOrderExpression(op, left, right)
+	case "+":
+	default:
+		return NewTypeError("unknown operator: %s %s %s", left.Type(), op, right.Type())
+	}
+	l := left.(*String)
+	r := right.(*String)
+	return &String{
+		Value: l.Value + r.Value,
+	}
+}
+
+func evalIntegerInfixExpression(op string, left, right Object) Object {
+	lval := left.(*Integer).Value
+	rval := right.(*Integer).Value
+
+	if overflows(op, lval, rval) {
+		return evalBigIntInfixExpression(op, left, right)
+	}
+
+	res := int64(0)
+	switch op {
+	case "+":
+		res = lval + rval
+
+	case "-":
+		res = lval - rval
+
+	case "*":
+		res = lval * rval
+
+	case "/":
+		if rval == 0 {
+			return NewError("division by zero")
+		}
+		res = lval / rval
+	case "<":
+		return NativeBool(lval < rval)
+
+	case ">":
+		return NativeBool(lval > rval)
+
+	case "==":
+		return NativeBool(lval == rval)
+	case "!=":
+
+		return NativeBool(lval != rval)
+
+	default:
+		return NewTypeError("unknown operator: %s %s %s", left.Type(), op, right.Type())
+	}
+
+	return &Integer{
+		Value: res,
+	}
+}
+
+// compareMixed orders an integer and a float exactly, rather than rounding
+// the integer to the nearest float. NaN is neither less nor greater than
+// any integer.
+func compareMixed(a, b Object) int {
+	l, lok := exactFloat(a)
+	r, rok := exactFloat(b)
+	if !lok || !rok {
+		return 0
+	}
+	return l.Cmp(r)
+}
+
+// exactFloat returns the value of a number as a big.Float without rounding,
+// it fails for NaN.
+func exactFloat(obj Object) (*big.Float, bool) {
+	if f, ok := obj.(*Float); ok {
+		if math.IsNaN(f.Value) {
+			return nil, false
+		}
+		return new(big.Float).SetFloat64(f.Value), true
+	}
+	i, _ := ToBig(obj)
+	return new(big.Float).SetInt(i), true
+}
+
+func isNumber(obj Object) bool {
+	return isInteger(obj) || obj.Type() == ObjFloat
+}
+
+// ToFloat returns the value of an Integer, BigInt or Float as a float64.
+func ToFloat(obj Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value), true
+	case *BigInt:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f, true
+	case *Float:
+		return obj.Value, true
+	default:
+		return 0, false
+	}
+}
+
+func evalFloatInfixExpression(op string, left, right Object) Object {
+	lval, _ := ToFloat(left)
+	rval, _ := ToFloat(right)
+
+	if left.Type() != right.Type() && !math.IsNaN(lval) && !math.IsNaN(rval) {
+		// an integer is compared exactly, not rounded to a float
+		switch op {
+		case "<":
+			return NativeBool(compareMixed(left, right) < 0)
+		case ">":
+			return NativeBool(compareMixed(left, right) > 0)
+		case "==":
+			return NativeBool(compareMixed(left, right) == 0)
+		case "!=":
+			return NativeBool(compareMixed(left, right) != 0)
+		}
+	}
+
+	res := float64(0)
+	switch op {
+	case "+":
+		res = lval + rval
+	case "-":
+		res = lval - rval
+	case "*":
+		res = lval * rval
+	case "/":
+		if rval == 0 {
+			return NewError("division by zero")
+		}
+		res = lval / rval
+	case "<":
+		return NativeBool(lval < rval)
+	case ">":
+		return NativeBool(lval > rval)
+	case "==":
+		return NativeBool(lval == rval)
+	case "!=":
+		return NativeBool(lval != rval)
+	default:
+		return NewTypeError("unknown operator: %s %s %s", left.Type(), op, right.Type())
+	}
+
+	return &Float{
+		Value: res,
+	}
+}
+
+// EvalIndex evaluates left[idx].
+func EvalIndex(left, idx Object) Object {
+	switch {
+
+	case (left.Type() == ObjArray || left.Type() == ObjTuple) && idx.Type() == ObjInteger:
+		return evalArrayIndexExpression(left, idx)
+	case left.Type() == ObjHash:
+		return evalHashIndexExpression(left, idx)
+	case left.Type() == ObjErrorValue:
+		name, ok := idx.(*String)
+		if !ok {
+			return NewTypeError("error field must be String, got %s", idx.Type())
+		}
+		if field := left.(*ErrorValue).Field(name.Value); field != nil {
+			return field
+		}
+		return Nil
+	default:
+		return NewTypeError("index operator not supported: %s", left.Type())
+	}
+}
+
+// AssignIndex stores value at idx of the array or hash left and returns it.
+// A non empty op makes it a compound assignment, value is then combined
+// with the element currently at idx.
+func AssignIndex(left, idx Object, op string, value Object) Object {
+	if op != "" {
+		current := EvalIndex(left, idx)
+		if IsError(current) {
+			return current
+		}
+		value = EvalInfix(op, current, value)
+		if IsError(value) {
+			return value
+		}
+	}
+
+	switch left := left.(type) {
+	case *Array:
+		i, ok := idx.(*Integer)
+		if !ok {
+			return NewTypeError("array index must be Integer, got %s", idx.Type())
+		}
+		if i.Value < 0 || i.Value >= left.Len() {
+			return NewIndexError("index out of range: %d with length %d", i.Value, left.Len())
+		}
+		left.Elements[i.Value] = value
+	case *Hash:
+		if err := left.Set(idx, value); err != nil {
+			return err
+		}
+	default:
+		return NewTypeError("index assignment not supported: %s", left.Type())
+	}
+	return value
+}
+
+func evalArrayIndexExpression(left, idx Object) Object {
+	elements, _ := elementsOf(left)
+	i := idx.(*Integer).Value
+	max := int64(len(elements)) - 1
+	if i < 0 || i > max {
+		// out of bound access os nil
+		return Nil
+	}
+	return elements[i]
+}
+
+func evalHashIndexExpression(hash, idx Object) Object {
+	value, ok, err := hash.(*Hash).Get(idx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return Nil
+	}
+	return value
+}
@@ -2,23 +2,54 @@ package runtime
 
 import (
 	"fmt"
+	"io"
+	"sort"
 )
 
 type Runtime struct {
-	store map[string]Object
-	outer *Runtime
+	store  map[string]Object
+	outer  *Runtime
+	budget *Budget
 }
 
 func New() *Runtime {
 	return &Runtime{
-		store: map[string]Object{},
+		store:  map[string]Object{},
+		budget: NewBudget(DefaultLimits),
 	}
 }
 
+// SetLimits changes the limits of the evaluations in r and in all its scopes.
+func (r *Runtime) SetLimits(limits Limits) {
+	r.budget.limits = limits
+}
+
+// Limits returns the limits of the evaluations in r.
+func (r *Runtime) Limits() Limits {
+	return r.budget.limits
+}
+
+// Budget returns the budget shared by r and all its scopes.
+func (r *Runtime) Budget() *Budget {
+	return r.budget
+}
+
 func (r *Runtime) Put(name string, obj Object) {
 	r.store[name] = obj
 }
 
+// Set rebinds name in the scope it was declared in, it reports false when
+// name is not declared in any enclosing scope.
+func (r *Runtime) Set(name string, obj Object) bool {
+	for s := r; s != nil; s = s.outer {
+		if _, ok := s.store[name]; ok {
+			s.store[name] = obj
+			return true
+		}
+	}
+	return false
+}
+
 func (r *Runtime) Get(name string) (Object, bool) {
 	v, ok := r.store[name]
 	// if we dont find it in current scop, we will check outer scope
@@ -29,14 +60,38 @@ func (r *Runtime) Get(name string) (Object, bool) {
 	return v, ok
 }
 
-func (r *Runtime) PrintVars() {
-	for k, v := range r.store {
-		fmt.Printf(">%s = %s \n", k, v.Inspect())
+// Names returns the names bound in r and its enclosing scopes, sorted.
+func (r *Runtime) Names() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for s := r; s != nil; s = s.outer {
+		for name := range s.store {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PrintVars writes the names bound in the scope r and their values to out.
+func (r *Runtime) PrintVars(out io.Writer) {
+	names := make([]string, 0, len(r.store))
+	for k := range r.store {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		fmt.Fprintf(out, ">%s = %s \n", k, r.store[k].Inspect())
 	}
 }
 
 func NewScope(outer *Runtime) *Runtime {
-	rt := New()
-	rt.outer = outer
-	return rt
+	return &Runtime{
+		store:  map[string]Object{},
+		outer:  outer,
+		budget: outer.budget,
+	}
 }
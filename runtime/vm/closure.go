@@ -0,0 +1,37 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+)
+
+// Upvalue is a variable captured by a closure. While the frame owning the
+// variable is live it points into the stack, once the frame returns the value
+// is moved into the upvalue itself so the closure keeps seeing it.
+type Upvalue struct {
+	loc    *runtime.Object
+	closed runtime.Object
+	slot   int
+}
+
+func (u *Upvalue) close() {
+	u.closed = *u.loc
+	u.loc = &u.closed
+	u.slot = -1
+}
+
+// Closure is a compiled function along with the variables it captured.
+type Closure struct {
+	Fn   *runtime.CompiledFunction
+	Free []*Upvalue
+}
+
+// Type reports closures as functions, they are what a function literal evaluates to.
+func (c *Closure) Type() runtime.ObjectType { return runtime.ObjFunction }
+func (c *Closure) Inspect() string {
+	if c.Fn.Name != "" {
+		return fmt.Sprintf("fn %s/%d", c.Fn.Name, c.Fn.NumParameters)
+	}
+	return fmt.Sprintf("fn/%d", c.Fn.NumParameters)
+}
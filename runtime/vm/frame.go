@@ -0,0 +1,25 @@
+package vm
+
+import "github.com/NishanthSpShetty/monkey/runtime/code"
+
+type Frame struct {
+	cl *Closure
+	ip int
+	// basePointer is the stack slot of the first local of the frame
+	basePointer int
+	// argc is the number of arguments the function was called with
+	argc int
+	// loops holds the stack pointer at the start of each running loop, innermost last
+	loops []int
+}
+
+func NewFrame(cl *Closure, basePointer int) *Frame {
+	return &Frame{
+		cl:          cl,
+		basePointer: basePointer,
+	}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
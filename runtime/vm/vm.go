@@ -0,0 +1,689 @@
+package vm
+
+import (
+	"context"
+
+	"github.com/NishanthSpShetty/monkey/runtime/code"
+	"github.com/NishanthSpShetty/monkey/runtime/compiler"
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+)
+
+const (
+	// StackSize is the initial size of the stack, it grows as needed. The
+	// calls nest as deep as runtime.DefaultLimits allow, as in the evaluator.
+	StackSize   = 2048
+	GlobalsSize = 65536
+)
+
+var infixOperators = map[code.Opcode]string{
+	code.OpAdd:         "+",
+	code.OpSub:         "-",
+	code.OpMul:         "*",
+	code.OpDiv:         "/",
+	code.OpEqual:       "==",
+	code.OpNotEqual:    "!=",
+	code.OpLessThan:    "<",
+	code.OpGreaterThan: ">",
+}
+
+type VM struct {
+	constants   []runtime.Object
+	globals     []runtime.Object
+	globalNames []string
+
+	stack []runtime.Object
+	// sp points to the next free slot, the top of the stack is stack[sp-1]
+	sp int
+
+	frames      []*Frame
+	framesIndex int
+	// budget bounds the resources used by the program
+	budget *runtime.Budget
+
+	// upvalues still pointing into the stack, ordered by slot
+	openUpvalues []*Upvalue
+
+	// handlers are the running try blocks, innermost last
+	handlers []handler
+}
+
+// handler is where an error raised in a try block is caught.
+type handler struct {
+	// frame is the framesIndex of the frame running the try
+	frame int
+	// sp is the stack pointer when the try started
+	sp int
+	// loops is the number of loops of the frame running around the try
+	loops int
+	// catch is the instruction the error value is pushed for
+	catch int
+}
+
+func New(bytecode *compiler.Bytecode) *VM {
+	return NewWithGlobals(bytecode, make([]runtime.Object, GlobalsSize))
+}
+
+// NewWithGlobals creates a vm sharing the globals of a previous run, as a REPL session does.
+func NewWithGlobals(bytecode *compiler.Bytecode, globals []runtime.Object) *VM {
+	mainFn := &runtime.CompiledFunction{
+		Instructions: bytecode.Instructions,
+		Positions:    bytecode.Positions,
+	}
+	frames := []*Frame{NewFrame(&Closure{Fn: mainFn}, 0)}
+
+	return &VM{
+		constants:   bytecode.Constants,
+		globals:     globals,
+		globalNames: bytecode.Globals,
+		stack:       make([]runtime.Object, StackSize),
+		frames:      frames,
+		framesIndex: 1,
+		budget:      runtime.NewBudget(runtime.DefaultLimits),
+	}
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+// SetLimits bounds the resources the program may use as runtime.Limits say,
+// the steps counted are the instructions run. runtime.DefaultLimits apply
+// until it is called.
+func (vm *VM) SetLimits(limits runtime.Limits) {
+	vm.budget = runtime.NewBudget(limits)
+}
+
+// Run executes the bytecode and returns the value of the program, like
+// evaluator.Eval it returns a *runtime.Error when the program fails.
+func (vm *VM) Run() runtime.Object {
+	return vm.RunContext(context.Background())
+}
+
+// RunContext is Run stopping with a runtime.Canceled error when ctx is done.
+func (vm *VM) RunContext(ctx context.Context) runtime.Object {
+	vm.budget.Start(ctx)
+	return vm.run(0)
+}
+
+// run executes instructions until the frame above base returns, its return
+// value is the result. With a base of 0 it runs the whole program.
+func (vm *VM) run(base int) runtime.Object {
+	for {
+		frame := vm.currentFrame()
+		ins := frame.Instructions()
+		if frame.ip >= len(ins) {
+			return nil
+		}
+		op := code.Opcode(ins[frame.ip])
+		frame.ip++
+		if err := vm.budget.Step(); err != nil {
+			vm.locate(err)
+			return err
+		}
+
+		var err *runtime.Error
+		switch op {
+		case code.OpConstant:
+			idx := code.ReadUint16(ins[frame.ip:])
+			frame.ip += 2
+			err = vm.push(vm.constants[idx])
+
+		case code.OpPop:
+			vm.pop()
+
+		case code.OpTrue:
+			err = vm.push(runtime.True)
+		case code.OpFalse:
+			err = vm.push(runtime.False)
+		case code.OpNil:
+			err = vm.push(runtime.Nil)
+
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv,
+			code.OpEqual, code.OpNotEqual, code.OpLessThan, code.OpGreaterThan:
+			right := vm.pop()
+			left := vm.pop()
+			err = vm.pushNew(runtime.EvalInfix(infixOperators[op], left, right))
+
+		case code.OpMinus:
+			err = vm.pushNew(runtime.EvalPrefix("-", vm.pop()))
+		case code.OpBang:
+			err = vm.pushNew(runtime.EvalPrefix("!", vm.pop()))
+
+		case code.OpJump:
+			frame.ip = int(code.ReadUint16(ins[frame.ip:]))
+
+		case code.OpJumpNotTruthy:
+			target := int(code.ReadUint16(ins[frame.ip:]))
+			frame.ip += 2
+			if !runtime.IsTruthy(vm.pop()) {
+				frame.ip = target
+			}
+
+		case code.OpSetGlobal:
+			idx := code.ReadUint16(ins[frame.ip:])
+			frame.ip += 2
+			vm.globals[idx] = vm.pop()
+
+		case code.OpAssignGlobal:
+			idx := code.ReadUint16(ins[frame.ip:])
+			frame.ip += 2
+			if vm.globals[idx] == nil {
+				err = runtime.NewNameError("assignment to undeclared identifier: %s", vm.globalNames[idx])
+				break
+			}
+			vm.globals[idx] = vm.pop()
+
+		case code.OpGetGlobal:
+			idx := code.ReadUint16(ins[frame.ip:])
+			frame.ip += 2
+			err = vm.pushGlobal(int(idx))
+
+		case code.OpSetLocal:
+			idx := code.ReadUint8(ins[frame.ip:])
+			frame.ip += 1
+			vm.stack[frame.basePointer+int(idx)] = vm.pop()
+
+		case code.OpGetLocal:
+			idx := code.ReadUint8(ins[frame.ip:])
+			frame.ip += 1
+			err = vm.push(vm.stack[frame.basePointer+int(idx)])
+
+		case code.OpGetFree:
+			idx := code.ReadUint8(ins[frame.ip:])
+			frame.ip += 1
+			err = vm.push(*frame.cl.Free[idx].loc)
+
+		case code.OpSetFree:
+			idx := code.ReadUint8(ins[frame.ip:])
+			frame.ip += 1
+			*frame.cl.Free[idx].loc = vm.pop()
+
+		case code.OpArray:
+			n := int(code.ReadUint16(ins[frame.ip:]))
+			frame.ip += 2
+			elements := make([]runtime.Object, n)
+			copy(elements, vm.stack[vm.sp-n:vm.sp])
+			vm.sp -= n
+			err = vm.pushNew(&runtime.Array{Elements: elements})
+
+		case code.OpHash:
+			n := int(code.ReadUint16(ins[frame.ip:]))
+			frame.ip += 2
+			err = vm.buildHash(n)
+
+		case code.OpIndex:
+			idx := vm.pop()
+			left := vm.pop()
+			err = vm.pushResult(runtime.EvalIndex(left, idx))
+
+		case code.OpSetIndex:
+			op := code.Opcode(code.ReadUint8(ins[frame.ip:]))
+			frame.ip += 1
+			value := vm.pop()
+			idx := vm.pop()
+			left := vm.pop()
+			err = vm.pushResult(runtime.AssignIndex(left, idx, infixOperators[op], value))
+
+		case code.OpClosure:
+			idx := code.ReadUint16(ins[frame.ip:])
+			frame.ip += 2
+			err = vm.pushClosure(int(idx))
+
+		case code.OpCall:
+			argc := int(code.ReadUint8(ins[frame.ip:]))
+			frame.ip += 1
+			err = vm.call(argc)
+
+		case code.OpSpread:
+			elements, serr := runtime.Spread(&caller{vm: vm}, vm.pop())
+			if serr != nil {
+				err = serr
+				break
+			}
+			err = vm.push(&spread{elements: elements})
+
+		case code.OpCallSpread:
+			argc := int(code.ReadUint8(ins[frame.ip:]))
+			frame.ip += 1
+			if argc, err = vm.flatten(argc); err == nil {
+				err = vm.call(argc)
+			}
+
+		case code.OpDefault:
+			idx := int(code.ReadUint8(ins[frame.ip:]))
+			if idx < frame.argc {
+				frame.ip = int(code.ReadUint16(ins[frame.ip+1:]))
+				break
+			}
+			frame.ip += 3
+
+		case code.OpLoop:
+			frame.loops = append(frame.loops, vm.sp)
+
+		case code.OpLoopEnd:
+			frame.loops = frame.loops[:len(frame.loops)-1]
+
+		case code.OpJumpLoop:
+			// break and continue leave values of the body behind
+			vm.sp = frame.loops[len(frame.loops)-1]
+			frame.ip = int(code.ReadUint16(ins[frame.ip:]))
+
+		case code.OpTry:
+			vm.handlers = append(vm.handlers, handler{
+				frame: vm.framesIndex,
+				sp:    vm.sp,
+				loops: len(frame.loops),
+				catch: int(code.ReadUint16(ins[frame.ip:])),
+			})
+			frame.ip += 2
+
+		case code.OpEndTry:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+		case code.OpThrow:
+			err = runtime.Throw(vm.pop())
+
+		case code.OpIter:
+			it, ierr := runtime.NewIterator(vm.pop())
+			if ierr != nil {
+				err = ierr
+				break
+			}
+			err = vm.push(it)
+
+		case code.OpIterNext:
+			end := int(code.ReadUint16(ins[frame.ip:]))
+			vars := int(code.ReadUint8(ins[frame.ip+2:]))
+			frame.ip += 3
+			err = vm.iterNext(end, vars)
+
+		case code.OpReturnValue, code.OpReturn:
+			var rv runtime.Object = runtime.Nil
+			if op == code.OpReturnValue {
+				rv = vm.pop()
+			}
+			vm.closeUpvalues(frame.basePointer)
+			vm.dropHandlers()
+			if vm.framesIndex == 1 {
+				// returning from the program itself
+				if op == code.OpReturn {
+					return nil
+				}
+				return rv
+			}
+			vm.framesIndex--
+			vm.budget.Leave()
+			vm.sp = frame.basePointer - 1
+			if vm.framesIndex == base {
+				// back in the builtin which called the function
+				return rv
+			}
+			err = vm.push(rv)
+
+		default:
+			err = runtime.NewError("unknown opcode %d", op)
+		}
+
+		if err != nil {
+			vm.locate(err)
+			if !vm.catch(err, base) {
+				return err
+			}
+		}
+	}
+}
+
+// catch unwinds the stack to the innermost try block run above base and
+// pushes the error value for its catch. It reports false when there is no
+// such block, or err is an exceeded limit, which cannot be caught.
+func (vm *VM) catch(err *runtime.Error, base int) bool {
+	if len(vm.handlers) == 0 || !err.Catchable() {
+		return false
+	}
+	h := vm.handlers[len(vm.handlers)-1]
+	if h.frame <= base {
+		// the try is around the builtin which called the function
+		return false
+	}
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+	vm.closeUpvalues(h.sp)
+	for ; vm.framesIndex > h.frame; vm.framesIndex-- {
+		vm.budget.Leave()
+	}
+	vm.sp = h.sp
+	frame := vm.currentFrame()
+	frame.loops = frame.loops[:h.loops]
+	frame.ip = h.catch
+	vm.grow(vm.sp + 1)
+	vm.stack[vm.sp] = &runtime.ErrorValue{Err: err}
+	vm.sp++
+	return true
+}
+
+// dropHandlers forgets the try blocks of the frame returning.
+func (vm *VM) dropHandlers() {
+	i := len(vm.handlers)
+	for i > 0 && vm.handlers[i-1].frame >= vm.framesIndex {
+		i--
+	}
+	vm.handlers = vm.handlers[:i]
+}
+
+// locate sets the position of err to the source of the instruction being run
+// and its stack to the calls running it, unless they were set where it was raised.
+func (vm *VM) locate(err *runtime.Error) {
+	if err.Stack == nil {
+		err.Stack = vm.stackTrace()
+	}
+	if err.Pos.IsValid() {
+		return
+	}
+	frame := vm.currentFrame()
+	// ip has moved past the opcode, any offset within the instruction maps to it
+	err.Pos = frame.cl.Fn.Positions.Lookup(frame.ip - 1)
+	err.File = frame.cl.Fn.File
+}
+
+// stackTrace describes the calls of the running frames, innermost first.
+func (vm *VM) stackTrace() []runtime.StackFrame {
+	var stack []runtime.StackFrame
+	for i := vm.framesIndex - 1; i > 0; i-- {
+		frame, caller := vm.frames[i], vm.frames[i-1]
+		fn := frame.cl.Fn
+		// the arguments as they were passed, without defaults
+		args := vm.stack[frame.basePointer : frame.basePointer+min(frame.argc, fn.NumParameters)]
+		if rest, ok := vm.stack[frame.basePointer+fn.NumParameters].(*runtime.Array); ok && fn.Variadic {
+			args = append(args[:len(args):len(args)], rest.Elements...)
+		}
+		// the caller is past its OpCall, which has the position of the call
+		pos := caller.cl.Fn.Positions.Lookup(caller.ip - 1)
+		sf := runtime.NewStackFrame(fn.Name, pos, args)
+		sf.File = caller.cl.Fn.File
+		stack = append(stack, sf)
+	}
+	return stack
+}
+
+func (vm *VM) push(obj runtime.Object) *runtime.Error {
+	vm.grow(vm.sp + 1)
+	vm.stack[vm.sp] = obj
+	vm.sp++
+	return nil
+}
+
+// grow makes room for size slots on the stack, moving the open upvalues
+// along with the slots they point to.
+func (vm *VM) grow(size int) {
+	if size <= len(vm.stack) {
+		return
+	}
+	stack := make([]runtime.Object, max(size, 2*len(vm.stack)))
+	copy(stack, vm.stack)
+	vm.stack = stack
+	for _, uv := range vm.openUpvalues {
+		uv.loc = &vm.stack[uv.slot]
+	}
+}
+
+// pushResult pushes the result of an operation, failing when it is an error.
+func (vm *VM) pushResult(obj runtime.Object) *runtime.Error {
+	if err, ok := obj.(*runtime.Error); ok {
+		return err
+	}
+	if obj == nil {
+		obj = runtime.Nil
+	}
+	return vm.push(obj)
+}
+
+// pushNew pushes obj like pushResult, charging the budget for obj which
+// the operation just created.
+func (vm *VM) pushNew(obj runtime.Object) *runtime.Error {
+	if err := vm.alloc(runtime.SizeOf(obj)); err != nil {
+		return err
+	}
+	return vm.pushResult(obj)
+}
+
+// alloc charges size bytes allocated to the budget.
+func (vm *VM) alloc(size int64) *runtime.Error {
+	if size <= 0 {
+		return nil
+	}
+	return vm.budget.Alloc(size)
+}
+
+func (vm *VM) pop() runtime.Object {
+	obj := vm.stack[vm.sp-1]
+	vm.sp--
+	return obj
+}
+
+// caller is the runtime.Caller of builtins called by the vm, they run their
+// function arguments and charge their work through it.
+type caller struct {
+	vm *VM
+	// charged is what the builtin allocated so far
+	charged int64
+}
+
+func (c *caller) Call(fn runtime.Object, args ...runtime.Object) runtime.Object {
+	return c.vm.callFunction(fn, args...)
+}
+
+func (c *caller) Step() *runtime.Error {
+	return c.vm.budget.Step()
+}
+
+func (c *caller) Alloc(size int64) *runtime.Error {
+	c.charged += size
+	return c.vm.budget.Alloc(size)
+}
+
+// callFunction runs fn to completion on top of the current stack so
+// builtins can call the functions they are given.
+func (vm *VM) callFunction(fn runtime.Object, args ...runtime.Object) runtime.Object {
+	base := vm.framesIndex
+	if err := vm.push(fn); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if err := vm.push(arg); err != nil {
+			return err
+		}
+	}
+	if err := vm.call(len(args)); err != nil {
+		return err
+	}
+	if vm.framesIndex == base {
+		// a builtin, which left its result on the stack
+		return vm.pop()
+	}
+	return vm.run(base)
+}
+
+func (vm *VM) pushGlobal(idx int) *runtime.Error {
+	if val := vm.globals[idx]; val != nil {
+		return vm.push(val)
+	}
+
+	// not bound yet, look at builtins like the evaluator does
+	name := vm.globalNames[idx]
+	if bf, ok := runtime.GetBuiltin(name); ok {
+		return vm.push(bf)
+	}
+	return runtime.NewNameError("identifier not found: %s", name)
+}
+
+// iterNext pushes the next variables of the iterator on top of the stack,
+// or jumps to end when it is exhausted.
+func (vm *VM) iterNext(end, vars int) *runtime.Error {
+	it := vm.stack[vm.sp-1].(*runtime.Iterator)
+	key, value, ok := it.Next()
+	if !ok {
+		vm.currentFrame().ip = end
+		return nil
+	}
+	if vars == 1 {
+		return vm.push(it.Single(key, value))
+	}
+	if err := vm.push(key); err != nil {
+		return err
+	}
+	return vm.push(value)
+}
+
+func (vm *VM) buildHash(n int) *runtime.Error {
+	h := runtime.NewHash()
+	for i := vm.sp - n; i < vm.sp; i += 2 {
+		if err := h.Set(vm.stack[i], vm.stack[i+1]); err != nil {
+			return err
+		}
+	}
+	vm.sp -= n
+	return vm.pushNew(h)
+}
+
+func (vm *VM) pushClosure(idx int) *runtime.Error {
+	fn, ok := vm.constants[idx].(*runtime.CompiledFunction)
+	if !ok {
+		return runtime.NewTypeError("not a function: %s", vm.constants[idx].Type())
+	}
+
+	frame := vm.currentFrame()
+	free := make([]*Upvalue, len(fn.Free))
+	for i, fv := range fn.Free {
+		if fv.Local {
+			free[i] = vm.captureUpvalue(frame.basePointer + fv.Index)
+		} else {
+			free[i] = frame.cl.Free[fv.Index]
+		}
+	}
+	return vm.push(&Closure{Fn: fn, Free: free})
+}
+
+// captureUpvalue returns the upvalue for a stack slot, sharing it between all
+// closures capturing the same variable.
+func (vm *VM) captureUpvalue(slot int) *Upvalue {
+	i := len(vm.openUpvalues)
+	for i > 0 && vm.openUpvalues[i-1].slot >= slot {
+		if vm.openUpvalues[i-1].slot == slot {
+			return vm.openUpvalues[i-1]
+		}
+		i--
+	}
+
+	uv := &Upvalue{loc: &vm.stack[slot], slot: slot}
+	vm.openUpvalues = append(vm.openUpvalues, nil)
+	copy(vm.openUpvalues[i+1:], vm.openUpvalues[i:])
+	vm.openUpvalues[i] = uv
+	return uv
+}
+
+// closeUpvalues detaches the upvalues of slots at or above last from the stack.
+func (vm *VM) closeUpvalues(last int) {
+	i := len(vm.openUpvalues)
+	for i > 0 && vm.openUpvalues[i-1].slot >= last {
+		vm.openUpvalues[i-1].close()
+		i--
+	}
+	vm.openUpvalues = vm.openUpvalues[:i]
+}
+
+// spread holds the elements of an argument spread into a call until
+// OpCallSpread passes them on, it never reaches the program.
+type spread struct {
+	elements []runtime.Object
+}
+
+func (s *spread) Type() runtime.ObjectType { return "Spread" }
+func (s *spread) Inspect() string          { return "..." }
+
+// flatten replaces the argc arguments on top of the stack by the elements
+// of the spread ones, it returns the number of arguments then.
+func (vm *VM) flatten(argc int) (int, *runtime.Error) {
+	args := make([]runtime.Object, 0, argc)
+	for _, arg := range vm.stack[vm.sp-argc : vm.sp] {
+		if s, ok := arg.(*spread); ok {
+			args = append(args, s.elements...)
+		} else {
+			args = append(args, arg)
+		}
+	}
+	vm.sp -= argc
+	vm.grow(vm.sp + len(args))
+	vm.sp += copy(vm.stack[vm.sp:], args)
+	return len(args), nil
+}
+
+func (vm *VM) call(argc int) *runtime.Error {
+	callee := vm.stack[vm.sp-1-argc]
+	switch callee := callee.(type) {
+	case *Closure:
+		fn := callee.Fn
+		most := fn.NumParameters
+		if fn.Variadic {
+			most = -1
+		}
+		if err := runtime.CheckArity(fn.NumRequired, most, argc); err != nil {
+			return err
+		}
+		frame := NewFrame(callee, vm.sp-argc)
+		frame.argc = argc
+		top := frame.basePointer + fn.NumLocals
+		if err := vm.budget.Enter(); err != nil {
+			return err
+		}
+		vm.grow(top)
+		if vm.framesIndex == len(vm.frames) {
+			vm.frames = append(vm.frames, frame)
+		} else {
+			vm.frames[vm.framesIndex] = frame
+		}
+		vm.framesIndex++
+
+		var rest *runtime.Array
+		if fn.Variadic {
+			// the arguments past the parameters are collected in the local after them
+			rest = &runtime.Array{Elements: []runtime.Object{}}
+			if argc > fn.NumParameters {
+				rest.Elements = append(rest.Elements, vm.stack[frame.basePointer+fn.NumParameters:vm.sp]...)
+			}
+		}
+		// locals which are not arguments start out unbound, so do the
+		// parameters which were not passed until they get their default
+		for i := frame.basePointer + min(argc, fn.NumParameters); i < top; i++ {
+			vm.stack[i] = nil
+		}
+		if rest != nil {
+			vm.stack[frame.basePointer+fn.NumParameters] = rest
+		}
+		vm.sp = top
+		return nil
+
+	case *runtime.Builtin:
+		args := make([]runtime.Object, argc)
+		copy(args, vm.stack[vm.sp-argc:vm.sp])
+		vm.sp -= argc + 1
+		var before int64
+		if argc > 0 {
+			before = runtime.SizeOf(args[0])
+		}
+		c := &caller{vm: vm}
+		res := callee.Call(c, args...)
+		// what the builtin charged while building res is paid already
+		size := runtime.SizeOf(res) - c.charged
+		if argc > 0 && res == args[0] {
+			// changed in place, like push, only the growth is new
+			size -= before
+		}
+		if err := vm.alloc(size); err != nil {
+			return err
+		}
+		return vm.pushResult(res)
+
+	default:
+		return runtime.NewTypeError("not a function: %s", callee.Type())
+	}
+}
@@ -0,0 +1,124 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/NishanthSpShetty/monkey/lexer"
+	"github.com/NishanthSpShetty/monkey/parser"
+	"github.com/NishanthSpShetty/monkey/runtime/compiler"
+	"github.com/NishanthSpShetty/monkey/runtime/evaluator/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func run(t *testing.T, input string) runtime.Object {
+	c := compiler.New()
+	err := c.Compile(parser.New(lexer.New(input)).ParseProgram())
+	assert.NoErrorf(t, err, "compiling %q", input)
+	return New(c.Bytecode()).Run()
+}
+
+func testIntegerObject(t *testing.T, obj runtime.Object, exp int64) {
+	io, ok := obj.(*runtime.Integer)
+	assert.Truef(t, ok, "runtime must be Integer object, got %T (%v)", obj, obj)
+	if io == nil {
+		return
+	}
+	assert.Equal(t, exp, io.Value)
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`
+let fibonacci = fn(x) {
+	if (x == 0) { return 0; }
+	if (x == 1) { return 1; }
+	fibonacci(x - 1) + fibonacci(x - 2);
+};
+fibonacci(15);`, 610},
+		{`
+let wrapper = fn() {
+	let countDown = fn(x) {
+		if (x == 0) { return 0; }
+		countDown(x - 1);
+	};
+	countDown(5);
+};
+wrapper();`, 0},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, run(t, tt.input), tt.expected)
+	}
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`
+let newAdderOuter = fn(a, b) {
+	let c = a + b;
+	fn(d) {
+		let e = d + c;
+		fn(f) { e + f; };
+	};
+};
+let newAdderInner = newAdderOuter(1, 2);
+let adder = newAdderInner(3);
+adder(8);`, 14},
+		{`
+let newClosure = fn(a, b) {
+	let one = fn() { a; };
+	let two = fn() { b; };
+	fn() { one() + two(); };
+};
+let closure = newClosure(9, 90);
+closure();`, 99},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, run(t, tt.input), tt.expected)
+	}
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(a) { a }()", "wrong number of arguments: want=1, got=0"},
+		{"let f = fn() { f() }; f()", "maximum call depth exceeded: 10000"},
+		{"1 / 0", "division by zero"},
+		{"5()", "not a function: Integer"},
+	}
+
+	for _, tt := range tests {
+		result := run(t, tt.input)
+		err, ok := result.(*runtime.Error)
+		assert.Truef(t, ok, "result must be Error, got %T", result)
+		if ok {
+			assert.Equal(t, tt.expected, err.Message)
+		}
+	}
+}
+
+func TestGlobalsPersistAcrossRuns(t *testing.T) {
+	symbols := compiler.NewSymbolTable()
+	constants := []runtime.Object{}
+	globals := make([]runtime.Object, GlobalsSize)
+
+	var result runtime.Object
+	for _, input := range []string{"let a = 40;", "let add = fn(x) { a + x };", "add(2)"} {
+		c := compiler.NewWithState(symbols, constants)
+		assert.NoError(t, c.Compile(parser.New(lexer.New(input)).ParseProgram()))
+		bytecode := c.Bytecode()
+		constants = bytecode.Constants
+
+		result = NewWithGlobals(bytecode, globals).Run()
+	}
+	testIntegerObject(t, result, 42)
+}
@@ -0,0 +1,101 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Position is a location in the source: a byte offset and a 1-based line and
+// column, the column counts characters rather than bytes.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// IsValid reports whether the position was set by the lexer.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// File is a named source text, used to render positions along with the line they point into.
+type File struct {
+	Name string
+	Src  string
+}
+
+func NewFile(name, src string) *File {
+	return &File{
+		Name: name,
+		Src:  src,
+	}
+}
+
+// Line returns the text of 1-based line n, without the line terminator.
+func (f *File) Line(n int) string {
+	if f == nil || n < 1 {
+		return ""
+	}
+	lines := strings.Split(f.Src, "\n")
+	if n > len(lines) {
+		return ""
+	}
+	return strings.TrimSuffix(lines[n-1], "\r")
+}
+
+// Location renders pos as file:line:col, leaving out the parts that are unknown.
+func (f *File) Location(pos Position) string {
+	name := ""
+	if f != nil {
+		name = f.Name
+	}
+	switch {
+	case !pos.IsValid():
+		return name
+	case name == "":
+		return pos.String()
+	default:
+		return name + ":" + pos.String()
+	}
+}
+
+// Format renders msg prefixed by the location of pos, followed by the source line
+// and a caret under the column pos points at.
+//
+//	main.mk:3:9: expected next token to be ), got ; instead
+//	    let x = (1;
+//	              ^
+func (f *File) Format(pos Position, msg string) string {
+	loc := f.Location(pos)
+	if loc == "" {
+		return msg
+	}
+	out := loc + ": " + msg
+	line := f.Line(pos.Line)
+	if !pos.IsValid() || line == "" {
+		return out
+	}
+
+	// keep tabs in the gutter so the caret lines up with the source
+	var gutter strings.Builder
+	col := 1
+	for _, ch := range line {
+		if col >= pos.Column {
+			break
+		}
+		if ch == '\t' {
+			gutter.WriteByte('\t')
+		} else {
+			gutter.WriteByte(' ')
+		}
+		col++
+	}
+	return out + "\n    " + line + "\n    " + gutter.String() + "^"
+}
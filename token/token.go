@@ -1,20 +1,29 @@
 package token
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 type TokenType string
 
 type Token struct {
 	Type    TokenType
 	Literal string
+	// Pos is where the token starts, End is just past its last character.
+	Pos Position
+	End Position
 }
 
 const (
-	ILLEGAL = "ILLEGAL"
+	ILLEGAL = "ILLEGAL" // the literal describes what is wrong
 	EOF     = "EOF"
 	// Identifiers + literals
 	IDENT = "IDENT" // add, foobar, x, y, ...
 	INT   = "INT"   // 1343456
+	FLOAT = "FLOAT" // 3.14, 1e-3
+
+	COMMENT = "COMMENT" // only produced when the lexer is asked to keep comments
 	// Operators
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -28,6 +37,12 @@ const (
 
 	EQ     = "=="
 	NOT_EQ = "!="
+
+	// compound assignments
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
@@ -37,6 +52,7 @@ const (
 	RBRACE    = "}"
 	LBRACKET  = "["
 	RBRACKET  = "]"
+	ELLIPSIS  = "..."
 	// Keywords
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
@@ -45,21 +61,39 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	IN       = "IN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	THROW    = "THROW"
 	STRING   = "STRING"
 	COLON    = ":"
 )
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"throw":    THROW,
 }
 
-func CreateForByte(tokenType TokenType, ch byte) Token {
+func CreateForRune(tokenType TokenType, ch rune) Token {
 	return Token{
 		Type:    tokenType,
 		Literal: string(ch),
@@ -87,6 +121,16 @@ func Eof() Token {
 	}
 }
 
+// Keywords returns the keywords of the language, sorted.
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
 func LookupIdent(ident string) TokenType {
 	if tok, ok := keywords[ident]; ok {
 		return tok